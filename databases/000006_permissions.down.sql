@@ -0,0 +1,14 @@
+BEGIN;
+
+CREATE TYPE user_role AS ENUM ('admin', 'staff');
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::user_role;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'staff';
+
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
+
+COMMIT;
@@ -0,0 +1,65 @@
+BEGIN;
+
+CREATE TABLE roles (
+  name VARCHAR(50) PRIMARY KEY,
+  description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE permissions (
+  name VARCHAR(100) PRIMARY KEY,
+  description VARCHAR(255) NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+  role VARCHAR(50) NOT NULL,
+  permission VARCHAR(100) NOT NULL,
+  PRIMARY KEY (role, permission),
+  FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE,
+  FOREIGN KEY (permission) REFERENCES permissions(name) ON DELETE CASCADE
+);
+
+INSERT INTO roles (name, description) VALUES
+  ('admin', 'Manages the catalogue, locations and users'),
+  ('staff', 'Receives and ships stock');
+
+INSERT INTO permissions (name, description) VALUES
+  ('product:read', 'List and view products'),
+  ('product:write', 'Create, import and update products'),
+  ('product:delete', 'Delete products'),
+  ('product:export', 'Export products'),
+  ('location:read', 'List locations'),
+  ('location:write', 'Create locations'),
+  ('location:export', 'Export locations'),
+  ('order:read', 'List and view orders'),
+  ('order:receive', 'Receive stock'),
+  ('order:ship', 'Ship stock'),
+  ('order:export', 'Export orders'),
+  ('user:read', 'List users'),
+  ('role:manage', 'Manage roles and their permissions');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'product:read'),
+  ('admin', 'product:write'),
+  ('admin', 'product:delete'),
+  ('admin', 'product:export'),
+  ('admin', 'location:read'),
+  ('admin', 'location:write'),
+  ('admin', 'location:export'),
+  ('admin', 'order:read'),
+  ('admin', 'order:export'),
+  ('admin', 'user:read'),
+  ('admin', 'role:manage'),
+  ('staff', 'product:read'),
+  ('staff', 'location:read'),
+  ('staff', 'order:read'),
+  ('staff', 'order:receive'),
+  ('staff', 'order:ship');
+
+ALTER TABLE users ALTER COLUMN role DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN role TYPE VARCHAR(50) USING role::text;
+ALTER TABLE users ALTER COLUMN role SET DEFAULT 'staff';
+ALTER TABLE users ADD FOREIGN KEY (role) REFERENCES roles(name);
+
+DROP TYPE user_role;
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+DELETE FROM permissions WHERE name = 'user:write';
+
+DROP TABLE IF EXISTS user_locations;
+
+ALTER TABLE users DROP COLUMN IF EXISTS all_locations;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Existing users keep access to every location until an admin narrows it.
+ALTER TABLE users ADD COLUMN all_locations BOOLEAN NOT NULL DEFAULT true;
+ALTER TABLE users ALTER COLUMN all_locations SET DEFAULT false;
+
+CREATE TABLE user_locations (
+  user_id UUID NOT NULL,
+  location_id UUID NOT NULL,
+  PRIMARY KEY (user_id, location_id),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (name, description) VALUES
+  ('user:write', 'Manage users and their locations');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'user:write');
+
+COMMIT;
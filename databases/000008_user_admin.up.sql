@@ -0,0 +1,15 @@
+BEGIN;
+
+ALTER TABLE users ADD COLUMN active BOOLEAN NOT NULL DEFAULT true;
+
+CREATE TABLE password_reset_tokens (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL,
+  token_hash VARCHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+CREATE TABLE invites (
+  id UUID PRIMARY KEY,
+  code_hash VARCHAR(64) NOT NULL UNIQUE,
+  role VARCHAR(50) NOT NULL,
+  created_by UUID,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  used_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (role) REFERENCES roles(name) ON DELETE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL,
+  -- The invite is consumed before the new user row exists.
+  FOREIGN KEY (used_by) REFERENCES users(id) ON DELETE SET NULL DEFERRABLE INITIALLY DEFERRED
+);
+
+COMMIT;
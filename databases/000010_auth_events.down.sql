@@ -0,0 +1,7 @@
+BEGIN;
+
+DELETE FROM permissions WHERE name = 'auth_event:read';
+
+DROP TABLE IF EXISTS auth_events;
+
+COMMIT;
@@ -0,0 +1,24 @@
+BEGIN;
+
+CREATE TABLE auth_events (
+  id UUID PRIMARY KEY,
+  event VARCHAR(50) NOT NULL,
+  email VARCHAR(100) NOT NULL,
+  user_id UUID,
+  ip VARCHAR(64) NOT NULL,
+  success BOOLEAN NOT NULL,
+  reason VARCHAR(50) NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX auth_events_email_created_at_idx ON auth_events (email, created_at);
+CREATE INDEX auth_events_ip_created_at_idx ON auth_events (ip, created_at);
+
+INSERT INTO permissions (name, description) VALUES
+  ('auth_event:read', 'View login attempts');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'auth_event:read');
+
+COMMIT;
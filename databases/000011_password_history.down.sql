@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS password_history;
+
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(100);
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Argon2id hashes carry their parameters and run longer than bcrypt.
+ALTER TABLE users ALTER COLUMN password TYPE VARCHAR(255);
+
+CREATE TABLE password_history (
+  id BIGSERIAL PRIMARY KEY,
+  user_id UUID NOT NULL,
+  password_hash VARCHAR(255) NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX password_history_user_id_created_at_idx ON password_history (user_id, created_at);
+
+COMMIT;
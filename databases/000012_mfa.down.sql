@@ -0,0 +1,11 @@
+BEGIN;
+
+DROP TABLE IF EXISTS mfa_challenges;
+
+DROP TABLE IF EXISTS recovery_codes;
+
+ALTER TABLE users DROP COLUMN IF EXISTS totp_last_step;
+ALTER TABLE users DROP COLUMN IF EXISTS mfa_enabled;
+ALTER TABLE users DROP COLUMN IF EXISTS totp_secret;
+
+COMMIT;
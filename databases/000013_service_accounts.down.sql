@@ -0,0 +1,13 @@
+BEGIN;
+
+DELETE FROM permissions WHERE name = 'service_account:manage';
+
+DROP TABLE IF EXISTS api_key_permissions;
+
+DROP TABLE IF EXISTS api_keys;
+
+DELETE FROM users WHERE service_account;
+
+ALTER TABLE users DROP COLUMN IF EXISTS service_account;
+
+COMMIT;
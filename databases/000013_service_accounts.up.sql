@@ -0,0 +1,40 @@
+BEGIN;
+
+-- Service accounts are users that can't log in with a password; they
+-- authenticate with API keys and share roles and location scopes.
+ALTER TABLE users ADD COLUMN service_account BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE api_keys (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL,
+  name VARCHAR(100) NOT NULL,
+  prefix VARCHAR(16) NOT NULL,
+  key_hash VARCHAR(64) NOT NULL UNIQUE,
+  expires_at TIMESTAMPTZ,
+  last_used_at TIMESTAMPTZ,
+  revoked_at TIMESTAMPTZ,
+  created_by UUID,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+-- A key can only use the permissions listed here, and only while the
+-- account's role still grants them.
+CREATE TABLE api_key_permissions (
+  api_key_id UUID NOT NULL,
+  permission VARCHAR(100) NOT NULL,
+  PRIMARY KEY (api_key_id, permission),
+  FOREIGN KEY (api_key_id) REFERENCES api_keys(id) ON DELETE CASCADE,
+  FOREIGN KEY (permission) REFERENCES permissions(name) ON DELETE CASCADE
+);
+
+INSERT INTO permissions (name, description) VALUES
+  ('service_account:manage', 'Manage service accounts and their API keys');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'service_account:manage');
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS oidc_login_states;
+
+DROP TABLE IF EXISTS user_identities;
+
+COMMIT;
@@ -0,0 +1,24 @@
+BEGIN;
+
+-- Links an identity provider subject to the local user it signed in as.
+CREATE TABLE user_identities (
+  issuer VARCHAR(255) NOT NULL,
+  subject VARCHAR(255) NOT NULL,
+  user_id UUID NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (issuer, subject),
+  UNIQUE (user_id, issuer),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE oidc_login_states (
+  id UUID PRIMARY KEY,
+  state_hash VARCHAR(64) NOT NULL UNIQUE,
+  nonce VARCHAR(64) NOT NULL,
+  code_verifier VARCHAR(128) NOT NULL,
+  expires_at TIMESTAMPTZ NOT NULL,
+  used_at TIMESTAMPTZ,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+COMMIT;
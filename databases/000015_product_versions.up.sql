@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Bumped on every write, including stock changes from orders, so a client
+-- can only overwrite a product it has seen in its current state.
+ALTER TABLE products ADD COLUMN version INT8 NOT NULL DEFAULT 1;
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Responses to requests sent with an Idempotency-Key header. Keys belong to
+-- the user (or service account) that sent them; status_code stays NULL while
+-- the first request is still running.
+CREATE TABLE idempotency_keys (
+  user_id UUID NOT NULL,
+  key VARCHAR(255) NOT NULL,
+  request_hash VARCHAR(64) NOT NULL,
+  status_code INT,
+  content_type VARCHAR(255),
+  response_body BYTEA,
+  expires_at TIMESTAMPTZ NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, key),
+  FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idempotency_keys_expires_at_idx ON idempotency_keys (expires_at);
+
+COMMIT;
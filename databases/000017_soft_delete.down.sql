@@ -0,0 +1,26 @@
+BEGIN;
+
+UPDATE permissions SET description = 'Delete products' WHERE name = 'product:delete';
+
+DELETE FROM permissions WHERE name = 'location:delete';
+
+ALTER TABLE products DROP CONSTRAINT products_location_id_fkey;
+ALTER TABLE products ADD CONSTRAINT products_location_id_fkey FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE CASCADE;
+
+ALTER TABLE orders DROP CONSTRAINT orders_product_id_fkey;
+ALTER TABLE orders ADD CONSTRAINT orders_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE CASCADE;
+
+-- Soft-deleted rows would break the old constraints, so drop them first.
+DELETE FROM products WHERE deleted_at IS NOT NULL;
+DELETE FROM locations WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS products_sku_live_key;
+ALTER TABLE products ADD CONSTRAINT products_sku_key UNIQUE (sku);
+
+ALTER TABLE locations DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE locations DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
+
+COMMIT;
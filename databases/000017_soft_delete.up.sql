@@ -0,0 +1,30 @@
+BEGIN;
+
+-- Products and locations are hidden rather than removed, so their order
+-- history survives and they can be restored.
+ALTER TABLE products ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE products ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE locations ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE locations ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- A SKU only has to be unique among live products.
+ALTER TABLE products DROP CONSTRAINT products_sku_key;
+CREATE UNIQUE INDEX products_sku_live_key ON products (sku) WHERE deleted_at IS NULL;
+
+-- Never let a stray hard delete take the history with it.
+ALTER TABLE orders DROP CONSTRAINT orders_product_id_fkey;
+ALTER TABLE orders ADD CONSTRAINT orders_product_id_fkey FOREIGN KEY (product_id) REFERENCES products(id) ON DELETE RESTRICT;
+
+ALTER TABLE products DROP CONSTRAINT products_location_id_fkey;
+ALTER TABLE products ADD CONSTRAINT products_location_id_fkey FOREIGN KEY (location_id) REFERENCES locations(id) ON DELETE RESTRICT;
+
+INSERT INTO permissions (name, description) VALUES
+  ('location:delete', 'Delete and restore locations');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'location:delete');
+
+UPDATE permissions SET description = 'Delete and restore products' WHERE name = 'product:delete';
+
+COMMIT;
@@ -0,0 +1,16 @@
+BEGIN;
+
+DELETE FROM permissions WHERE name = 'audit:read';
+
+DROP TRIGGER IF EXISTS user_locations_audit ON user_locations;
+DROP TRIGGER IF EXISTS users_audit ON users;
+DROP TRIGGER IF EXISTS orders_audit ON orders;
+DROP TRIGGER IF EXISTS locations_audit ON locations;
+DROP TRIGGER IF EXISTS products_audit ON products;
+
+DROP FUNCTION IF EXISTS audit_row();
+DROP FUNCTION IF EXISTS audit_changes(JSONB, JSONB, TEXT[]);
+
+DROP TABLE IF EXISTS audit_logs;
+
+COMMIT;
@@ -0,0 +1,104 @@
+BEGIN;
+
+CREATE TABLE audit_logs (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  actor_id UUID,
+  action VARCHAR(20) NOT NULL,
+  entity VARCHAR(50) NOT NULL,
+  entity_id UUID NOT NULL,
+  changes JSONB NOT NULL,
+  ip VARCHAR(64) NOT NULL DEFAULT '',
+  request_id VARCHAR(128) NOT NULL DEFAULT '',
+  created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX audit_logs_entity_idx ON audit_logs (entity, entity_id, created_at);
+CREATE INDEX audit_logs_actor_id_idx ON audit_logs (actor_id, created_at);
+CREATE INDEX audit_logs_created_at_idx ON audit_logs (created_at);
+
+-- audit_changes lists the fields that differ as {"field": {"before": ..,
+-- "after": ..}}. Redacted fields only show that they changed.
+CREATE FUNCTION audit_changes(old_row JSONB, new_row JSONB, redacted TEXT[]) RETURNS JSONB AS $$
+  SELECT COALESCE(jsonb_object_agg(key, jsonb_build_object(
+      'before', CASE WHEN old_row IS NULL OR NOT old_row ? key THEN NULL WHEN key = ANY(redacted) THEN '"[redacted]"'::jsonb ELSE old_row -> key END,
+      'after', CASE WHEN new_row IS NULL OR NOT new_row ? key THEN NULL WHEN key = ANY(redacted) THEN '"[redacted]"'::jsonb ELSE new_row -> key END
+    )), '{}'::jsonb)
+  FROM jsonb_object_keys(COALESCE(old_row, '{}'::jsonb) || COALESCE(new_row, '{}'::jsonb)) AS key
+  WHERE old_row -> key IS DISTINCT FROM new_row -> key
+$$ LANGUAGE sql IMMUTABLE;
+
+-- audit_row records every row change in the same transaction as the change.
+-- The actor, address and request ID come from the audit.* settings the
+-- application sets on the transaction; changes made outside a tagged
+-- transaction are recorded without them.
+--
+-- Arguments: entity name, id column, redacted columns, ignored columns. An
+-- update that only touches ignored columns is not recorded.
+CREATE FUNCTION audit_row() RETURNS TRIGGER AS $$
+DECLARE
+  old_row JSONB;
+  new_row JSONB;
+  ignored TEXT[] := TG_ARGV[3]::TEXT[];
+  changes JSONB;
+  audit_action TEXT;
+BEGIN
+  IF TG_OP <> 'INSERT' THEN
+    old_row := to_jsonb(OLD) - ignored;
+  END IF;
+  IF TG_OP <> 'DELETE' THEN
+    new_row := to_jsonb(NEW) - ignored;
+  END IF;
+
+  changes := audit_changes(old_row, new_row, TG_ARGV[2]::TEXT[]);
+  IF changes = '{}'::jsonb THEN
+    RETURN NULL;
+  END IF;
+
+  audit_action := CASE TG_OP
+    WHEN 'INSERT' THEN 'create'
+    WHEN 'DELETE' THEN 'delete'
+    WHEN 'UPDATE' THEN CASE
+      WHEN old_row ->> 'deleted_at' IS NULL AND new_row ->> 'deleted_at' IS NOT NULL THEN 'delete'
+      WHEN old_row ->> 'deleted_at' IS NOT NULL AND new_row ->> 'deleted_at' IS NULL THEN 'restore'
+      ELSE 'update'
+    END
+  END;
+
+  INSERT INTO audit_logs (actor_id, action, entity, entity_id, changes, ip, request_id)
+  VALUES (
+    NULLIF(current_setting('audit.actor_id', true), '')::UUID,
+    audit_action,
+    TG_ARGV[0],
+    (COALESCE(new_row, old_row) ->> TG_ARGV[1])::UUID,
+    changes,
+    COALESCE(current_setting('audit.ip', true), ''),
+    COALESCE(current_setting('audit.request_id', true), '')
+  );
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER products_audit AFTER INSERT OR UPDATE OR DELETE ON products
+  FOR EACH ROW EXECUTE FUNCTION audit_row('product', 'id', '{}', '{version}');
+
+CREATE TRIGGER locations_audit AFTER INSERT OR UPDATE OR DELETE ON locations
+  FOR EACH ROW EXECUTE FUNCTION audit_row('location', 'id', '{}', '{}');
+
+CREATE TRIGGER orders_audit AFTER INSERT OR UPDATE OR DELETE ON orders
+  FOR EACH ROW EXECUTE FUNCTION audit_row('order', 'id', '{}', '{}');
+
+CREATE TRIGGER users_audit AFTER INSERT OR UPDATE OR DELETE ON users
+  FOR EACH ROW EXECUTE FUNCTION audit_row('user', 'id', '{password,totp_secret}', '{totp_last_step}');
+
+CREATE TRIGGER user_locations_audit AFTER INSERT OR UPDATE OR DELETE ON user_locations
+  FOR EACH ROW EXECUTE FUNCTION audit_row('user_location', 'user_id', '{}', '{}');
+
+INSERT INTO permissions (name, description) VALUES
+  ('audit:read', 'View the audit log');
+
+INSERT INTO role_permissions (role, permission) VALUES
+  ('admin', 'audit:read');
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+-- The granted access can't be told apart from later assignments, so it is
+-- left in place.
+
+COMMIT;
@@ -0,0 +1,13 @@
+BEGIN;
+
+-- Admins who joined through an invite were created without any location.
+-- Give them every location, like the bootstrapped admin, unless an admin
+-- has assigned them locations explicitly since.
+UPDATE users SET all_locations = true
+FROM invites
+WHERE invites.used_by = users.id
+  AND users.role = 'admin'
+  AND NOT users.all_locations
+  AND NOT EXISTS (SELECT 1 FROM user_locations WHERE user_locations.user_id = users.id);
+
+COMMIT;
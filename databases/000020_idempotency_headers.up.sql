@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Response headers replayed together with the stored body, such as the
+-- Location of a created resource.
+ALTER TABLE idempotency_keys ADD COLUMN response_headers JSONB;
+
+COMMIT;
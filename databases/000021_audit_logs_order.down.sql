@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP INDEX IF EXISTS audit_logs_created_at_idx;
+CREATE INDEX audit_logs_created_at_idx ON audit_logs (created_at);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+-- Audit ids are random, so listings are ordered by (created_at, id) instead.
+DROP INDEX IF EXISTS audit_logs_created_at_idx;
+CREATE INDEX audit_logs_created_at_idx ON audit_logs (created_at, id);
+
+COMMIT;
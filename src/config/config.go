@@ -1,7 +1,11 @@
 package config
 
 import (
+	"fmt"
+	"log/slog"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,17 +20,85 @@ type DBConfig struct {
 }
 
 type HTTPConfig struct {
-	Port string
+	Port           string
+	TrustedProxies []string
+	MetricsAddr    string
+}
+
+type JWTConfig struct {
+	Algorithm            string
+	SecretKey            string
+	SigningKeyFile       string
+	SigningKeyID         string
+	VerificationKeyFiles []string
+}
+
+type PasswordConfig struct {
+	MinLength        int
+	BreachedListFile string
+	HistorySize      int
+}
+
+type MFAConfig struct {
+	Issuer        string
+	RequiredRoles []string
+}
+
+// OIDCConfig turns on single sign-on when IssuerURL is set. GroupRoles is
+// checked in order and the first group the user belongs to picks the role.
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	GroupsClaim  string
+	GroupRoles   []GroupRole
+}
+
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+type IdempotencyConfig struct {
+	TTL time.Duration
+}
+
+type LogConfig struct {
+	Level slog.Level
+}
+
+type RegistrationMode string
+
+const (
+	RegistrationDisabled RegistrationMode = "disabled"
+	RegistrationInvite   RegistrationMode = "invite"
+	RegistrationStaff    RegistrationMode = "staff"
+)
+
+type RegistrationConfig struct {
+	Mode RegistrationMode
 }
 
 type Config struct {
-	DB   DBConfig
-	Http HTTPConfig
+	DB           DBConfig
+	Http         HTTPConfig
+	JWT          JWTConfig
+	Registration RegistrationConfig
+	Password     PasswordConfig
+	MFA          MFAConfig
+	OIDC         OIDCConfig
+	Idempotency  IdempotencyConfig
+	Log          LogConfig
 }
 
 var (
-	secretKey = os.Getenv("SECRET_KEY")
-	expTime   = 120 * time.Minute
+	expTime        = 120 * time.Minute
+	refreshExpTime = 7 * 24 * time.Hour
+	resetExpTime   = 24 * time.Hour
+	inviteExpTime  = 72 * time.Hour
+	mfaExpTime     = 5 * time.Minute
+	oidcExpTime    = 10 * time.Minute
 )
 
 func NewEnv() (Config, error) {
@@ -40,21 +112,189 @@ func NewEnv() (Config, error) {
 		Schema:   os.Getenv("DB_SCHEMA"),
 	}
 	http := HTTPConfig{
-		Port: os.Getenv("PORT"),
+		Port:           os.Getenv("PORT"),
+		TrustedProxies: splitList(os.Getenv("TRUSTED_PROXIES")),
+		MetricsAddr:    os.Getenv("METRICS_ADDR"),
+	}
+
+	if http.MetricsAddr == "" {
+		http.MetricsAddr = "127.0.0.1:9090"
+	}
+
+	jwt := JWTConfig{
+		Algorithm:            os.Getenv("JWT_ALGORITHM"),
+		SecretKey:            os.Getenv("SECRET_KEY"),
+		SigningKeyFile:       os.Getenv("JWT_SIGNING_KEY_FILE"),
+		SigningKeyID:         os.Getenv("JWT_SIGNING_KEY_ID"),
+		VerificationKeyFiles: splitList(os.Getenv("JWT_VERIFICATION_KEY_FILES")),
+	}
+
+	registration := RegistrationConfig{
+		Mode: RegistrationMode(strings.ToLower(os.Getenv("REGISTRATION_MODE"))),
+	}
+
+	switch registration.Mode {
+	case "":
+		registration.Mode = RegistrationDisabled
+	case RegistrationDisabled, RegistrationInvite, RegistrationStaff:
+	default:
+		return Config{}, fmt.Errorf("unsupported REGISTRATION_MODE %s", registration.Mode)
+	}
+
+	password := PasswordConfig{
+		MinLength:        8,
+		BreachedListFile: os.Getenv("PASSWORD_BREACHED_LIST_FILE"),
+		HistorySize:      5,
+	}
+
+	if err := intEnv("PASSWORD_MIN_LENGTH", &password.MinLength); err != nil {
+		return Config{}, err
+	}
+
+	if err := intEnv("PASSWORD_HISTORY_SIZE", &password.HistorySize); err != nil {
+		return Config{}, err
+	}
+
+	mfa := MFAConfig{
+		Issuer:        os.Getenv("MFA_ISSUER"),
+		RequiredRoles: splitList(os.Getenv("MFA_REQUIRED_ROLES")),
+	}
+
+	if mfa.Issuer == "" {
+		mfa.Issuer = "Warehouse"
+	}
+
+	oidc, err := newOIDCConfig()
+	if err != nil {
+		return Config{}, err
+	}
+
+	idempotency := IdempotencyConfig{
+		TTL: 24 * time.Hour,
+	}
+
+	if err := durationEnv("IDEMPOTENCY_KEY_TTL", &idempotency.TTL); err != nil {
+		return Config{}, err
+	}
+
+	var logConfig LogConfig
+	if level := os.Getenv("LOG_LEVEL"); level != "" {
+		if err := logConfig.Level.UnmarshalText([]byte(level)); err != nil {
+			return Config{}, fmt.Errorf("unsupported LOG_LEVEL %s", level)
+		}
 	}
 
 	config := Config{
-		DB:   db,
-		Http: http,
+		DB:           db,
+		Http:         http,
+		JWT:          jwt,
+		Registration: registration,
+		Password:     password,
+		MFA:          mfa,
+		OIDC:         oidc,
+		Idempotency:  idempotency,
+		Log:          logConfig,
 	}
 
 	return config, nil
 }
 
-func GetJwtSecretKey() []byte {
-	return []byte(secretKey)
-}
-
 func GetExpTime() time.Duration {
 	return expTime
 }
+
+func GetRefreshExpTime() time.Duration {
+	return refreshExpTime
+}
+
+func GetResetExpTime() time.Duration {
+	return resetExpTime
+}
+
+func GetInviteExpTime() time.Duration {
+	return inviteExpTime
+}
+
+func GetMFAExpTime() time.Duration {
+	return mfaExpTime
+}
+
+func GetOIDCExpTime() time.Duration {
+	return oidcExpTime
+}
+
+func newOIDCConfig() (OIDCConfig, error) {
+	oidc := OIDCConfig{
+		IssuerURL:    strings.TrimSuffix(os.Getenv("OIDC_ISSUER_URL"), "/"),
+		ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+		GroupsClaim:  os.Getenv("OIDC_GROUPS_CLAIM"),
+	}
+
+	if oidc.IssuerURL == "" {
+		return oidc, nil
+	}
+
+	if oidc.GroupsClaim == "" {
+		oidc.GroupsClaim = "groups"
+	}
+
+	for _, item := range splitList(os.Getenv("OIDC_GROUP_ROLES")) {
+		group, role, ok := strings.Cut(item, "=")
+		if !ok || group == "" || role == "" {
+			return OIDCConfig{}, fmt.Errorf("OIDC_GROUP_ROLES entry %q is not group=role", item)
+		}
+
+		oidc.GroupRoles = append(oidc.GroupRoles, GroupRole{Group: group, Role: role})
+	}
+
+	if oidc.ClientID == "" || oidc.RedirectURL == "" || len(oidc.GroupRoles) == 0 {
+		return OIDCConfig{}, fmt.Errorf("OIDC_CLIENT_ID, OIDC_REDIRECT_URL and OIDC_GROUP_ROLES are required with OIDC_ISSUER_URL")
+	}
+
+	return oidc, nil
+}
+
+// intEnv overrides value when the variable is set.
+func intEnv(name string, value *int) error {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("%s must be a non-negative integer", name)
+	}
+
+	*value = parsed
+	return nil
+}
+
+// durationEnv overrides value when the variable is set, e.g. "24h".
+func durationEnv(name string, value *time.Duration) error {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(raw)
+	if err != nil || parsed <= 0 {
+		return fmt.Errorf("%s must be a positive duration such as 24h", name)
+	}
+
+	*value = parsed
+	return nil
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
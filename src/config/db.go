@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq"
@@ -44,6 +44,6 @@ func NewDB(conf DBConfig) (PostgresDb, error) {
 		return db, err
 	}
 
-	log.Printf("sql database connection %s success", db.Conn.DriverName())
+	slog.Info("sql database connection success", "driver", db.Conn.DriverName())
 	return db, nil
 }
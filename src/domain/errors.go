@@ -0,0 +1,111 @@
+package domain
+
+// Kind says what went wrong, independent of transport. helpers maps each kind
+// to an HTTP status.
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindValidation
+	KindUnauthorized
+	KindForbidden
+	KindNotFound
+	KindConflict
+	KindPreconditionFailed
+	KindInsufficientStock
+	KindUnprocessable
+	KindTooManyRequests
+)
+
+// Error is what repositories and services return for failures a caller can
+// act on. Code is a stable machine-readable identifier sent to clients, so it
+// must not change once released; Message is for humans.
+//
+// Callers add context with fmt.Errorf("...: %w", err) and test with
+// errors.Is, either against a kind (domain.ErrNotFound) or a specific error.
+type Error struct {
+	Kind    Kind
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+// FieldError describes one invalid input field. Field is the name the client
+// sent (the JSON name, with a path for nested values), Rule the check that
+// failed and Param its argument, if any.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is matches errors of the same kind. A target with a code also has to match
+// the code, so both errors.Is(err, domain.ErrNotFound) and
+// errors.Is(err, errProductNotFound) work.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+
+	return e.Kind == t.Kind && (t.Code == "" || e.Code == t.Code)
+}
+
+// Kind-only sentinels for errors.Is.
+var (
+	ErrValidation         = &Error{Kind: KindValidation}
+	ErrUnauthorized       = &Error{Kind: KindUnauthorized}
+	ErrForbidden          = &Error{Kind: KindForbidden}
+	ErrNotFound           = &Error{Kind: KindNotFound}
+	ErrConflict           = &Error{Kind: KindConflict}
+	ErrPreconditionFailed = &Error{Kind: KindPreconditionFailed}
+	ErrInsufficientStock  = &Error{Kind: KindInsufficientStock}
+	ErrUnprocessable      = &Error{Kind: KindUnprocessable}
+	ErrTooManyRequests    = &Error{Kind: KindTooManyRequests}
+)
+
+func Validation(code string, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// InvalidFields is a validation error that lists every invalid field.
+func InvalidFields(code string, message string, fields []FieldError) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message, Fields: fields}
+}
+
+func Unauthorized(code string, message string) *Error {
+	return &Error{Kind: KindUnauthorized, Code: code, Message: message}
+}
+
+func Forbidden(code string, message string) *Error {
+	return &Error{Kind: KindForbidden, Code: code, Message: message}
+}
+
+func NotFound(code string, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+func Conflict(code string, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+func PreconditionFailed(code string, message string) *Error {
+	return &Error{Kind: KindPreconditionFailed, Code: code, Message: message}
+}
+
+func InsufficientStock(code string, message string) *Error {
+	return &Error{Kind: KindInsufficientStock, Code: code, Message: message}
+}
+
+func Unprocessable(code string, message string) *Error {
+	return &Error{Kind: KindUnprocessable, Code: code, Message: message}
+}
+
+func TooManyRequests(code string, message string) *Error {
+	return &Error{Kind: KindTooManyRequests, Code: code, Message: message}
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+type AuditHandler interface {
+	ListAuditLogs(c *gin.Context)
+}
+
+type auditHandlerImpl struct {
+	audit services.AuditService
+}
+
+func NewAuditHandler(audit services.AuditService) AuditHandler {
+	return &auditHandlerImpl{audit: audit}
+}
+
+func (h *auditHandlerImpl) ListAuditLogs(c *gin.Context) {
+	pagination, err := bindPagination(c)
+	if err != nil {
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	if err := utils.Validate(pagination); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	filter := &dto.AuditLogFilter{
+		Entity: c.Query("entity"),
+		Action: dto.AuditAction(c.Query("action")),
+	}
+
+	if filter.ActorID, err = uuidQuery(c, "actor_id"); err != nil {
+		helpers.BadRequestError(c, "actor_id must be a uuid")
+		return
+	}
+
+	if filter.EntityID, err = uuidQuery(c, "entity_id"); err != nil {
+		helpers.BadRequestError(c, "entity_id must be a uuid")
+		return
+	}
+
+	if filter.From, err = timeQuery(c, "from"); err != nil {
+		helpers.BadRequestError(c, "from must be an RFC 3339 time")
+		return
+	}
+
+	if filter.To, err = timeQuery(c, "to"); err != nil {
+		helpers.BadRequestError(c, "to must be an RFC 3339 time")
+		return
+	}
+
+	logs, page, err := h.audit.GetAll(c.Request.Context(), filter, pagination)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OKWithMetadata(c, logs, paginationMetadata(c, pagination, logs, page, func(item *dto.AuditLog) uuid.UUID {
+		return item.ID
+	}))
+}
+
+// uuidQuery parses an optional uuid query parameter.
+func uuidQuery(c *gin.Context, name string) (*uuid.UUID, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &id, nil
+}
+
+// timeQuery parses an optional RFC 3339 query parameter.
+func timeQuery(c *gin.Context, name string) (*time.Time, error) {
+	value, ok := c.GetQuery(name)
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// bindJSON decodes and validates the request body. Failures come back as
+// validation errors listing every invalid field, ready for helpers.Error.
+func bindJSON(c *gin.Context, obj any) error {
+	if err := c.ShouldBindJSON(obj); err != nil {
+		return utils.BindingError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag formats a row version as a strong entity tag.
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatchVersion reads the version named by If-Match. present is false
+// without the header, and "*" yields 0, meaning any version. A tag that is
+// not one of ours yields -1, which no row has, so the write fails with 412
+// as if it named an old version.
+func ifMatchVersion(c *gin.Context) (version int64, present bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		return 0, false
+	}
+
+	if header == "*" {
+		return 0, true
+	}
+
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return -1, true
+	}
+
+	version, err := strconv.ParseInt(strings.TrimSuffix(tag, `"`), 10, 64)
+	if err != nil || version < 1 {
+		return -1, true
+	}
+
+	return version, true
+}
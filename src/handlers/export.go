@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+const exportFlushEvery = 500
+
+// bindExport reads the export filters. They mirror the list endpoints except
+// that the export always walks the table in id order and size defaults to
+// everything.
+func bindExport(c *gin.Context) (*web.PaginationRequest, string, error) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "ndjson" {
+		return nil, "", fmt.Errorf("format must be csv or ndjson")
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", "0"))
+	if err != nil || size < 0 {
+		return nil, "", fmt.Errorf("size must be a number of at least 0")
+	}
+
+	after, err := utils.DecodeCursor(c.Query("cursor"))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &web.PaginationRequest{
+		Size:   size,
+		Keyset: true,
+		After:  after,
+	}, format, nil
+}
+
+// streamExport writes every item produced by run as CSV or NDJSON. Headers are
+// only sent once the first item (or the end of an empty result) arrives, so a
+// failing query can still be reported as a normal error response.
+func streamExport[T any](c *gin.Context, name, format string, header []string, record func(T) []string, run func(fn func(T) error) error) {
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	started := false
+	rows := 0
+
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+
+		if format == "ndjson" {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.ndjson"`, name))
+			c.Status(http.StatusOK)
+			jsonEncoder = json.NewEncoder(c.Writer)
+			return
+		}
+
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, name))
+		c.Status(http.StatusOK)
+		csvWriter = csv.NewWriter(c.Writer)
+		csvWriter.Write(header)
+	}
+
+	flush := func() error {
+		if csvWriter != nil {
+			csvWriter.Flush()
+			if err := csvWriter.Error(); err != nil {
+				return err
+			}
+		}
+		c.Writer.Flush()
+		return nil
+	}
+
+	err := run(func(item T) error {
+		start()
+
+		if jsonEncoder != nil {
+			if err := jsonEncoder.Encode(item); err != nil {
+				return err
+			}
+		} else if err := csvWriter.Write(escapeFormulas(record(item))); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%exportFlushEvery == 0 {
+			return flush()
+		}
+
+		return nil
+	})
+	if err != nil {
+		if !started {
+			helpers.Error(c, err)
+			return
+		}
+
+		// The status line is already sent, so all that is left is to stop
+		// writing and leave the error for the logger.
+		c.Error(err)
+		return
+	}
+
+	start()
+	flush()
+}
+
+// escapeFormulas prefixes cells a spreadsheet would run as a formula with a
+// quote, so a product named "=HYPERLINK(...)" stays text when the CSV is
+// opened.
+func escapeFormulas(record []string) []string {
+	for i, cell := range record {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			record[i] = "'" + cell
+		}
+	}
+
+	return record
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+type KeyHandler interface {
+	JWKS(c *gin.Context)
+}
+
+type keyHandlerImpl struct{}
+
+func NewKeyHandler() KeyHandler {
+	return &keyHandlerImpl{}
+}
+
+// JWKS is served as a bare JSON Web Key Set rather than the usual response
+// envelope so standard JWT libraries can consume it directly.
+func (h *keyHandlerImpl) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, utils.GetJWKS())
+}
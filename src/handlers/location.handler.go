@@ -5,9 +5,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 	"github.com/nabilwafi/warehouse-management-system/src/helpers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
-	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
 	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
@@ -15,6 +15,10 @@ import (
 type LocationHandler interface {
 	AddLocation(c *gin.Context)
 	GetAllLocations(c *gin.Context)
+	GetLocationByID(c *gin.Context)
+	DeleteLocation(c *gin.Context)
+	RestoreLocation(c *gin.Context)
+	ExportLocations(c *gin.Context)
 }
 
 type locationHandlerImpl struct {
@@ -32,54 +36,112 @@ func NewLocationHandler(location services.LocationService, validation *validator
 func (h *locationHandlerImpl) AddLocation(c *gin.Context) {
 	var location dto.Location
 
-	if err := c.ShouldBindJSON(&location); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := bindJSON(c, &location); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	if status, msg := utils.Validate(location); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	if err := utils.Validate(location); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	code, err := h.location.Save(&location)
+	created, err := h.location.Save(c.Request.Context(), locationScope(c), auditActor(c), &location)
 	if err != nil {
-		helpers.SuccessByCode(c, code, nil)
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.Created(c, "Successfully Created Data")
+	helpers.CreatedAt(c, resourcePath("locations", created.ID), created)
 }
 
 func (h *locationHandlerImpl) GetAllLocations(c *gin.Context) {
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pagination, err := bindPagination(c)
 	if err != nil {
-		page = 1
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	if err := utils.Validate(pagination); err != nil {
+		helpers.Error(c, err)
+		return
 	}
 
-	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	locations, page, err := h.location.GetAll(c.Request.Context(), locationScope(c), pagination)
 	if err != nil {
-		size = 10
+		helpers.Error(c, err)
+		return
 	}
 
-	pagination := &web.PaginationRequest{
-		Page: page,
-		Size: size,
+	helpers.OKWithMetadata(c, locations, paginationMetadata(c, pagination, locations, page, func(item *dto.Location) uuid.UUID {
+		return item.ID
+	}))
+}
+
+func (h *locationHandlerImpl) ExportLocations(c *gin.Context) {
+	pagination, format, err := bindExport(c)
+	if err != nil {
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	header := []string{"id", "name", "capacity"}
+	record := func(location *dto.Location) []string {
+		return []string{location.ID.String(), location.Name, strconv.FormatInt(location.Capacity, 10)}
 	}
 
-	if status, msg := utils.Validate(pagination); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	streamExport(c, "locations", format, header, record, func(fn func(*dto.Location) error) error {
+		return h.location.Export(c.Request.Context(), locationScope(c), pagination, fn)
+	})
+}
+
+func (h *locationHandlerImpl) GetLocationByID(c *gin.Context) {
+	locationID, err := uuid.Parse(c.Param("location_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
 		return
 	}
 
-	users, code, err := h.location.GetAll(pagination)
+	location, err := h.location.GetByID(c.Request.Context(), locationScope(c), locationID)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.OKWithMetadata(c, users, helpers.Metadata{
-		Page: page,
-		Size: size,
-	})
+	helpers.OK(c, location)
+}
+
+func (h *locationHandlerImpl) DeleteLocation(c *gin.Context) {
+	locationID, err := uuid.Parse(c.Param("location_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	if _, ok := currentUser(c); !ok {
+		return
+	}
+
+	if err := h.location.Delete(c.Request.Context(), locationScope(c), auditActor(c), locationID); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, "Successfully delete location")
+}
+
+func (h *locationHandlerImpl) RestoreLocation(c *gin.Context) {
+	locationID, err := uuid.Parse(c.Param("location_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	location, err := h.location.Restore(c.Request.Context(), locationScope(c), auditActor(c), locationID)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, location)
 }
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+)
+
+type OIDCHandler interface {
+	Login(c *gin.Context)
+	Callback(c *gin.Context)
+}
+
+type oidcHandlerImpl struct {
+	oidc services.OIDCService
+}
+
+func NewOIDCHandler(oidc services.OIDCService) OIDCHandler {
+	return &oidcHandlerImpl{oidc: oidc}
+}
+
+// Login sends the browser to the identity provider.
+func (h *oidcHandlerImpl) Login(c *gin.Context) {
+	authURL, err := h.oidc.StartLogin(c.Request.Context())
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback is where the identity provider sends the browser back to. It
+// answers like a password login: tokens, or an MFA challenge.
+func (h *oidcHandlerImpl) Callback(c *gin.Context) {
+	if reason := c.Query("error"); reason != "" {
+		helpers.UnauthorizedError(c, "single sign-on failed: "+reason)
+		return
+	}
+
+	code, state := c.Query("code"), c.Query("state")
+	if code == "" || state == "" {
+		helpers.BadRequestError(c, "code and state are required")
+		return
+	}
+
+	tokens, challenge, err := h.oidc.CompleteLogin(c.Request.Context(), auditActor(c), code, state)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	if challenge != nil {
+		helpers.OK(c, challenge)
+		return
+	}
+
+	helpers.OK(c, tokens)
+}
@@ -8,7 +8,6 @@ import (
 	"github.com/google/uuid"
 	"github.com/nabilwafi/warehouse-management-system/src/helpers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
-	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
 	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
@@ -16,7 +15,9 @@ import (
 type OrderHandler interface {
 	ReceiveOrder(c *gin.Context)
 	ShipOrder(c *gin.Context)
+	BatchOrders(c *gin.Context)
 	GetAllOrders(c *gin.Context)
+	ExportOrders(c *gin.Context)
 	GetOrderByID(c *gin.Context)
 }
 
@@ -31,64 +32,90 @@ func NewOrderHandler(order services.OrderService, validation *validator.Validate
 
 func (h *OrderHandlerImpl) ReceiveOrder(c *gin.Context) {
 	var order dto.OrderCreateRequest
-	if err := c.ShouldBindJSON(&order); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := bindJSON(c, &order); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	code, err := h.order.ReceiveOrder(&order)
+	created, err := h.order.ReceiveOrder(c.Request.Context(), locationScope(c), auditActor(c), &order)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.SuccessByCode(c, code, "Successfully Receive Data")
+	helpers.CreatedAt(c, resourcePath("orders", created.ID), created)
 }
 
 func (h *OrderHandlerImpl) ShipOrder(c *gin.Context) {
 	var order dto.OrderCreateRequest
-	if err := c.ShouldBindJSON(&order); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := bindJSON(c, &order); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	code, err := h.order.ShipOrder(&order)
+	created, err := h.order.ShipOrder(c.Request.Context(), locationScope(c), auditActor(c), &order)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.SuccessByCode(c, code, "Successfully Shipping Data")
+	helpers.CreatedAt(c, resourcePath("orders", created.ID), created)
 }
 
-func (h *OrderHandlerImpl) GetAllOrders(c *gin.Context) {
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+func (h *OrderHandlerImpl) BatchOrders(c *gin.Context) {
+	var batch dto.OrderBatchRequest
+	if err := bindJSON(c, &batch); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	result, err := h.order.BatchOrders(c.Request.Context(), locationScope(c), auditActor(c), &batch)
 	if err != nil {
-		page = 1
+		helpers.ErrorWithData(c, err, result)
+		return
 	}
 
-	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	helpers.OK(c, result)
+}
+
+func (h *OrderHandlerImpl) GetAllOrders(c *gin.Context) {
+	pagination, err := bindPagination(c)
 	if err != nil {
-		size = 10
+		helpers.BadRequestError(c, err.Error())
+		return
 	}
 
-	pagination := &web.PaginationRequest{
-		Page: page,
-		Size: size,
+	if err := utils.Validate(pagination); err != nil {
+		helpers.Error(c, err)
+		return
 	}
 
-	if status, msg := utils.Validate(pagination); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	orders, page, err := h.order.GetAllOrders(c.Request.Context(), locationScope(c), pagination)
+	if err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	users, code, err := h.order.GetAllOrders(pagination)
+	helpers.OKWithMetadata(c, orders, paginationMetadata(c, pagination, orders, page, func(item *dto.Order) uuid.UUID {
+		return item.ID
+	}))
+}
+
+func (h *OrderHandlerImpl) ExportOrders(c *gin.Context) {
+	pagination, format, err := bindExport(c)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.BadRequestError(c, err.Error())
 		return
 	}
 
-	helpers.OK(c, users)
+	header := []string{"id", "type", "product_id", "quantity"}
+	record := func(order *dto.Order) []string {
+		return []string{order.ID.String(), string(order.Type), order.ProductID.String(), strconv.FormatInt(order.Quantity, 10)}
+	}
+
+	streamExport(c, "orders", format, header, record, func(fn func(*dto.Order) error) error {
+		return h.order.ExportOrders(c.Request.Context(), locationScope(c), pagination, fn)
+	})
 }
 
 func (h *OrderHandlerImpl) GetOrderByID(c *gin.Context) {
@@ -100,11 +127,11 @@ func (h *OrderHandlerImpl) GetOrderByID(c *gin.Context) {
 		return
 	}
 
-	users, code, err := h.order.GetOrderByID(orderIDConv)
+	users, err := h.order.GetOrderByID(c.Request.Context(), locationScope(c), orderIDConv)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.SuccessByCode(c, code, users)
+	helpers.OK(c, users)
 }
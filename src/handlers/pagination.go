@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// maxPageSize keeps a single request from reading the whole table.
+const maxPageSize = 100
+
+// bindPagination reads page/size from the query string. Passing a cursor
+// parameter (empty for the first page) switches the listing to keyset mode.
+func bindPagination(c *gin.Context) (*web.PaginationRequest, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return nil, fmt.Errorf("page must be a number of at least 1")
+	}
+
+	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	if err != nil || size < 1 || size > maxPageSize {
+		return nil, fmt.Errorf("size must be a number between 1 and %d", maxPageSize)
+	}
+
+	pagination := &web.PaginationRequest{
+		Page: page,
+		Size: size,
+	}
+
+	if cursor, ok := c.GetQuery("cursor"); ok {
+		after, err := utils.DecodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		pagination.Page = 0
+		pagination.Keyset = true
+		pagination.After = after
+	}
+
+	return pagination, nil
+}
+
+// paginationMetadata builds the list metadata and sets the RFC 8288 Link
+// header pointing at the neighbouring pages.
+func paginationMetadata[T any](c *gin.Context, pagination *web.PaginationRequest, items []T, page *web.PageInfo, id func(T) uuid.UUID) helpers.Metadata {
+	metadata := helpers.Metadata{
+		Page:    pagination.Page,
+		Size:    pagination.Size,
+		HasNext: page.HasNext,
+	}
+
+	var links []string
+
+	if pagination.Keyset {
+		links = append(links, pageLink(c, "first", "cursor", ""))
+
+		if page.HasNext && len(items) > 0 {
+			metadata.NextCursor = utils.EncodeCursor(id(items[len(items)-1]))
+			links = append(links, pageLink(c, "next", "cursor", metadata.NextCursor))
+		}
+	} else {
+		totalItems := page.TotalItems
+		totalPages := int64(0)
+		if pagination.Size > 0 {
+			totalPages = (totalItems + int64(pagination.Size) - 1) / int64(pagination.Size)
+		}
+
+		metadata.TotalItems = &totalItems
+		metadata.TotalPages = &totalPages
+
+		links = append(links, pageLink(c, "first", "page", "1"))
+		if pagination.Page > 1 {
+			links = append(links, pageLink(c, "prev", "page", strconv.Itoa(pagination.Page-1)))
+		}
+		if page.HasNext {
+			links = append(links, pageLink(c, "next", "page", strconv.Itoa(pagination.Page+1)))
+		}
+		if totalPages > 0 {
+			links = append(links, pageLink(c, "last", "page", strconv.FormatInt(totalPages, 10)))
+		}
+	}
+
+	c.Header("Link", strings.Join(links, ", "))
+
+	return metadata
+}
+
+func pageLink(c *gin.Context, rel, key, value string) string {
+	target := *c.Request.URL
+	query := target.Query()
+	query.Set(key, value)
+	target.RawQuery = query.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, target.String(), rel)
+}
@@ -1,15 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
+	"net/http"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/nabilwafi/warehouse-management-system/src/helpers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
-	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
 	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
@@ -17,9 +21,13 @@ import (
 type ProductHandler interface {
 	AddProduct(c *gin.Context)
 	GetAllProducts(c *gin.Context)
+	ExportProducts(c *gin.Context)
 	GetProductByID(c *gin.Context)
 	UpdateProduct(c *gin.Context)
+	PatchProduct(c *gin.Context)
 	DeleteProduct(c *gin.Context)
+	RestoreProduct(c *gin.Context)
+	ImportProducts(c *gin.Context)
 }
 
 type ProductHandlerImpl struct {
@@ -36,55 +44,62 @@ func NewProductHandler(product services.ProductService, validation *validator.Va
 
 func (h *ProductHandlerImpl) AddProduct(c *gin.Context) {
 	var product dto.Product
-	if err := c.ShouldBindJSON(&product); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := bindJSON(c, &product); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	if status, msg := utils.Validate(&product); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	if err := utils.Validate(&product); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	code, err := h.product.Create(&product)
+	created, err := h.product.Create(c.Request.Context(), locationScope(c), auditActor(c), &product)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.Created(c, "Successfuly Created Data")
+	helpers.CreatedAt(c, resourcePath("products", created.ID), created)
 }
 
 func (h *ProductHandlerImpl) GetAllProducts(c *gin.Context) {
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
-	if err != nil {
-		page = 1
-	}
-
-	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	pagination, err := bindPagination(c)
 	if err != nil {
-		size = 10
+		helpers.BadRequestError(c, err.Error())
+		return
 	}
 
-	pagination := &web.PaginationRequest{
-		Page: page,
-		Size: size,
+	if err := utils.Validate(pagination); err != nil {
+		helpers.Error(c, err)
+		return
 	}
 
-	if status, msg := utils.Validate(pagination); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	products, page, err := h.product.GetAll(c.Request.Context(), locationScope(c), pagination)
+	if err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	users, code, err := h.product.GetAll(pagination)
+	helpers.OKWithMetadata(c, products, paginationMetadata(c, pagination, products, page, func(item *dto.Product) uuid.UUID {
+		return item.ID
+	}))
+}
+
+func (h *ProductHandlerImpl) ExportProducts(c *gin.Context) {
+	pagination, format, err := bindExport(c)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.BadRequestError(c, err.Error())
 		return
 	}
 
-	helpers.OKWithMetadata(c, users, helpers.Metadata{
-		Page: page,
-		Size: size,
+	header := []string{"id", "name", "sku", "quantity", "location_id"}
+	record := func(product *dto.Product) []string {
+		return []string{product.ID.String(), product.Name, product.SKU, strconv.FormatInt(product.Quantity, 10), product.LocationID.String()}
+	}
+
+	streamExport(c, "products", format, header, record, func(fn func(*dto.Product) error) error {
+		return h.product.Export(c.Request.Context(), locationScope(c), pagination, fn)
 	})
 }
 
@@ -97,40 +112,74 @@ func (h *ProductHandlerImpl) GetProductByID(c *gin.Context) {
 		return
 	}
 
-	users, code, err := h.product.GetByID(productIDConv)
+	product, err := h.product.GetByID(c.Request.Context(), locationScope(c), productIDConv)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.SuccessByCode(c, code, users)
+	c.Header("ETag", versionETag(product.Version))
+	helpers.OK(c, product)
 }
 
+// UpdateProduct replaces the whole product, so it must name the version it
+// was based on in If-Match.
 func (h *ProductHandlerImpl) UpdateProduct(c *gin.Context) {
 	productID := c.Param("product_id")
 
-	fmt.Println(productID)
-
 	productDConv, err := uuid.Parse(productID)
 	if err != nil {
 		helpers.BadRequestError(c, "not uuid")
 		return
 	}
 
+	version, ok := ifMatchVersion(c)
+	if !ok {
+		helpers.ErrorByCode(c, http.StatusPreconditionRequired, "If-Match header is required")
+		return
+	}
+
 	var product dto.Product
+	if err := bindJSON(c, &product); err != nil {
+		helpers.Error(c, err)
+		return
+	}
 	product.ID = productDConv
+	product.Version = version
 
-	if err := c.ShouldBindJSON(&product); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := h.product.Update(c.Request.Context(), locationScope(c), auditActor(c), &product); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
+	helpers.OK(c, "Successfully Updated Data")
+}
+
+// PatchProduct changes only the fields sent and never the quantity. If-Match
+// is optional here.
+func (h *ProductHandlerImpl) PatchProduct(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	var patch dto.ProductPatch
+	if err := bindJSON(c, &patch); err != nil {
+		helpers.Error(c, err)
+		return
 	}
 
-	code, err := h.product.Update(&product)
+	version, _ := ifMatchVersion(c)
+	product, err := h.product.Patch(c.Request.Context(), locationScope(c), auditActor(c), productID, &patch, version)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.OK(c, "Successfully Updated Data")
+	c.Header("ETag", versionETag(product.Version))
+	helpers.OK(c, product)
 }
 
 func (h *ProductHandlerImpl) DeleteProduct(c *gin.Context) {
@@ -142,11 +191,163 @@ func (h *ProductHandlerImpl) DeleteProduct(c *gin.Context) {
 		return
 	}
 
-	code, err := h.product.Delete(productIDConv)
-	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+	if _, ok := currentUser(c); !ok {
+		return
+	}
+
+	if err := h.product.Delete(c.Request.Context(), locationScope(c), auditActor(c), productIDConv); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
 	helpers.OK(c, "Successfully delete product")
 }
+
+func (h *ProductHandlerImpl) RestoreProduct(c *gin.Context) {
+	productID, err := uuid.Parse(c.Param("product_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	product, err := h.product.Restore(c.Request.Context(), locationScope(c), auditActor(c), productID)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	c.Header("ETag", versionETag(product.Version))
+	helpers.OK(c, product)
+}
+
+// maxImportSize caps the upload so a huge file can't exhaust memory or disk
+// while the multipart form is parsed.
+const maxImportSize = 10 << 20
+
+func (h *ProductHandlerImpl) ImportProducts(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	file, header, err := c.Request.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		helpers.ErrorByCode(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file must not exceed %d MB", maxImportSize>>20))
+		return
+	}
+	if err != nil {
+		helpers.BadRequestError(c, "file is required")
+		return
+	}
+	defer file.Close()
+
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(header.Filename)) {
+	case ".csv":
+		records, err = utils.ReadCSV(file)
+	case ".xlsx":
+		records, err = utils.ReadXLSX(file)
+	default:
+		helpers.BadRequestError(c, "file must be csv or xlsx")
+		return
+	}
+	if err != nil {
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	rows, err := parseProductRows(records)
+	if err != nil {
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		helpers.BadRequestError(c, "dry_run must be a boolean")
+		return
+	}
+
+	report, err := h.product.Import(c.Request.Context(), locationScope(c), auditActor(c), rows, dryRun)
+	if err != nil {
+		helpers.ErrorWithData(c, err, report)
+		return
+	}
+
+	helpers.OK(c, report)
+}
+
+var productImportColumns = []string{"name", "sku", "quantity", "location_id"}
+
+func parseProductRows(records [][]string) ([]*dto.ProductImportRow, error) {
+	if len(records) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	columns := make(map[string]int)
+	for i, column := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+
+	for _, column := range productImportColumns {
+		if _, ok := columns[column]; !ok {
+			return nil, fmt.Errorf("missing column %s", column)
+		}
+	}
+
+	var rows []*dto.ProductImportRow
+	for i, record := range records[1:] {
+		if isBlankRecord(record) {
+			continue
+		}
+
+		field := func(column string) string {
+			if idx := columns[column]; idx < len(record) {
+				return strings.TrimSpace(record[idx])
+			}
+			return ""
+		}
+
+		row := &dto.ProductImportRow{Row: i + 2}
+		product := &dto.Product{
+			Name: field("name"),
+			SKU:  field("sku"),
+		}
+
+		quantity, err := strconv.ParseInt(field("quantity"), 10, 64)
+		if err != nil {
+			row.Errors = append(row.Errors, "quantity must be an integer")
+		}
+		product.Quantity = quantity
+
+		locationID, err := uuid.Parse(field("location_id"))
+		if err != nil {
+			row.Errors = append(row.Errors, "location_id must be a uuid")
+		}
+		product.LocationID = locationID
+
+		if err := binding.Validator.ValidateStruct(product); err != nil {
+			var ve validator.ValidationErrors
+			if errors.As(err, &ve) {
+				for _, fe := range ve {
+					row.Errors = append(row.Errors, fmt.Sprintf("data not valid : %s (%s)", fe.Field(), fe.Tag()))
+				}
+			} else {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+
+		row.Product = product
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"github.com/google/uuid"
+)
+
+// apiBasePath matches the group the routes are mounted under.
+const apiBasePath = "/api/v1"
+
+// resourcePath is where a single resource of collection can be fetched,
+// e.g. /api/v1/products/<id>, used for the Location header on 201s.
+func resourcePath(collection string, id uuid.UUID) string {
+	return apiBasePath + "/" + collection + "/" + id.String()
+}
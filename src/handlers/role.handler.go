@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+)
+
+type RoleHandler interface {
+	ListRoles(c *gin.Context)
+	CreateRole(c *gin.Context)
+	UpdateRole(c *gin.Context)
+	DeleteRole(c *gin.Context)
+	ListPermissions(c *gin.Context)
+}
+
+type roleHandlerImpl struct {
+	permission services.PermissionService
+}
+
+func NewRoleHandler(permission services.PermissionService) RoleHandler {
+	return &roleHandlerImpl{permission: permission}
+}
+
+func (h *roleHandlerImpl) ListRoles(c *gin.Context) {
+	roles, err := h.permission.ListRoles(c.Request.Context())
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, roles)
+}
+
+func (h *roleHandlerImpl) CreateRole(c *gin.Context) {
+	var role dto.Role
+
+	if err := bindJSON(c, &role); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	if err := h.permission.CreateRole(c.Request.Context(), &role); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.Created(c, role)
+}
+
+func (h *roleHandlerImpl) UpdateRole(c *gin.Context) {
+	var request dto.RoleUpdateRequest
+
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	role, err := h.permission.UpdateRole(c.Request.Context(), dto.UserRole(c.Param("role")), &request)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, role)
+}
+
+func (h *roleHandlerImpl) DeleteRole(c *gin.Context) {
+	if err := h.permission.DeleteRole(c.Request.Context(), dto.UserRole(c.Param("role"))); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, "Successfully Deleted Role")
+}
+
+func (h *roleHandlerImpl) ListPermissions(c *gin.Context) {
+	permissions, err := h.permission.ListPermissions(c.Request.Context())
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, permissions)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// locationScope reads the locations granted by the caller's token. Without a
+// token the scope is nil, which the services treat as no access.
+func locationScope(c *gin.Context) *dto.LocationScope {
+	user, exists := c.Get("user")
+	if !exists {
+		return nil
+	}
+
+	claims, ok := user.(*utils.CustomClaims)
+	if !ok {
+		return nil
+	}
+
+	return &claims.Scope
+}
+
+// currentUser returns the caller's claims, or answers 401 and returns false.
+func currentUser(c *gin.Context) (*utils.CustomClaims, bool) {
+	user, exists := c.Get("user")
+	if !exists {
+		helpers.UnauthorizedError(c, "please login first")
+		return nil, false
+	}
+
+	claims, ok := user.(*utils.CustomClaims)
+	if !ok {
+		helpers.InternalServerError(c, "internal server error")
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// auditActor describes the caller for the audit log. Without a token the
+// change is still recorded, just without a user.
+func auditActor(c *gin.Context) *dto.AuditActor {
+	actor := &dto.AuditActor{
+		IP:        c.ClientIP(),
+		RequestID: utils.RequestID(c.Request.Context()),
+	}
+
+	if user, exists := c.Get("user"); exists {
+		if claims, ok := user.(*utils.CustomClaims); ok {
+			actor.UserID = claims.ID
+		}
+	}
+
+	return actor
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+)
+
+type ServiceAccountHandler interface {
+	CreateServiceAccount(c *gin.Context)
+	CreateAPIKey(c *gin.Context)
+	ListAPIKeys(c *gin.Context)
+	RevokeAPIKey(c *gin.Context)
+}
+
+type serviceAccountHandlerImpl struct {
+	serviceAccount services.ServiceAccountService
+}
+
+func NewServiceAccountHandler(serviceAccount services.ServiceAccountService) ServiceAccountHandler {
+	return &serviceAccountHandlerImpl{serviceAccount: serviceAccount}
+}
+
+func (h *serviceAccountHandlerImpl) CreateServiceAccount(c *gin.Context) {
+	var request dto.ServiceAccountRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	account, err := h.serviceAccount.CreateServiceAccount(c.Request.Context(), auditActor(c), &request)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, account)
+}
+
+func (h *serviceAccountHandlerImpl) CreateAPIKey(c *gin.Context) {
+	claims, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	serviceAccountID, err := uuid.Parse(c.Param("service_account_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	var request dto.APIKeyRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	key, err := h.serviceAccount.CreateAPIKey(c.Request.Context(), claims.ID, serviceAccountID, &request)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, key)
+}
+
+func (h *serviceAccountHandlerImpl) ListAPIKeys(c *gin.Context) {
+	serviceAccountID, err := uuid.Parse(c.Param("service_account_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	keys, err := h.serviceAccount.GetAPIKeys(c.Request.Context(), serviceAccountID)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, keys)
+}
+
+func (h *serviceAccountHandlerImpl) RevokeAPIKey(c *gin.Context) {
+	serviceAccountID, err := uuid.Parse(c.Param("service_account_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	keyID, err := uuid.Parse(c.Param("key_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	if err := h.serviceAccount.RevokeAPIKey(c.Request.Context(), serviceAccountID, keyID); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, "Successfully Revoked API Key")
+}
@@ -4,18 +4,35 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/nabilwafi/warehouse-management-system/src/helpers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
-	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
 	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
 
 type UserHandler interface {
 	Login(c *gin.Context)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
 	Register(c *gin.Context)
 	GetMe(c *gin.Context)
 	ListUsers(c *gin.Context)
+	SetLocationScope(c *gin.Context)
+	UpdateUser(c *gin.Context)
+	DeactivateUser(c *gin.Context)
+	ActivateUser(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	CreatePasswordReset(c *gin.Context)
+	ResetPassword(c *gin.Context)
+	CreateInvite(c *gin.Context)
+	ListAuthEvents(c *gin.Context)
+	VerifyMFA(c *gin.Context)
+	EnrollMFAWithChallenge(c *gin.Context)
+	StartMFAEnrollment(c *gin.Context)
+	ConfirmMFAEnrollment(c *gin.Context)
+	DisableMFA(c *gin.Context)
+	ResetMFA(c *gin.Context)
 }
 
 type userHandlerImpl struct {
@@ -29,19 +46,18 @@ func NewUserHandlerImpl(user services.UserService) UserHandler {
 func (h *userHandlerImpl) Register(c *gin.Context) {
 	var register dto.RegisterRequest
 
-	if err := c.ShouldBindJSON(&register); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := bindJSON(c, &register); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	if status, msg := utils.Validate(&register); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	if err := utils.Validate(&register); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	code, err := h.user.Register(&register)
-	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+	if err := h.user.Register(c.Request.Context(), auditActor(c), &register); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
@@ -51,23 +67,74 @@ func (h *userHandlerImpl) Register(c *gin.Context) {
 func (h *userHandlerImpl) Login(c *gin.Context) {
 	var login dto.LoginRequest
 
-	if err := c.ShouldBindJSON(&login); err != nil {
-		helpers.BadRequestError(c, err.Error())
+	if err := bindJSON(c, &login); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	if status, msg := utils.Validate(&login); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	if err := utils.Validate(&login); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	token, code, err := h.user.Login(&login)
+	tokens, challenge, err := h.user.Login(c.Request.Context(), &login, c.ClientIP())
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
+		return
+	}
+
+	if challenge != nil {
+		helpers.OK(c, challenge)
+		return
+	}
+
+	helpers.OK(c, tokens)
+}
+
+func (h *userHandlerImpl) Refresh(c *gin.Context) {
+	var request dto.RefreshTokenRequest
+
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	tokens, err := h.user.Refresh(c.Request.Context(), request.RefreshToken)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, tokens)
+}
+
+func (h *userHandlerImpl) Logout(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		helpers.UnauthorizedError(c, "please login first")
+		return
+	}
+
+	userData, ok := user.(*utils.CustomClaims)
+	if !ok {
+		helpers.InternalServerError(c, "internal server error")
+		return
+	}
+
+	var request dto.LogoutRequest
+	if c.Request.ContentLength > 0 {
+		if err := bindJSON(c, &request); err != nil {
+			helpers.Error(c, err)
+			return
+		}
+	}
+
+	if err := h.user.Logout(c.Request.Context(), userData, request.RefreshToken); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.OK(c, token)
+	helpers.OK(c, "Successfully Logout")
 }
 
 func (h *userHandlerImpl) GetMe(c *gin.Context) {
@@ -83,9 +150,9 @@ func (h *userHandlerImpl) GetMe(c *gin.Context) {
 		return
 	}
 
-	user, code, err := h.user.GetUserByID(userData.ID)
+	user, err := h.user.GetUserByID(c.Request.Context(), userData.ID)
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.Error(c, err)
 		return
 	}
 
@@ -93,34 +160,323 @@ func (h *userHandlerImpl) GetMe(c *gin.Context) {
 }
 
 func (h *userHandlerImpl) ListUsers(c *gin.Context) {
-	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pagination, err := bindPagination(c)
+	if err != nil {
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	if err := utils.Validate(pagination); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	users, page, err := h.user.GetAllUser(c.Request.Context(), pagination)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OKWithMetadata(c, users, paginationMetadata(c, pagination, users, page, func(item *dto.User) uuid.UUID {
+		return item.ID
+	}))
+}
+
+func (h *userHandlerImpl) SetLocationScope(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	var scope dto.LocationScope
+	if err := bindJSON(c, &scope); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	user, err := h.user.SetLocationScope(c.Request.Context(), auditActor(c), userID, &scope)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, user)
+}
+
+func (h *userHandlerImpl) UpdateUser(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	var request dto.UserUpdateRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	user, err := h.user.UpdateUser(c.Request.Context(), auditActor(c), userID, &request)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, user)
+}
+
+func (h *userHandlerImpl) DeactivateUser(c *gin.Context) {
+	h.setActive(c, false)
+}
+
+func (h *userHandlerImpl) ActivateUser(c *gin.Context) {
+	h.setActive(c, true)
+}
+
+func (h *userHandlerImpl) setActive(c *gin.Context, active bool) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	if err := h.user.SetActive(c.Request.Context(), auditActor(c), userID, active); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	if active {
+		helpers.OK(c, "Successfully Activated User")
+		return
+	}
+
+	helpers.OK(c, "Successfully Deactivated User")
+}
+
+func (h *userHandlerImpl) ChangePassword(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		helpers.UnauthorizedError(c, "please login first")
+		return
+	}
+
+	userData, ok := user.(*utils.CustomClaims)
+	if !ok {
+		helpers.InternalServerError(c, "internal server error")
+		return
+	}
+
+	var request dto.PasswordChangeRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	if err := h.user.ChangePassword(c.Request.Context(), auditActor(c), userData.ID, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, "Successfully Changed Password")
+}
+
+func (h *userHandlerImpl) CreatePasswordReset(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	reset, err := h.user.CreatePasswordReset(c.Request.Context(), userID)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, reset)
+}
+
+func (h *userHandlerImpl) ResetPassword(c *gin.Context) {
+	var request dto.PasswordResetRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	if err := h.user.ResetPassword(c.Request.Context(), auditActor(c), &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, "Successfully Reset Password")
+}
+
+func (h *userHandlerImpl) CreateInvite(c *gin.Context) {
+	user, exists := c.Get("user")
+	if !exists {
+		helpers.UnauthorizedError(c, "please login first")
+		return
+	}
+
+	userData, ok := user.(*utils.CustomClaims)
+	if !ok {
+		helpers.InternalServerError(c, "internal server error")
+		return
+	}
+
+	var request dto.InviteRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	invite, err := h.user.CreateInvite(c.Request.Context(), userData.ID, &request)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, invite)
+}
+
+func (h *userHandlerImpl) ListAuthEvents(c *gin.Context) {
+	pagination, err := bindPagination(c)
+	if err != nil {
+		helpers.BadRequestError(c, err.Error())
+		return
+	}
+
+	if err := utils.Validate(pagination); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	filter := &dto.AuthEventFilter{
+		Email: c.Query("email"),
+		IP:    c.Query("ip"),
+	}
+
+	if value, ok := c.GetQuery("success"); ok {
+		success, err := strconv.ParseBool(value)
+		if err != nil {
+			helpers.BadRequestError(c, "success must be true or false")
+			return
+		}
+
+		filter.Success = &success
+	}
+
+	events, page, err := h.user.GetAuthEvents(c.Request.Context(), filter, pagination)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OKWithMetadata(c, events, paginationMetadata(c, pagination, events, page, func(item *dto.AuthEvent) uuid.UUID {
+		return item.ID
+	}))
+}
+
+func (h *userHandlerImpl) VerifyMFA(c *gin.Context) {
+	var request dto.MFAVerifyRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	tokens, err := h.user.VerifyMFA(c.Request.Context(), auditActor(c), &request)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, tokens)
+}
+
+func (h *userHandlerImpl) EnrollMFAWithChallenge(c *gin.Context) {
+	var request dto.MFAChallengeRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	enrollment, err := h.user.StartMFAEnrollmentWithChallenge(c.Request.Context(), auditActor(c), request.MFAToken)
+	if err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, enrollment)
+}
+
+func (h *userHandlerImpl) StartMFAEnrollment(c *gin.Context) {
+	claims, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.user.StartMFAEnrollment(c.Request.Context(), auditActor(c), claims.ID)
 	if err != nil {
-		page = 1
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, enrollment)
+}
+
+func (h *userHandlerImpl) ConfirmMFAEnrollment(c *gin.Context) {
+	claims, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	var request dto.MFACodeRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
+		return
 	}
 
-	size, err := strconv.Atoi(c.DefaultQuery("size", "10"))
+	result, err := h.user.ConfirmMFAEnrollment(c.Request.Context(), auditActor(c), claims.ID, request.Code)
 	if err != nil {
-		size = 10
+		helpers.Error(c, err)
+		return
 	}
 
-	pagination := &web.PaginationRequest{
-		Page: page,
-		Size: size,
+	helpers.OK(c, result)
+}
+
+func (h *userHandlerImpl) DisableMFA(c *gin.Context) {
+	claims, ok := currentUser(c)
+	if !ok {
+		return
 	}
 
-	if status, msg := utils.Validate(pagination); msg != "" {
-		helpers.ErrorByCode(c, status, msg)
+	var request dto.MFACodeRequest
+	if err := bindJSON(c, &request); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	users, code, err := h.user.GetAllUser(pagination)
+	if err := h.user.DisableMFA(c.Request.Context(), auditActor(c), claims, request.Code); err != nil {
+		helpers.Error(c, err)
+		return
+	}
+
+	helpers.OK(c, "Successfully Disabled Two-Factor Authentication")
+}
+
+func (h *userHandlerImpl) ResetMFA(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("user_id"))
 	if err != nil {
-		helpers.ErrorByCode(c, code, err.Error())
+		helpers.BadRequestError(c, "not uuid")
+		return
+	}
+
+	if err := h.user.ResetMFA(c.Request.Context(), auditActor(c), userID); err != nil {
+		helpers.Error(c, err)
 		return
 	}
 
-	helpers.OKWithMetadata(c, users, helpers.Metadata{
-		Page: page,
-		Size: size,
-	})
+	helpers.OK(c, "Successfully Reset Two-Factor Authentication")
 }
@@ -0,0 +1,59 @@
+package helpers
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix namespaces the type URI of every problem; the suffix is
+// the error_code clients already know from the BaseResponse envelope.
+const problemTypePrefix = "urn:warehouse:problem:"
+
+// Problem is an RFC 7807 problem document. Code, Errors and Data are
+// extension members.
+type Problem struct {
+	Type     string              `json:"type"`
+	Title    string              `json:"title"`
+	Status   int                 `json:"status"`
+	Detail   string              `json:"detail,omitempty"`
+	Instance string              `json:"instance,omitempty"`
+	Code     string              `json:"code,omitempty"`
+	Errors   []domain.FieldError `json:"errors,omitempty"`
+	Data     any                 `json:"data,omitempty"`
+}
+
+// WantsProblem reports whether the client opted into problem documents by
+// listing application/problem+json in its Accept header. Everyone else keeps
+// getting the BaseResponse envelope.
+func WantsProblem(c *gin.Context) bool {
+	for _, accept := range c.Request.Header.Values("Accept") {
+		for _, part := range strings.Split(accept, ",") {
+			mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+			if err == nil && mediaType == ProblemContentType {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func writeProblem(c *gin.Context, status int, code string, msg string, fields []domain.FieldError, data any) {
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(status, &Problem{
+		Type:     problemTypePrefix + code,
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   msg,
+		Instance: c.Request.URL.Path,
+		Code:     code,
+		Errors:   fields,
+		Data:     data,
+	})
+}
@@ -1,22 +1,31 @@
 package helpers
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 )
 
 type BaseResponse struct {
-	Status       string `json:"status"`
-	StatusCode   int    `json:"status_code"`
-	Data         any    `json:"data,omitempty"`
-	ErrorMessage any    `json:"error_message,omitempty"`
-	Metadata     `json:"metadata,omitempty"`
+	Status       string              `json:"status"`
+	StatusCode   int                 `json:"status_code"`
+	Data         any                 `json:"data,omitempty"`
+	ErrorMessage any                 `json:"error_message,omitempty"`
+	ErrorCode    string              `json:"error_code,omitempty"`
+	Errors       []domain.FieldError `json:"errors,omitempty"`
+	Metadata     *Metadata           `json:"metadata,omitempty"`
 }
 
 type Metadata struct {
-	Page int `json:"page,omitempty"`
-	Size int `json:"size,omitempty"`
+	Page       int    `json:"page,omitempty"`
+	Size       int    `json:"size,omitempty"`
+	TotalItems *int64 `json:"total_items,omitempty"`
+	TotalPages *int64 `json:"total_pages,omitempty"`
+	HasNext    bool   `json:"has_next"`
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 func OK(c *gin.Context, data any) {
@@ -32,7 +41,7 @@ func OKWithMetadata(c *gin.Context, data any, pagination Metadata) {
 		Status:     "OK",
 		StatusCode: http.StatusOK,
 		Data:       data,
-		Metadata:   pagination,
+		Metadata:   &pagination,
 	})
 }
 
@@ -40,69 +49,120 @@ func Created(c *gin.Context, data any) {
 	c.JSON(http.StatusCreated, &BaseResponse{
 		Status:     "CREATED",
 		StatusCode: http.StatusCreated,
+		Data:       data,
 	})
 }
 
+// CreatedAt answers 201 with the new resource and a Location header pointing
+// to where it can be fetched.
+func CreatedAt(c *gin.Context, location string, data any) {
+	c.Header("Location", location)
+	Created(c, data)
+}
+
 func BadRequestError(c *gin.Context, msg string) {
-	c.JSON(http.StatusBadRequest, &BaseResponse{
-		Status:       "BAD REQUEST",
-		StatusCode:   http.StatusBadRequest,
-		ErrorMessage: msg,
-	})
+	ErrorByCode(c, http.StatusBadRequest, msg)
 }
 
 func NotFoundError(c *gin.Context, msg string) {
-	c.JSON(http.StatusNotFound, &BaseResponse{
-		Status:       "NOT FOUND",
-		StatusCode:   http.StatusNotFound,
-		ErrorMessage: msg,
-	})
+	ErrorByCode(c, http.StatusNotFound, msg)
 }
 
 func UnauthorizedError(c *gin.Context, msg string) {
-	c.JSON(http.StatusUnauthorized, &BaseResponse{
-		Status:       "UNAUTHORIZED",
-		StatusCode:   http.StatusUnauthorized,
-		ErrorMessage: msg,
-	})
+	ErrorByCode(c, http.StatusUnauthorized, msg)
 }
 
 func ForbiddenError(c *gin.Context, msg string) {
-	c.JSON(http.StatusForbidden, &BaseResponse{
-		Status:       "FORBIDDEN",
-		StatusCode:   http.StatusForbidden,
-		ErrorMessage: msg,
-	})
+	ErrorByCode(c, http.StatusForbidden, msg)
+}
+
+func ConflictError(c *gin.Context, msg string) {
+	ErrorByCode(c, http.StatusConflict, msg)
+}
+
+func UnprocessableEntityError(c *gin.Context, msg string, data any) {
+	errorResponse(c, http.StatusUnprocessableEntity, codeByStatus[http.StatusUnprocessableEntity], msg, nil, data)
+}
+
+func TooManyRequestsError(c *gin.Context, msg string) {
+	ErrorByCode(c, http.StatusTooManyRequests, msg)
 }
 
 func InternalServerError(c *gin.Context, msg string) {
-	c.JSON(http.StatusInternalServerError, &BaseResponse{
-		Status:       "INTERNAL SERVER ERROR",
-		StatusCode:   http.StatusInternalServerError,
-		ErrorMessage: msg,
-	})
+	ErrorByCode(c, http.StatusInternalServerError, msg)
+}
+
+// codeByStatus is the error_code used when a handler answers with a bare
+// status instead of a domain error.
+var codeByStatus = map[int]string{
+	http.StatusBadRequest:          "bad_request",
+	http.StatusUnauthorized:        "unauthorized",
+	http.StatusForbidden:           "forbidden",
+	http.StatusNotFound:            "not_found",
+	http.StatusConflict:            "conflict",
+	http.StatusUnprocessableEntity: "unprocessable_entity",
+	http.StatusTooManyRequests:     "too_many_requests",
+	http.StatusInternalServerError: "internal_error",
 }
 
+// ErrorByCode answers with a status and message chosen by the handler. Prefer
+// Error for failures coming from services, which keeps their error code.
 func ErrorByCode(c *gin.Context, errorCode int, msg string) {
-	switch errorCode {
-	case 400:
-		BadRequestError(c, msg)
-	case 401:
-		UnauthorizedError(c, msg)
-	case 403:
-		ForbiddenError(c, msg)
-	case 404:
-		NotFoundError(c, msg)
-	default:
-		InternalServerError(c, msg)
+	code, ok := codeByStatus[errorCode]
+	if !ok {
+		code = strings.ReplaceAll(strings.ToLower(http.StatusText(errorCode)), " ", "_")
+	}
+
+	errorResponse(c, errorCode, code, msg, nil, nil)
+}
+
+var statusByKind = map[domain.Kind]int{
+	domain.KindValidation:         http.StatusBadRequest,
+	domain.KindUnauthorized:       http.StatusUnauthorized,
+	domain.KindForbidden:          http.StatusForbidden,
+	domain.KindNotFound:           http.StatusNotFound,
+	domain.KindConflict:           http.StatusConflict,
+	domain.KindPreconditionFailed: http.StatusPreconditionFailed,
+	domain.KindInsufficientStock:  http.StatusConflict,
+	domain.KindUnprocessable:      http.StatusUnprocessableEntity,
+	domain.KindTooManyRequests:    http.StatusTooManyRequests,
+}
+
+// Error writes the response for an error returned by a service. Domain errors
+// keep their message and code; anything else is reported as an internal
+// error and its details only reach the request log.
+func Error(c *gin.Context, err error) {
+	ErrorWithData(c, err, nil)
+}
+
+// ErrorWithData is Error for failures that come with a report, such as the
+// per-line results of a rejected batch.
+func ErrorWithData(c *gin.Context, err error, data any) {
+	var domainErr *domain.Error
+	if !errors.As(err, &domainErr) || domainErr.Kind == domain.KindInternal {
+		c.Error(err)
+		errorResponse(c, http.StatusInternalServerError, "internal_error", "internal server error", nil, data)
+		return
 	}
+
+	errorResponse(c, statusByKind[domainErr.Kind], domainErr.Code, domainErr.Message, domainErr.Fields, data)
 }
 
-func SuccessByCode(c *gin.Context, successCode int, data any) {
-	switch successCode {
-	case 200:
-		OK(c, data)
-	default:
-		Created(c, data)
+// errorResponse writes the error in the format the client asked for: a
+// problem document when it accepts application/problem+json, the
+// BaseResponse envelope otherwise.
+func errorResponse(c *gin.Context, status int, code string, msg string, fields []domain.FieldError, data any) {
+	if WantsProblem(c) {
+		writeProblem(c, status, code, msg, fields, data)
+		return
 	}
+
+	c.JSON(status, &BaseResponse{
+		Status:       strings.ToUpper(http.StatusText(status)),
+		StatusCode:   status,
+		Data:         data,
+		ErrorMessage: msg,
+		ErrorCode:    code,
+		Errors:       fields,
+	})
 }
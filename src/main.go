@@ -1,60 +1,179 @@
 package main
 
 import (
-	"log"
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/joho/godotenv"
 	"github.com/nabilwafi/warehouse-management-system/src/config"
 	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/repositories"
 	"github.com/nabilwafi/warehouse-management-system/src/routes"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
 
 func init() {
 	err := godotenv.Load(".env")
 	if err != nil {
-		log.Fatal("Error loading .env file")
-		return
+		fatal("Error loading .env file", err)
 	}
 }
 
 func main() {
 	env, err := config.NewEnv()
 	if err != nil {
-		log.Fatal("Error loading .env file")
-		return
+		fatal("Error loading .env file", err)
+	}
+
+	logger := utils.NewLogger(os.Stdout, env.Log.Level)
+	slog.SetDefault(logger)
+
+	if err := utils.LoadKeys(env.JWT); err != nil {
+		fatal("Error loading JWT keys", err)
+	}
+
+	if err := utils.LoadPasswordPolicy(env.Password); err != nil {
+		fatal("Error loading password policy", err)
 	}
 
 	db, err := config.NewDB(env.DB)
 	if err != nil {
-		log.Fatal("Error connection to DB")
+		fatal("Error connection to DB", err)
 	}
 
+	utils.RegisterDBStats(db.Conn.DB, env.DB.Name)
+
 	validate := validator.New()
 
-	r := gin.Default()
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware(logger), middlewares.LoggerMiddleware(), middlewares.MetricsMiddleware(), gin.Recovery())
+
+	// Login throttling keys on the client address, so only named proxies may
+	// set X-Forwarded-For.
+	if err := r.SetTrustedProxies(env.Http.TrustedProxies); err != nil {
+		fatal("Error setting trusted proxies", err)
+	}
 
 	transactionRepo := repositories.NewTransactionRepository(db.Conn)
+	auditRepo := repositories.NewAuditRepository(db.Conn)
+
+	tokenRepo := repositories.NewTokenRepository(db.Conn)
+
+	inviteRepo := repositories.NewInviteRepository(db.Conn)
+	authEventRepo := repositories.NewAuthEventRepository(db.Conn)
+	mfaRepo := repositories.NewMFARepository(db.Conn)
 
 	userRepo := repositories.NewUserRepository(db.Conn)
-	userService := services.NewUserService(userRepo)
+	userService := services.NewUserService(userRepo, tokenRepo, inviteRepo, authEventRepo, mfaRepo, auditRepo, env.Registration.Mode, env.MFA)
+
+	if len(os.Args) > 1 && os.Args[1] == "bootstrap-admin" {
+		bootstrapAdmin(userService, os.Args[2:])
+		return
+	}
+
 	userHandler := handlers.NewUserHandlerImpl(userService)
 
 	productRepo := repositories.NewProductRepository(db.Conn)
-	productService := services.NewProductService(productRepo)
+	locationRepo := repositories.NewLocationRepository(db.Conn)
+	productService := services.NewProductService(productRepo, locationRepo, transactionRepo, auditRepo)
 	productHandler := handlers.NewProductHandler(productService, validate)
 
-	locationRepo := repositories.NewLocationRepository(db.Conn)
-	locationService := services.NewLocationService(locationRepo)
+	locationService := services.NewLocationService(locationRepo, auditRepo)
 	locationHandler := handlers.NewLocationHandler(locationService, validate)
 
 	orderRepo := repositories.NewOrderRepository(db.Conn)
-	orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo)
+	orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo, auditRepo)
 	orderHandler := handlers.NewOrderHandler(orderService, validate)
 
-	router := routes.NewRouter(r, userHandler, productHandler, locationHandler, orderHandler)
+	keyHandler := handlers.NewKeyHandler()
+
+	permissionRepo := repositories.NewPermissionRepository(db.Conn)
+	permissionService := services.NewPermissionService(permissionRepo, transactionRepo)
+	roleHandler := handlers.NewRoleHandler(permissionService)
+
+	serviceAccountRepo := repositories.NewServiceAccountRepository(db.Conn)
+	serviceAccountService := services.NewServiceAccountService(serviceAccountRepo, userRepo, permissionRepo, auditRepo)
+	serviceAccountHandler := handlers.NewServiceAccountHandler(serviceAccountService)
+
+	var oidcProvider utils.OIDCProvider
+	if env.OIDC.IssuerURL != "" {
+		if oidcProvider, err = utils.NewOIDCProvider(context.Background(), env.OIDC); err != nil {
+			fatal("Error loading OIDC provider", err)
+		}
+	}
+
+	oidcRepo := repositories.NewOIDCRepository(db.Conn)
+	oidcService := services.NewOIDCService(oidcProvider, oidcRepo, userRepo, tokenRepo, authEventRepo, mfaRepo, auditRepo, env.MFA, env.OIDC.GroupRoles)
+	oidcHandler := handlers.NewOIDCHandler(oidcService)
+
+	auditService := services.NewAuditService(auditRepo)
+	auditHandler := handlers.NewAuditHandler(auditService)
+
+	idempotencyRepo := repositories.NewIdempotencyRepository(db.Conn)
+	idempotencyService := services.NewIdempotencyService(idempotencyRepo, env.Idempotency.TTL)
+	go purgeIdempotencyKeys(idempotencyService)
+
+	authenticate := gin.HandlersChain{middlewares.JWTMiddleware(userService), middlewares.APIKeyMiddleware(serviceAccountService)}
+
+	go func() {
+		if err := routes.StartMetrics(env.Http.MetricsAddr); err != nil {
+			fatal("Error serving metrics", err)
+		}
+	}()
+
+	router := routes.NewRouter(r, authenticate, middlewares.PermissionMiddleware(permissionService), middlewares.IdempotencyMiddleware(idempotencyService), userHandler, productHandler, locationHandler, orderHandler, keyHandler, roleHandler, serviceAccountHandler, oidcHandler, auditHandler)
 	router.Start(env.Http.Port)
 }
+
+// bootstrapAdmin creates the first admin account. The password is read from
+// BOOTSTRAP_ADMIN_PASSWORD so it doesn't end up in the shell history.
+func bootstrapAdmin(userService services.UserService, args []string) {
+	flags := flag.NewFlagSet("bootstrap-admin", flag.ExitOnError)
+	email := flags.String("email", "", "admin email")
+	name := flags.String("name", "Administrator", "admin name")
+	flags.Parse(args)
+
+	password := os.Getenv("BOOTSTRAP_ADMIN_PASSWORD")
+	if *email == "" || password == "" {
+		fatal("bootstrap-admin needs -email and BOOTSTRAP_ADMIN_PASSWORD", nil)
+	}
+
+	if err := userService.BootstrapAdmin(context.Background(), &dto.RegisterRequest{Email: *email, Name: *name, Password: password}); err != nil {
+		fatal("Error creating admin", err)
+	}
+
+	slog.Info("Created admin", "email", *email)
+}
+
+// purgeIdempotencyKeys deletes expired idempotency keys once an hour, so the
+// request path only ever touches the key it reserves.
+func purgeIdempotencyKeys(idempotency services.IdempotencyService) {
+	for range time.Tick(time.Hour) {
+		purged, err := idempotency.PurgeExpired(context.Background())
+		if err != nil {
+			slog.Error("purging expired idempotency keys failed", "error", err)
+			continue
+		}
+
+		slog.Info("purged expired idempotency keys", "count", purged)
+	}
+}
+
+// fatal logs why the service can't start and exits.
+func fatal(msg string, err error) {
+	if err != nil {
+		slog.Error(msg, "error", err)
+	} else {
+		slog.Error(msg)
+	}
+
+	os.Exit(1)
+}
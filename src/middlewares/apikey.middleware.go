@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// APIKeyMiddleware authenticates service accounts. The key is read from
+// X-API-Key, or from a bearer token carrying the API key prefix for clients
+// that can only send Authorization. Like JWTMiddleware it leaves the request
+// anonymous when the key is missing or no longer valid.
+func APIKeyMiddleware(serviceAccounts services.ServiceAccountService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, exists := c.Get("user"); exists {
+			c.Next()
+			return
+		}
+
+		key := c.GetHeader("X-API-Key")
+		if bearer := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "); key == "" && strings.HasPrefix(bearer, utils.APIKeyPrefix) {
+			key = bearer
+		}
+
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		identity, err := serviceAccounts.AuthenticateAPIKey(c.Request.Context(), key)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		setUser(c, &utils.CustomClaims{
+			ID:        identity.User.ID,
+			Name:      identity.User.Name,
+			Email:     identity.User.Email,
+			Role:      identity.User.Role,
+			Scope:     identity.User.Scope,
+			APIKeyID:  identity.KeyID,
+			KeyScopes: identity.Scopes,
+		})
+
+		c.Next()
+	}
+}
+
+// UserOnlyMiddleware keeps API keys away from endpoints that manage a
+// person's own login, such as passwords and two-factor settings.
+func UserOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user, exists := c.Get("user"); exists {
+			if claims, ok := user.(*utils.CustomClaims); ok && claims.APIKeyID != uuid.Nil {
+				helpers.ForbiddenError(c, "not available with an api key")
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
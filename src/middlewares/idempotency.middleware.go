@@ -0,0 +1,141 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotentBodySize caps the body read into memory to hash it. A batch
+// of 1000 order lines fits well within it.
+const maxIdempotentBodySize = 1 << 20
+
+// replayedHeaders are the response headers stored with a key and sent again
+// on replay, besides Content-Type.
+var replayedHeaders = []string{"Location", "ETag"}
+
+// IdempotencyMiddleware makes retries safe for requests that carry an
+// Idempotency-Key header. The first request runs and its response is stored;
+// a retry with the same key and payload gets that response back without
+// running the handler again, and the same key with another payload is
+// rejected. Server errors are not stored, so those can be retried for real.
+// It must run after authentication, since keys belong to the caller.
+func IdempotencyMiddleware(idempotency services.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > maxIdempotencyKeyLength {
+			helpers.BadRequestError(c, "Idempotency-Key must be at most 255 characters")
+			c.Abort()
+			return
+		}
+
+		user, exists := c.Get("user")
+		if !exists {
+			helpers.UnauthorizedError(c, "unauthorized")
+			c.Abort()
+			return
+		}
+		claims := user.(*utils.CustomClaims)
+
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxIdempotentBodySize))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			helpers.ErrorByCode(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d MB", maxIdempotentBodySize>>20))
+			c.Abort()
+			return
+		}
+		if err != nil {
+			helpers.BadRequestError(c, "request body could not be read")
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		requestHash := utils.HashToken(c.Request.Method + " " + c.Request.URL.Path + "\n" + string(body))
+
+		stored, err := idempotency.Begin(c.Request.Context(), claims.ID, key, requestHash)
+		if err != nil {
+			helpers.Error(c, err)
+			c.Abort()
+			return
+		}
+
+		if stored != nil {
+			for name, value := range stored.Headers {
+				c.Header(name, value)
+			}
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// The outcome is stored even if the client has gone away meanwhile.
+		ctx := context.WithoutCancel(c.Request.Context())
+
+		// gin.Recovery sits above this middleware, so a panicking handler
+		// would otherwise leave the key in progress until it expires.
+		defer func() {
+			if p := recover(); p != nil {
+				if err := idempotency.Release(ctx, claims.ID, key); err != nil {
+					c.Error(err)
+				}
+				panic(p)
+			}
+		}()
+
+		recorder := &bodyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+
+		c.Next()
+
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := idempotency.Release(ctx, claims.ID, key); err != nil {
+				c.Error(err)
+			}
+			return
+		}
+
+		headers := map[string]string{}
+		for _, name := range replayedHeaders {
+			if value := recorder.Header().Get(name); value != "" {
+				headers[name] = value
+			}
+		}
+
+		if err := idempotency.Complete(ctx, claims.ID, key, recorder.Status(), recorder.Header().Get("Content-Type"), headers, recorder.body.Bytes()); err != nil {
+			c.Error(err)
+		}
+	}
+}
+
+// bodyRecorder keeps a copy of the response body while writing it through.
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *bodyRecorder) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
@@ -5,11 +5,11 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/nabilwafi/warehouse-management-system/src/helpers"
-	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
 	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
 
-func JWTMiddleware() gin.HandlerFunc {
+func JWTMiddleware(user services.UserService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -25,34 +25,32 @@ func JWTMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		c.Set("user", claims)
+		revoked, err := user.IsTokenRevoked(c.Request.Context(), claims.RegisteredClaims.ID)
+		if err != nil || revoked {
+			c.Next()
+			return
+		}
+
+		active, err := user.IsUserActive(c.Request.Context(), claims.ID, claims.Role)
+		if err != nil || !active {
+			c.Next()
+			return
+		}
+
+		setUser(c, claims)
 
 		c.Next()
 	}
 }
 
-func RoleMiddleware(allowedRoles ...dto.UserRole) gin.HandlerFunc {
+func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		user, exists := c.Get("user")
-
-		if !exists {
-			helpers.ForbiddenError(c, "forbidden")
+		if _, exists := c.Get("user"); !exists {
+			helpers.UnauthorizedError(c, "unauthorized")
 			c.Abort()
+			return
 		}
 
-		decodedUser := user.(*utils.CustomClaims)
-
-		role := decodedUser.Role
-
-		for _, allowedRole := range allowedRoles {
-			if role == allowedRole {
-				c.Set("user", decodedUser)
-				c.Next()
-				return
-			}
-		}
-
-		helpers.ForbiddenError(c, "forbidden")
-		c.Abort()
+		c.Next()
 	}
 }
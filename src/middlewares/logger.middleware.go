@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// LoggerMiddleware writes one line per request with the request logger, so
+// it carries the request ID and, once authenticated, the user ID. Errors
+// the handlers attached with c.Error are included; server errors are logged
+// at error level.
+func LoggerMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", status),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("ip", c.ClientIP()),
+		}
+
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.Any("errors", c.Errors.Errors()))
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+
+		utils.Logger(c.Request.Context()).Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
@@ -0,0 +1,48 @@
+package middlewares
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// PermissionMiddleware returns a factory for handlers that only let through
+// users whose role grants the given permission.
+func PermissionMiddleware(permissions services.PermissionService) func(permission string) gin.HandlerFunc {
+	return func(permission string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			user, exists := c.Get("user")
+			if !exists {
+				helpers.UnauthorizedError(c, "unauthorized")
+				c.Abort()
+				return
+			}
+
+			claims := user.(*utils.CustomClaims)
+
+			granted, err := permissions.HasPermission(c.Request.Context(), claims.Role, permission)
+			if err != nil {
+				helpers.Error(c, err)
+				c.Abort()
+				return
+			}
+
+			// An API key only carries the permissions it was issued with.
+			if claims.APIKeyID != uuid.Nil && !slices.Contains(claims.KeyScopes, permission) {
+				granted = false
+			}
+
+			if !granted {
+				helpers.ForbiddenError(c, "forbidden")
+				c.Abort()
+				return
+			}
+
+			c.Next()
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package middlewares
+
+import (
+	"log/slog"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID keeps client-chosen IDs short and free of characters that
+// could break log lines or headers.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestIDMiddleware gives every request an ID, taken from X-Request-ID
+// when the client sent a usable one, and a logger that carries it. Both are
+// stored in the request context and the ID is echoed in the response.
+func RequestIDMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		c.Header(RequestIDHeader, requestID)
+
+		ctx := utils.WithRequestID(c.Request.Context(), requestID)
+		ctx = utils.WithLogger(ctx, logger.With("request_id", requestID))
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+	}
+}
+
+// setUser makes the caller available to handlers and names it in every log
+// line written for the rest of the request.
+func setUser(c *gin.Context, claims *utils.CustomClaims) {
+	c.Set("user", claims)
+
+	ctx := c.Request.Context()
+	c.Request = c.Request.WithContext(utils.WithLogger(ctx, utils.Logger(ctx).With("user_id", claims.ID)))
+}
@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditActionCreate  AuditAction = "create"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+)
+
+// AuditActor is who makes a change, tagged onto the transaction so the audit
+// triggers can record it.
+type AuditActor struct {
+	UserID    uuid.UUID
+	IP        string
+	RequestID string
+}
+
+// AuditLog is one changed row. Changes maps each changed field to its
+// {"before": .., "after": ..} values.
+type AuditLog struct {
+	ID        uuid.UUID       `json:"id"`
+	ActorID   *uuid.UUID      `json:"actor_id"`
+	Action    AuditAction     `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  uuid.UUID       `json:"entity_id"`
+	Changes   json.RawMessage `json:"changes"`
+	IP        string          `json:"ip"`
+	RequestID string          `json:"request_id,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type AuditLogFilter struct {
+	ActorID  *uuid.UUID
+	Entity   string
+	EntityID *uuid.UUID
+	Action   AuditAction
+	From     *time.Time
+	To       *time.Time
+}
@@ -0,0 +1,49 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuthEventType string
+
+const (
+	AuthEventLogin    AuthEventType = "login"
+	AuthEventSSOLogin AuthEventType = "sso_login"
+)
+
+const (
+	AuthReasonUnknownEmail  = "unknown_email"
+	AuthReasonWrongPassword = "wrong_password"
+	AuthReasonDeactivated   = "deactivated"
+	AuthReasonLocked        = "locked"
+	AuthReasonWrongMFACode  = "wrong_mfa_code"
+	AuthReasonNoRole        = "no_role"
+)
+
+type AuthEvent struct {
+	ID        uuid.UUID     `json:"id"`
+	Event     AuthEventType `json:"event"`
+	Email     string        `json:"email"`
+	UserID    *uuid.UUID    `json:"user_id"`
+	IP        string        `json:"ip"`
+	Success   bool          `json:"success"`
+	Reason    string        `json:"reason,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type AuthEventFilter struct {
+	Email   string
+	IP      string
+	Success *bool
+}
+
+// LoginFailures counts recent failed logins for an account and for the
+// address the attempt comes from, with the time of the latest of each.
+type LoginFailures struct {
+	Account     int
+	AccountLast time.Time
+	IP          int
+	IPLast      time.Time
+}
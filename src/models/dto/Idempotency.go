@@ -0,0 +1,20 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey is a request sent with an Idempotency-Key header and, once
+// it finished, the response to replay. StatusCode is 0 while it is running.
+type IdempotencyKey struct {
+	UserID       uuid.UUID
+	Key          string
+	RequestHash  string
+	StatusCode   int
+	ContentType  string
+	Headers      map[string]string
+	ResponseBody []byte
+	ExpiresAt    time.Time
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Invite struct {
+	ID        uuid.UUID
+	CodeHash  string
+	Role      UserRole
+	CreatedBy uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	UsedBy    *uuid.UUID
+}
+
+type InviteRequest struct {
+	Role UserRole `json:"role" binding:"required,max=50"`
+}
+
+type InviteResponse struct {
+	InviteCode string    `json:"invite_code"`
+	Role       UserRole  `json:"role"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
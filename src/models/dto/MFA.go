@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type MFAChallenge struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+// MFAChallengeResponse is what a correct password returns when a second
+// factor is needed. EnrollmentRequired means the user's role demands MFA
+// and the challenge must first be used to enrol.
+type MFAChallengeResponse struct {
+	MFAToken           string `json:"mfa_token"`
+	ExpiresIn          int64  `json:"expires_in"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+}
+
+type TOTPState struct {
+	Secret   *string
+	Enabled  bool
+	LastStep *int64
+}
+
+type MFAEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+type MFAEnrollmentResult struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32"`
+}
+
+type MFAChallengeRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OIDCLoginState is kept between sending the browser to the identity
+// provider and its return, and can be used once.
+type OIDCLoginState struct {
+	ID           uuid.UUID
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+// OIDCIdentity holds the verified claims of an ID token.
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
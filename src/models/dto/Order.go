@@ -23,3 +23,45 @@ type OrderCreateRequest struct {
 	ProductID uuid.UUID `json:"product_id" form:"product_id" binding:"required,uuid"`
 	Quantity  int64     `json:"quantity" form:"quantity" binding:"required,min=0"`
 }
+
+type OrderBatchMode string
+
+const (
+	OrderBatchModeAtomic     OrderBatchMode = "atomic"
+	OrderBatchModeBestEffort OrderBatchMode = "best_effort"
+)
+
+type OrderBatchLineStatus string
+
+const (
+	OrderBatchLineApplied    OrderBatchLineStatus = "applied"
+	OrderBatchLineFailed     OrderBatchLineStatus = "failed"
+	OrderBatchLineRolledBack OrderBatchLineStatus = "rolled_back"
+)
+
+type OrderBatchLine struct {
+	Type      OrderType `json:"type" binding:"required,oneof=receiving shipping"`
+	ProductID uuid.UUID `json:"product_id" binding:"required,uuid"`
+	Quantity  int64     `json:"quantity" binding:"required,min=0"`
+}
+
+type OrderBatchRequest struct {
+	Mode  OrderBatchMode   `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Lines []OrderBatchLine `json:"lines" binding:"required,min=1,max=1000,dive"`
+}
+
+type OrderBatchLineResult struct {
+	Line      int                  `json:"line"`
+	Type      OrderType            `json:"type"`
+	ProductID uuid.UUID            `json:"product_id"`
+	Quantity  int64                `json:"quantity"`
+	Status    OrderBatchLineStatus `json:"status"`
+	Error     string               `json:"error,omitempty"`
+}
+
+type OrderBatchResult struct {
+	Mode    OrderBatchMode         `json:"mode"`
+	Applied int                    `json:"applied"`
+	Failed  int                    `json:"failed"`
+	Lines   []OrderBatchLineResult `json:"lines"`
+}
@@ -11,4 +11,31 @@ type Product struct {
 	Quantity   int64     `json:"quantity" binding:"required,min=0"`
 	LocationID uuid.UUID `json:"location_id" binding:"required,uuid"`
 	Location   *Location `json:"location,omitempty"`
+	Version    int64     `json:"version"`
+}
+
+// ProductPatch changes only the fields that are set. Quantity is left out on
+// purpose: stock only moves through orders.
+type ProductPatch struct {
+	Name       *string    `json:"name" binding:"omitempty,min=1,max=100"`
+	SKU        *string    `json:"sku" binding:"omitempty,min=1,max=100"`
+	LocationID *uuid.UUID `json:"location_id"`
+}
+
+type ProductImportRow struct {
+	Row     int
+	Product *Product
+	Errors  []string
+}
+
+type ProductImportRowError struct {
+	Row    int      `json:"row"`
+	Errors []string `json:"errors"`
+}
+
+type ProductImportReport struct {
+	DryRun    bool                    `json:"dry_run"`
+	TotalRows int                     `json:"total_rows"`
+	Imported  int                     `json:"imported"`
+	Errors    []ProductImportRowError `json:"errors"`
 }
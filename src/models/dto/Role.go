@@ -0,0 +1,38 @@
+package dto
+
+const (
+	PermissionProductRead    = "product:read"
+	PermissionProductWrite   = "product:write"
+	PermissionProductDelete  = "product:delete"
+	PermissionProductExport  = "product:export"
+	PermissionLocationRead   = "location:read"
+	PermissionLocationWrite  = "location:write"
+	PermissionLocationDelete = "location:delete"
+	PermissionLocationExport = "location:export"
+	PermissionOrderRead      = "order:read"
+	PermissionOrderReceive   = "order:receive"
+	PermissionOrderShip      = "order:ship"
+	PermissionOrderExport    = "order:export"
+	PermissionUserRead       = "user:read"
+	PermissionUserWrite      = "user:write"
+	PermissionRoleManage     = "role:manage"
+	PermissionAuthEventRead  = "auth_event:read"
+	PermissionServiceAccount = "service_account:manage"
+	PermissionAuditRead      = "audit:read"
+)
+
+type Role struct {
+	Name        UserRole `json:"name" binding:"required,max=50"`
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required,dive,max=100"`
+}
+
+type RoleUpdateRequest struct {
+	Description string   `json:"description" binding:"max=255"`
+	Permissions []string `json:"permissions" binding:"required,dive,max=100"`
+}
+
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
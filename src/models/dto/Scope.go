@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// LocationScope lists the locations a user may work with. All grants every
+// location, including ones created later. A nil scope grants nothing.
+type LocationScope struct {
+	All         bool        `json:"all_locations"`
+	LocationIDs []uuid.UUID `json:"location_ids"`
+}
+
+func (s *LocationScope) Allows(locationID uuid.UUID) bool {
+	if s == nil {
+		return false
+	}
+
+	return s.All || slices.Contains(s.LocationIDs, locationID)
+}
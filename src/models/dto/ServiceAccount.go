@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type ServiceAccountRequest struct {
+	Name string   `json:"name" binding:"required,max=100"`
+	Role UserRole `json:"role" binding:"required,max=50"`
+}
+
+type APIKey struct {
+	ID               uuid.UUID  `json:"id"`
+	ServiceAccountID uuid.UUID  `json:"service_account_id"`
+	Name             string     `json:"name"`
+	Prefix           string     `json:"prefix"`
+	KeyHash          string     `json:"-"`
+	Scopes           []string   `json:"scopes"`
+	ExpiresAt        *time.Time `json:"expires_at"`
+	LastUsedAt       *time.Time `json:"last_used_at"`
+	RevokedAt        *time.Time `json:"revoked_at"`
+	CreatedBy        *uuid.UUID `json:"created_by"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type APIKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1,dive,max=100"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse carries the key itself, which is only shown once.
+type APIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyIdentity is the service account a presented key belongs to.
+type APIKeyIdentity struct {
+	KeyID  uuid.UUID
+	Scopes []string
+	User   User
+}
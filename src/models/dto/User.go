@@ -10,11 +10,26 @@ const (
 )
 
 type User struct {
-	ID       uuid.UUID `json:"id"`
-	Email    string    `json:"email"`
-	Password string    `json:"-"`
-	Name     string    `json:"name"`
-	Role     UserRole  `json:"role"`
+	ID             uuid.UUID     `json:"id"`
+	Email          string        `json:"email"`
+	Password       string        `json:"-"`
+	Name           string        `json:"name"`
+	Role           UserRole      `json:"role"`
+	Scope          LocationScope `json:"scope"`
+	Active         bool          `json:"active"`
+	MFAEnabled     bool          `json:"mfa_enabled"`
+	ServiceAccount bool          `json:"service_account"`
+}
+
+type UserUpdateRequest struct {
+	Email string   `json:"email" binding:"required,email,max=100"`
+	Name  string   `json:"name" binding:"required,max=100"`
+	Role  UserRole `json:"role" binding:"required,max=50"`
+}
+
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 type LoginRequest struct {
@@ -29,10 +44,14 @@ type UserClaims struct {
 	Role  UserRole
 }
 
+// RegisterRequest never takes the role from the client; the service decides
+// it from the registration mode or the invite being redeemed.
 type RegisterRequest struct {
-	ID       uuid.UUID `json:"id"`
-	Email    string    `json:"email"`
-	Password string    `json:"password"`
-	Name     string    `json:"name"`
-	Role     UserRole  `json:"role"`
+	ID           uuid.UUID `json:"-"`
+	Email        string    `json:"email" binding:"required,email,max=100"`
+	Password     string    `json:"password" binding:"required"`
+	Name         string    `json:"name" binding:"required,max=100"`
+	Role         UserRole  `json:"-"`
+	AllLocations bool      `json:"-"`
+	InviteCode   string    `json:"invite_code"`
 }
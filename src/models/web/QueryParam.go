@@ -1,6 +1,15 @@
 package web
 
+import "github.com/google/uuid"
+
 type PaginationRequest struct {
-	Page int
-	Size int
+	Page   int
+	Size   int
+	Keyset bool
+	After  uuid.UUID
+}
+
+type PageInfo struct {
+	TotalItems int64
+	HasNext    bool
 }
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+)
+
+// AuditRepository ties changes to the audit log. The log itself is written
+// by database triggers, in the same transaction as the change; the
+// application only tells them who is acting.
+type AuditRepository interface {
+	Transaction(ctx context.Context, actor *dto.AuditActor, fn func(tx *sqlx.Tx) error) error
+	TagWithTransaction(ctx context.Context, tx *sqlx.Tx, actor *dto.AuditActor) error
+	GetAll(ctx context.Context, filter *dto.AuditLogFilter, pagination *web.PaginationRequest) ([]*dto.AuditLog, *web.PageInfo, error)
+}
+
+type auditRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewAuditRepository(db *sqlx.DB) AuditRepository {
+	return &auditRepositoryImpl{
+		db: db,
+	}
+}
+
+// Transaction runs fn in its own transaction tagged with actor, committing
+// when fn succeeds.
+func (r *auditRepositoryImpl) Transaction(ctx context.Context, actor *dto.AuditActor, fn func(tx *sqlx.Tx) error) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		if err := r.TagWithTransaction(ctx, tx, actor); err != nil {
+			return err
+		}
+
+		return fn(tx)
+	})
+}
+
+// TagWithTransaction names the actor for the rest of tx. The settings are
+// transaction-local, so they never leak to the next user of the connection.
+func (r *auditRepositoryImpl) TagWithTransaction(ctx context.Context, tx *sqlx.Tx, actor *dto.AuditActor) error {
+	if actor == nil {
+		return nil
+	}
+
+	actorID := ""
+	if actor.UserID != uuid.Nil {
+		actorID = actor.UserID.String()
+	}
+
+	_, err := tx.ExecContext(ctx, "SELECT set_config('audit.actor_id', $1, true), set_config('audit.ip', $2, true), set_config('audit.request_id', $3, true)", actorID, actor.IP, actor.RequestID)
+
+	return logError(ctx, err)
+}
+
+func (r *auditRepositoryImpl) GetAll(ctx context.Context, filter *dto.AuditLogFilter, pagination *web.PaginationRequest) ([]*dto.AuditLog, *web.PageInfo, error) {
+	var logs []*dto.AuditLog
+	var total int64
+
+	const where = `($1::uuid IS NULL OR actor_id = $1) AND ($2 = '' OR entity = $2) AND ($3::uuid IS NULL OR entity_id = $3)
+		AND ($4 = '' OR action = $4) AND ($5::timestamptz IS NULL OR created_at >= $5) AND ($6::timestamptz IS NULL OR created_at < $6)`
+	args := []any{filter.ActorID, filter.Entity, filter.EntityID, filter.Action, filter.From, filter.To}
+
+	var rows *sqlx.Rows
+	var err error
+
+	// Ids are random, so the log is ordered by time with the id breaking ties.
+	// The keyset cursor is still an id; its position is looked up.
+	if pagination.Keyset {
+		rows, err = r.db.QueryxContext(ctx, `SELECT id, actor_id, action, entity, entity_id, changes, ip, request_id, created_at, 0 FROM public.audit_logs WHERE `+where+`
+			AND ($7::uuid = '00000000-0000-0000-0000-000000000000' OR (created_at, id) > (SELECT created_at, id FROM public.audit_logs WHERE id = $7))
+			ORDER BY created_at, id LIMIT $8`, append(args, pagination.After, pagination.Size+1)...)
+	} else {
+		offset := (pagination.Page - 1) * pagination.Size
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, actor_id, action, entity, entity_id, changes, ip, request_id, created_at, COUNT(*) OVER() FROM public.audit_logs WHERE "+where+" ORDER BY created_at, id OFFSET $7 LIMIT $8", append(args, offset, pagination.Size)...)
+	}
+	if err != nil {
+		return nil, nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var log dto.AuditLog
+		if err := rows.Scan(&log.ID, &log.ActorID, &log.Action, &log.Entity, &log.EntityID, &log.Changes, &log.IP, &log.RequestID, &log.CreatedAt, &total); err != nil {
+			return nil, nil, logError(ctx, err)
+		}
+		logs = append(logs, &log)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, logError(ctx, err)
+	}
+
+	return buildPage(ctx, r.db, pagination, logs, total, "SELECT COUNT(*) FROM public.audit_logs WHERE "+where, args...)
+}
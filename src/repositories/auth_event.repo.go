@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+)
+
+type AuthEventRepository interface {
+	SaveAuthEvent(ctx context.Context, event *dto.AuthEvent) error
+	CountLoginFailures(ctx context.Context, email string, ip string, since time.Time) (*dto.LoginFailures, error)
+	GetAll(ctx context.Context, filter *dto.AuthEventFilter, pagination *web.PaginationRequest) ([]*dto.AuthEvent, *web.PageInfo, error)
+}
+
+type authEventRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewAuthEventRepository(db *sqlx.DB) AuthEventRepository {
+	return &authEventRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *authEventRepositoryImpl) SaveAuthEvent(ctx context.Context, event *dto.AuthEvent) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.auth_events (id, event, email, user_id, ip, success, reason) VALUES ($1, $2, $3, $4, $5, $6, $7)", event.ID, event.Event, event.Email, event.UserID, event.IP, event.Success, event.Reason)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+// CountLoginFailures counts failures since the later of `since` and the
+// account's last successful login. The address count ignores successes so
+// logging into one account doesn't reset guessing against others.
+func (r *authEventRepositoryImpl) CountLoginFailures(ctx context.Context, email string, ip string, since time.Time) (*dto.LoginFailures, error) {
+	var failures dto.LoginFailures
+	var accountLast, ipLast sql.NullTime
+
+	err := r.db.QueryRowContext(ctx, `SELECT
+			COUNT(*) FILTER (WHERE email = $1 AND created_at > GREATEST($3, (SELECT MAX(created_at) FROM public.auth_events WHERE email = $1 AND event = $4 AND success))),
+			MAX(created_at) FILTER (WHERE email = $1),
+			COUNT(*) FILTER (WHERE ip = $2),
+			MAX(created_at) FILTER (WHERE ip = $2)
+		FROM public.auth_events
+		WHERE (email = $1 OR ip = $2) AND event = $4 AND NOT success AND created_at > $3`,
+		email, ip, since, dto.AuthEventLogin).Scan(&failures.Account, &accountLast, &failures.IP, &ipLast)
+	if err != nil {
+		return nil, logError(ctx, err)
+	}
+
+	failures.AccountLast = accountLast.Time
+	failures.IPLast = ipLast.Time
+
+	return &failures, nil
+}
+
+func (r *authEventRepositoryImpl) GetAll(ctx context.Context, filter *dto.AuthEventFilter, pagination *web.PaginationRequest) ([]*dto.AuthEvent, *web.PageInfo, error) {
+	var events []*dto.AuthEvent
+	var total int64
+
+	const where = "($1 = '' OR email = $1) AND ($2 = '' OR ip = $2) AND ($3::boolean IS NULL OR success = $3)"
+
+	var rows *sqlx.Rows
+	var err error
+
+	if pagination.Keyset {
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, event, email, user_id, ip, success, reason, created_at, 0 FROM public.auth_events WHERE "+where+" AND id > $4 ORDER BY id LIMIT $5", filter.Email, filter.IP, filter.Success, pagination.After, pagination.Size+1)
+	} else {
+		offset := (pagination.Page - 1) * pagination.Size
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, event, email, user_id, ip, success, reason, created_at, COUNT(*) OVER() FROM public.auth_events WHERE "+where+" ORDER BY id OFFSET $4 LIMIT $5", filter.Email, filter.IP, filter.Success, offset, pagination.Size)
+	}
+	if err != nil {
+		return nil, nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var event dto.AuthEvent
+		if err := rows.Scan(&event.ID, &event.Event, &event.Email, &event.UserID, &event.IP, &event.Success, &event.Reason, &event.CreatedAt, &total); err != nil {
+			return nil, nil, logError(ctx, err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, logError(ctx, err)
+	}
+
+	return buildPage(ctx, r.db, pagination, events, total, "SELECT COUNT(*) FROM public.auth_events WHERE "+where, filter.Email, filter.IP, filter.Success)
+}
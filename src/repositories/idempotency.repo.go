@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type IdempotencyRepository interface {
+	ReserveIdempotencyKey(ctx context.Context, key *dto.IdempotencyKey) (bool, error)
+	FindIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*dto.IdempotencyKey, error)
+	SaveIdempotencyResponse(ctx context.Context, key *dto.IdempotencyKey) error
+	DeleteIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error
+	DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+var errIdempotencyKeyNotFound = domain.NotFound("idempotency_key_not_found", "idempotency key not found")
+
+type idempotencyRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) IdempotencyRepository {
+	return &idempotencyRepositoryImpl{
+		db: db,
+	}
+}
+
+// ReserveIdempotencyKey stores a new key, taking over the user's expired key
+// of the same name. It reports false when the user already holds the key and
+// it has not expired yet.
+func (r *idempotencyRepositoryImpl) ReserveIdempotencyKey(ctx context.Context, key *dto.IdempotencyKey) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `INSERT INTO public.idempotency_keys (user_id, key, request_hash, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, key) DO UPDATE SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL, response_headers = NULL, response_body = NULL, expires_at = EXCLUDED.expires_at, created_at = now()
+		WHERE idempotency_keys.expires_at < now()`, key.UserID, key.Key, key.RequestHash, key.ExpiresAt)
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	return rows == 1, nil
+}
+
+func (r *idempotencyRepositoryImpl) FindIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*dto.IdempotencyKey, error) {
+	var idempotencyKey dto.IdempotencyKey
+	var statusCode sql.NullInt64
+	var contentType sql.NullString
+	var headers []byte
+
+	if err := r.db.QueryRowContext(ctx, "SELECT user_id, key, request_hash, status_code, content_type, response_headers, response_body, expires_at FROM public.idempotency_keys WHERE user_id = $1 AND key = $2 AND expires_at >= now()", userID, key).Scan(&idempotencyKey.UserID, &idempotencyKey.Key, &idempotencyKey.RequestHash, &statusCode, &contentType, &headers, &idempotencyKey.ResponseBody, &idempotencyKey.ExpiresAt); err != nil {
+		return nil, notFoundOr(ctx, err, errIdempotencyKeyNotFound)
+	}
+
+	idempotencyKey.StatusCode = int(statusCode.Int64)
+	idempotencyKey.ContentType = contentType.String
+
+	if headers != nil {
+		if err := json.Unmarshal(headers, &idempotencyKey.Headers); err != nil {
+			return nil, logError(ctx, err)
+		}
+	}
+
+	return &idempotencyKey, nil
+}
+
+func (r *idempotencyRepositoryImpl) SaveIdempotencyResponse(ctx context.Context, key *dto.IdempotencyKey) error {
+	headers, err := json.Marshal(key.Headers)
+	if err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, "UPDATE public.idempotency_keys SET status_code = $3, content_type = $4, response_headers = $5, response_body = $6 WHERE user_id = $1 AND key = $2", key.UserID, key.Key, key.StatusCode, key.ContentType, headers, key.ResponseBody)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errIdempotencyKeyNotFound)
+}
+
+func (r *idempotencyRepositoryImpl) DeleteIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM public.idempotency_keys WHERE user_id = $1 AND key = $2", userID, key)
+	return logError(ctx, err)
+}
+
+// DeleteExpiredIdempotencyKeys purges keys nobody can replay anymore and
+// returns how many were removed.
+func (r *idempotencyRepositoryImpl) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM public.idempotency_keys WHERE expires_at < now()")
+	if err != nil {
+		return 0, logError(ctx, err)
+	}
+
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, logError(ctx, err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type InviteRepository interface {
+	SaveInvite(ctx context.Context, invite *dto.Invite) error
+	UseInviteWithTransaction(ctx context.Context, tx *sqlx.Tx, hash string, userID uuid.UUID) (*dto.Invite, error)
+}
+
+var errInviteNotFound = domain.NotFound("invite_not_found", "invite not found")
+
+type inviteRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewInviteRepository(db *sqlx.DB) InviteRepository {
+	return &inviteRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *inviteRepositoryImpl) SaveInvite(ctx context.Context, invite *dto.Invite) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.invites (id, code_hash, role, created_by, expires_at) VALUES ($1, $2, $3, $4, $5)", invite.ID, invite.CodeHash, invite.Role, invite.CreatedBy, invite.ExpiresAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return errUnknownRole
+		}
+
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+// UseInviteWithTransaction marks an unused, unexpired invite as redeemed by
+// userID in one statement so two registrations can't share a code.
+func (r *inviteRepositoryImpl) UseInviteWithTransaction(ctx context.Context, tx *sqlx.Tx, hash string, userID uuid.UUID) (*dto.Invite, error) {
+	var invite dto.Invite
+
+	err := tx.QueryRowContext(ctx, `UPDATE public.invites SET used_at = now(), used_by = $2
+		WHERE code_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING id, code_hash, role, expires_at, used_at, used_by`, hash, userID).Scan(&invite.ID, &invite.CodeHash, &invite.Role, &invite.ExpiresAt, &invite.UsedAt, &invite.UsedBy)
+	if err != nil {
+		return nil, notFoundOr(ctx, err, errInviteNotFound)
+	}
+
+	return &invite, nil
+}
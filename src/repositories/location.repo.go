@@ -1,20 +1,33 @@
 package repositories
 
 import (
-	"database/sql"
+	"context"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 )
 
 type LocationRepository interface {
-	Save(location *dto.Location) error
-	FindByName(name string) (*dto.Location, int, error)
-	GetAllLocation(pagination *web.PaginationRequest) ([]*dto.Location, error)
+	SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, location *dto.Location) (*dto.Location, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*dto.Location, error)
+	FindByName(ctx context.Context, name string) (*dto.Location, error)
+	FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*dto.Location, error)
+	GetAllLocation(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Location, *web.PageInfo, error)
+	StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Location) error) error
+	DeleteWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, deletedBy uuid.UUID) error
+	FindDeletedByID(ctx context.Context, id uuid.UUID) (*dto.Location, error)
+	RestoreWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*dto.Location, error)
 }
 
+var (
+	errLocationNotFound = domain.NotFound("location_not_found", "location not found")
+	errLocationNotEmpty = domain.Conflict("location_not_empty", "location still has products")
+)
+
 type locationRepositoryImpl struct {
 	db *sqlx.DB
 }
@@ -25,53 +38,162 @@ func NewLocationRepository(db *sqlx.DB) LocationRepository {
 	}
 }
 
-func (r *locationRepositoryImpl) Save(location *dto.Location) error {
-	uuid := uuid.New()
+func (r *locationRepositoryImpl) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, location *dto.Location) (*dto.Location, error) {
+	var locationData dto.Location
 
-	_, err := r.db.Exec("INSERT INTO public.locations (id, name, capacity) VALUES ($1, $2, $3)", uuid, location.Name, location.Capacity)
-	if err != nil {
-		return err
+	if err := tx.QueryRowContext(ctx, "INSERT INTO public.locations (id, name, capacity) VALUES ($1, $2, $3) RETURNING id, name, capacity", uuid.New(), location.Name, location.Capacity).Scan(&locationData.ID, &locationData.Name, &locationData.Capacity); err != nil {
+		return nil, logError(ctx, err)
 	}
 
-	return nil
+	return &locationData, nil
 }
 
-func (r *locationRepositoryImpl) FindByName(name string) (*dto.Location, int, error) {
+func (r *locationRepositoryImpl) FindByID(ctx context.Context, id uuid.UUID) (*dto.Location, error) {
 	var locationData dto.Location
 
-	if err := r.db.QueryRow("SELECT id, name, capacity FROM public.locations WHERE name = $1", name).Scan(&locationData.ID, &locationData.Name, &locationData.Capacity); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, 404, sql.ErrNoRows
-		}
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, capacity FROM public.locations WHERE id = $1 AND deleted_at IS NULL", id).Scan(&locationData.ID, &locationData.Name, &locationData.Capacity); err != nil {
+		return nil, notFoundOr(ctx, err, errLocationNotFound)
+	}
+
+	return &locationData, nil
+}
 
-		return nil, 500, err
+func (r *locationRepositoryImpl) FindByName(ctx context.Context, name string) (*dto.Location, error) {
+	var locationData dto.Location
+
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, capacity FROM public.locations WHERE name = $1 AND deleted_at IS NULL", name).Scan(&locationData.ID, &locationData.Name, &locationData.Capacity); err != nil {
+		return nil, notFoundOr(ctx, err, errLocationNotFound)
 	}
 
-	return &locationData, 200, nil
+	return &locationData, nil
 }
 
-func (r *locationRepositoryImpl) GetAllLocation(pagination *web.PaginationRequest) ([]*dto.Location, error) {
+// FindByIDs returns the locations among ids that exist and are not deleted.
+func (r *locationRepositoryImpl) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*dto.Location, error) {
 	var locationData []*dto.Location
 
-	offset := (pagination.Page - 1) * pagination.Size
+	locationIDs := make([]string, len(ids))
+	for i, id := range ids {
+		locationIDs[i] = id.String()
+	}
 
-	rows, err := r.db.Queryx("SELECT id, name, capacity FROM public.locations OFFSET $1 LIMIT $2", offset, pagination.Size)
+	rows, err := r.db.QueryxContext(ctx, "SELECT id, name, capacity FROM public.locations WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL", pq.Array(locationIDs))
 	if err != nil {
-		return nil, err
+		return nil, logError(ctx, err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var location dto.Location
 		if err := rows.Scan(&location.ID, &location.Name, &location.Capacity); err != nil {
-			return nil, err
+			return nil, logError(ctx, err)
 		}
 		locationData = append(locationData, &location)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, logError(ctx, err)
 	}
 
 	return locationData, nil
 }
+
+func (r *locationRepositoryImpl) GetAllLocation(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Location, *web.PageInfo, error) {
+	var locationData []*dto.Location
+	var total int64
+
+	var rows *sqlx.Rows
+	var err error
+
+	if pagination.Keyset {
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, name, capacity, 0 FROM public.locations WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR id = ANY($1::uuid[])) AND id > $2 ORDER BY id LIMIT $3", scopeFilter(scope), pagination.After, pagination.Size+1)
+	} else {
+		offset := (pagination.Page - 1) * pagination.Size
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, name, capacity, COUNT(*) OVER() FROM public.locations WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR id = ANY($1::uuid[])) OFFSET $2 LIMIT $3", scopeFilter(scope), offset, pagination.Size)
+	}
+	if err != nil {
+		return nil, nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var location dto.Location
+		if err := rows.Scan(&location.ID, &location.Name, &location.Capacity, &total); err != nil {
+			return nil, nil, logError(ctx, err)
+		}
+		locationData = append(locationData, &location)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, logError(ctx, err)
+	}
+
+	return buildPage(ctx, r.db, pagination, locationData, total, "SELECT COUNT(*) FROM public.locations WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR id = ANY($1::uuid[]))", scopeFilter(scope))
+}
+
+func (r *locationRepositoryImpl) StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Location) error) error {
+	rows, err := r.db.QueryxContext(ctx, "SELECT id, name, capacity FROM public.locations WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR id = ANY($1::uuid[])) AND id > $2 ORDER BY id LIMIT $3", scopeFilter(scope), pagination.After, streamLimit(pagination))
+	if err != nil {
+		return logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var location dto.Location
+		if err := rows.Scan(&location.ID, &location.Name, &location.Capacity); err != nil {
+			return logError(ctx, err)
+		}
+
+		if err := fn(&location); err != nil {
+			return logError(ctx, err)
+		}
+	}
+
+	return logError(ctx, rows.Err())
+}
+
+// Delete hides the location, but only once no live product is stored in it.
+func (r *locationRepositoryImpl) DeleteWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, deletedBy uuid.UUID) error {
+	var deleted, found bool
+
+	err := tx.QueryRowContext(ctx, `WITH deleted AS (
+			UPDATE public.locations SET deleted_at = now(), deleted_by = $2
+			WHERE id = $1 AND deleted_at IS NULL
+				AND NOT EXISTS (SELECT 1 FROM public.products WHERE location_id = $1 AND deleted_at IS NULL)
+			RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM deleted), EXISTS (SELECT 1 FROM public.locations WHERE id = $1 AND deleted_at IS NULL)`, id, deletedBy).Scan(&deleted, &found)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	if !found {
+		return errLocationNotFound
+	}
+
+	if !deleted {
+		return errLocationNotEmpty
+	}
+
+	return nil
+}
+
+func (r *locationRepositoryImpl) FindDeletedByID(ctx context.Context, id uuid.UUID) (*dto.Location, error) {
+	var locationData dto.Location
+
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, capacity FROM public.locations WHERE id = $1 AND deleted_at IS NOT NULL", id).Scan(&locationData.ID, &locationData.Name, &locationData.Capacity); err != nil {
+		return nil, notFoundOr(ctx, err, errLocationNotFound)
+	}
+
+	return &locationData, nil
+}
+
+func (r *locationRepositoryImpl) RestoreWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*dto.Location, error) {
+	var locationData dto.Location
+
+	if err := tx.QueryRowContext(ctx, "UPDATE public.locations SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING id, name, capacity", id).Scan(&locationData.ID, &locationData.Name, &locationData.Capacity); err != nil {
+		return nil, notFoundOr(ctx, err, errLocationNotFound)
+	}
+
+	return &locationData, nil
+}
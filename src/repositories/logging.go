@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// logError records an unexpected database error with the request's logger,
+// which carries the request and user IDs, and returns it unchanged. Missing
+// rows, domain errors and cancelled requests are expected and not logged.
+func logError(ctx context.Context, err error) error {
+	var domainErr *domain.Error
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) || errors.As(err, &domainErr) {
+		return err
+	}
+
+	attrs := []any{slog.String("error", err.Error()), slog.String("caller", caller())}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		attrs = append(attrs, slog.String("sql_state", string(pqErr.Code)))
+	}
+
+	utils.Logger(ctx).ErrorContext(ctx, "database error", attrs...)
+
+	return err
+}
+
+// caller names the repository method that hit the error, looking past the
+// shared helpers.
+func caller() string {
+	pcs := make([]uintptr, 4)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		name := frame.Function[strings.LastIndex(frame.Function, "/")+1:]
+		if (name != "repositories.notFoundOr" && name != "repositories.affected") || !more {
+			return name
+		}
+	}
+}
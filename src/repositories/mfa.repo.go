@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type MFARepository interface {
+	GetTOTP(ctx context.Context, userID uuid.UUID) (*dto.TOTPState, error)
+	SetPendingTOTPSecretWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, secret string) error
+	EnableMFAWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, recoveryCodeHashes []string) error
+	DisableMFAWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error
+	UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error)
+	SaveChallenge(ctx context.Context, challenge *dto.MFAChallenge) error
+	FindChallenge(ctx context.Context, hash string, maxAttempts int) (*dto.MFAChallenge, error)
+	FailChallenge(ctx context.Context, id uuid.UUID) error
+	UseChallenge(ctx context.Context, id uuid.UUID) (bool, error)
+}
+
+var (
+	errMFAAlreadyEnabled   = domain.Conflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	errTOTPNotPending      = domain.Conflict("mfa_not_pending", "no two-factor enrollment is pending")
+	errMFAChallengeInvalid = domain.NotFound("mfa_challenge_not_found", "mfa challenge not found")
+)
+
+type mfaRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewMFARepository(db *sqlx.DB) MFARepository {
+	return &mfaRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *mfaRepositoryImpl) GetTOTP(ctx context.Context, userID uuid.UUID) (*dto.TOTPState, error) {
+	var state dto.TOTPState
+
+	if err := r.db.QueryRowContext(ctx, "SELECT totp_secret, mfa_enabled, totp_last_step FROM public.users WHERE id = $1", userID).Scan(&state.Secret, &state.Enabled, &state.LastStep); err != nil {
+		return nil, notFoundOr(ctx, err, errUserNotFound)
+	}
+
+	return &state, nil
+}
+
+// SetPendingTOTPSecret stores a secret awaiting its first code. It leaves an
+// enabled secret alone, so enrolling can't be used to swap it out.
+func (r *mfaRepositoryImpl) SetPendingTOTPSecretWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, secret string) error {
+	result, err := tx.ExecContext(ctx, "UPDATE public.users SET totp_secret = $2, totp_last_step = NULL WHERE id = $1 AND NOT mfa_enabled", userID, secret)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errMFAAlreadyEnabled)
+}
+
+// EnableMFAWithTransaction turns on the pending secret and replaces any recovery codes.
+func (r *mfaRepositoryImpl) EnableMFAWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, recoveryCodeHashes []string) error {
+	var updated int64
+
+	err := tx.QueryRowContext(ctx, `WITH updated AS (
+			UPDATE public.users SET mfa_enabled = true WHERE id = $1 AND totp_secret IS NOT NULL RETURNING id
+		), removed AS (
+			DELETE FROM public.recovery_codes WHERE user_id IN (SELECT id FROM updated)
+		), added AS (
+			INSERT INTO public.recovery_codes (user_id, code_hash)
+			SELECT updated.id, unnest($2::text[]) FROM updated
+		)
+		SELECT COUNT(*) FROM updated`, userID, pq.Array(recoveryCodeHashes)).Scan(&updated)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	if updated == 0 {
+		return errTOTPNotPending
+	}
+
+	return nil
+}
+
+func (r *mfaRepositoryImpl) DisableMFAWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error {
+	result, err := tx.ExecContext(ctx, `WITH removed AS (
+			DELETE FROM public.recovery_codes WHERE user_id = $1
+		)
+		UPDATE public.users SET mfa_enabled = false, totp_secret = NULL, totp_last_step = NULL WHERE id = $1`, userID)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errUserNotFound)
+}
+
+// UseTOTPStep records the time step of an accepted code. It fails for a
+// step at or before the last one, which stops a code from being replayed.
+func (r *mfaRepositoryImpl) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE public.users SET totp_last_step = $2 WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)", userID, step)
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *mfaRepositoryImpl) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE public.recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL", userID, hash)
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *mfaRepositoryImpl) SaveChallenge(ctx context.Context, challenge *dto.MFAChallenge) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.mfa_challenges (id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)", challenge.ID, challenge.UserID, challenge.TokenHash, challenge.ExpiresAt)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+// FindChallenge returns a challenge that is unused, unexpired and has
+// attempts left.
+func (r *mfaRepositoryImpl) FindChallenge(ctx context.Context, hash string, maxAttempts int) (*dto.MFAChallenge, error) {
+	var challenge dto.MFAChallenge
+
+	err := r.db.QueryRowContext(ctx, `SELECT id, user_id, token_hash, attempts, expires_at, used_at FROM public.mfa_challenges
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now() AND attempts < $2`, hash, maxAttempts).Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.Attempts, &challenge.ExpiresAt, &challenge.UsedAt)
+	if err != nil {
+		return nil, notFoundOr(ctx, err, errMFAChallengeInvalid)
+	}
+
+	return &challenge, nil
+}
+
+func (r *mfaRepositoryImpl) FailChallenge(ctx context.Context, id uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE public.mfa_challenges SET attempts = attempts + 1 WHERE id = $1", id)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *mfaRepositoryImpl) UseChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE public.mfa_challenges SET used_at = now() WHERE id = $1 AND used_at IS NULL", id)
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	return rowsAffected == 1, nil
+}
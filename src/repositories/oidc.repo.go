@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type OIDCRepository interface {
+	SaveLoginState(ctx context.Context, state *dto.OIDCLoginState) error
+	UseLoginState(ctx context.Context, hash string) (*dto.OIDCLoginState, error)
+	FindUserIDBySubject(ctx context.Context, issuer string, subject string) (uuid.UUID, error)
+	LinkIdentity(ctx context.Context, userID uuid.UUID, issuer string, subject string) error
+}
+
+var (
+	errLoginStateNotFound = domain.NotFound("login_state_not_found", "login state not found")
+	errIdentityNotFound   = domain.NotFound("identity_not_found", "identity not found")
+	errIdentityLinked     = domain.Conflict("identity_linked", "account is already linked to another identity")
+)
+
+type oidcRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewOIDCRepository(db *sqlx.DB) OIDCRepository {
+	return &oidcRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *oidcRepositoryImpl) SaveLoginState(ctx context.Context, state *dto.OIDCLoginState) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.oidc_login_states (id, state_hash, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4, $5)", state.ID, state.StateHash, state.Nonce, state.CodeVerifier, state.ExpiresAt)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+// UseLoginState marks an unused, unexpired state as used and returns it, so
+// a callback can't be replayed.
+func (r *oidcRepositoryImpl) UseLoginState(ctx context.Context, hash string) (*dto.OIDCLoginState, error) {
+	var state dto.OIDCLoginState
+
+	err := r.db.QueryRowContext(ctx, `UPDATE public.oidc_login_states SET used_at = now()
+		WHERE state_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING id, state_hash, nonce, code_verifier, expires_at`, hash).Scan(&state.ID, &state.StateHash, &state.Nonce, &state.CodeVerifier, &state.ExpiresAt)
+	if err != nil {
+		return nil, notFoundOr(ctx, err, errLoginStateNotFound)
+	}
+
+	return &state, nil
+}
+
+func (r *oidcRepositoryImpl) FindUserIDBySubject(ctx context.Context, issuer string, subject string) (uuid.UUID, error) {
+	var userID uuid.UUID
+
+	if err := r.db.QueryRowContext(ctx, "SELECT user_id FROM public.user_identities WHERE issuer = $1 AND subject = $2", issuer, subject).Scan(&userID); err != nil {
+		return uuid.Nil, notFoundOr(ctx, err, errIdentityNotFound)
+	}
+
+	return userID, nil
+}
+
+func (r *oidcRepositoryImpl) LinkIdentity(ctx context.Context, userID uuid.UUID, issuer string, subject string) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.user_identities (issuer, subject, user_id) VALUES ($1, $2, $3)", issuer, subject, userID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errIdentityLinked
+		}
+
+		return logError(ctx, err)
+	}
+
+	return nil
+}
@@ -1,20 +1,26 @@
 package repositories
 
 import (
-	"database/sql"
+	"context"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 )
 
 type OrderRepository interface {
-	SaveWithTransaction(tx *sqlx.Tx, order *dto.Order) error
-	FindAll(pagination *web.PaginationRequest) ([]*dto.Order, error)
-	FindByID(id uuid.UUID) (*dto.Order, int, error)
+	SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, order *dto.Order) (*dto.Order, error)
+	SaveBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, orders []*dto.Order) error
+	FindAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Order, *web.PageInfo, error)
+	StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Order) error) error
+	FindByID(ctx context.Context, id uuid.UUID) (*dto.Order, error)
 }
 
+var errOrderNotFound = domain.NotFound("order_not_found", "order not found")
+
 type orderRepositoryImpl struct {
 	db *sqlx.DB
 }
@@ -25,53 +31,93 @@ func NewOrderRepository(db *sqlx.DB) OrderRepository {
 	}
 }
 
-func (r *orderRepositoryImpl) SaveWithTransaction(tx *sqlx.Tx, order *dto.Order) error {
-	uuid := uuid.New()
+func (r *orderRepositoryImpl) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, order *dto.Order) (*dto.Order, error) {
+	var orderData dto.Order
+
+	if err := tx.QueryRowContext(ctx, "INSERT INTO public.orders (id, type, product_id, quantity) VALUES ($1, $2, $3, $4) RETURNING id, type, product_id, quantity", uuid.New(), order.Type, order.ProductID, order.Quantity).Scan(&orderData.ID, &orderData.Type, &orderData.ProductID, &orderData.Quantity); err != nil {
+		return nil, logError(ctx, err)
+	}
 
-	_, err := tx.Exec("INSERT INTO public.orders (id, type, product_id, quantity) VALUES ($1, $2, $3, $4)", uuid, order.Type, order.ProductID, order.Quantity)
+	return &orderData, nil
+}
+
+func (r *orderRepositoryImpl) SaveBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, orders []*dto.Order) error {
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema("public", "orders", "id", "type", "product_id", "quantity"))
 	if err != nil {
-		return err
+		return logError(ctx, err)
 	}
+	defer stmt.Close()
 
-	return nil
+	for _, order := range orders {
+		if _, err := stmt.ExecContext(ctx, uuid.New(), order.Type, order.ProductID, order.Quantity); err != nil {
+			return logError(ctx, err)
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
+	return logError(ctx, err)
 }
 
-func (r *orderRepositoryImpl) FindAll(pagination *web.PaginationRequest) ([]*dto.Order, error) {
+func (r *orderRepositoryImpl) FindAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Order, *web.PageInfo, error) {
 	var orderData []*dto.Order
+	var total int64
 
-	offset := (pagination.Page - 1) * pagination.Size
+	var rows *sqlx.Rows
+	var err error
 
-	rows, err := r.db.Queryx("SELECT id, type, product_id, quantity FROM public.orders OFFSET $1 LIMIT $2", offset, pagination.Size)
+	if pagination.Keyset {
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, type, product_id, quantity, 0 FROM public.orders WHERE ($1::uuid[] IS NULL OR product_id IN (SELECT id FROM public.products WHERE location_id = ANY($1::uuid[]))) AND id > $2 ORDER BY id LIMIT $3", scopeFilter(scope), pagination.After, pagination.Size+1)
+	} else {
+		offset := (pagination.Page - 1) * pagination.Size
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, type, product_id, quantity, COUNT(*) OVER() FROM public.orders WHERE ($1::uuid[] IS NULL OR product_id IN (SELECT id FROM public.products WHERE location_id = ANY($1::uuid[]))) OFFSET $2 LIMIT $3", scopeFilter(scope), offset, pagination.Size)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, logError(ctx, err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var order dto.Order
-		if err := rows.Scan(&order.ID, &order.Type, &order.ProductID, &order.Quantity); err != nil {
-			return nil, err
+		if err := rows.Scan(&order.ID, &order.Type, &order.ProductID, &order.Quantity, &total); err != nil {
+			return nil, nil, logError(ctx, err)
 		}
 		orderData = append(orderData, &order)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, logError(ctx, err)
 	}
 
-	return orderData, nil
+	return buildPage(ctx, r.db, pagination, orderData, total, "SELECT COUNT(*) FROM public.orders WHERE ($1::uuid[] IS NULL OR product_id IN (SELECT id FROM public.products WHERE location_id = ANY($1::uuid[])))", scopeFilter(scope))
 }
 
-func (r *orderRepositoryImpl) FindByID(id uuid.UUID) (*dto.Order, int, error) {
-	var orderData dto.Order
+func (r *orderRepositoryImpl) StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Order) error) error {
+	rows, err := r.db.QueryxContext(ctx, "SELECT id, type, product_id, quantity FROM public.orders WHERE ($1::uuid[] IS NULL OR product_id IN (SELECT id FROM public.products WHERE location_id = ANY($1::uuid[]))) AND id > $2 ORDER BY id LIMIT $3", scopeFilter(scope), pagination.After, streamLimit(pagination))
+	if err != nil {
+		return logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var order dto.Order
+		if err := rows.Scan(&order.ID, &order.Type, &order.ProductID, &order.Quantity); err != nil {
+			return logError(ctx, err)
+		}
 
-	if err := r.db.QueryRow("SELECT id, type, product_id, quantity FROM public.orders WHERE id = $1", id).Scan(&orderData.ID, &orderData.Type, &orderData.ProductID, &orderData.Quantity); err != nil {
-		if sql.ErrNoRows != nil {
-			return nil, 404, sql.ErrNoRows
+		if err := fn(&order); err != nil {
+			return logError(ctx, err)
 		}
+	}
+
+	return logError(ctx, rows.Err())
+}
+
+func (r *orderRepositoryImpl) FindByID(ctx context.Context, id uuid.UUID) (*dto.Order, error) {
+	var orderData dto.Order
 
-		return nil, 500, err
+	if err := r.db.QueryRowContext(ctx, "SELECT id, type, product_id, quantity FROM public.orders WHERE id = $1", id).Scan(&orderData.ID, &orderData.Type, &orderData.ProductID, &orderData.Quantity); err != nil {
+		return nil, notFoundOr(ctx, err, errOrderNotFound)
 	}
 
-	return &orderData, 200, nil
+	return &orderData, nil
 }
@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+)
+
+// buildPage turns the rows of a listing query into a page. Keyset queries
+// fetch one look-ahead row which is trimmed here; offset queries carry the
+// window count, which is re-queried with count only when the page is past
+// the end.
+func buildPage[T any](ctx context.Context, db *sqlx.DB, pagination *web.PaginationRequest, items []T, total int64, count string, args ...any) ([]T, *web.PageInfo, error) {
+	if pagination.Keyset {
+		hasNext := len(items) > pagination.Size
+		if hasNext {
+			items = items[:pagination.Size]
+		}
+
+		return items, &web.PageInfo{HasNext: hasNext}, nil
+	}
+
+	if len(items) == 0 && pagination.Page > 1 {
+		if err := db.GetContext(ctx, &total, count, args...); err != nil {
+			return nil, nil, logError(ctx, err)
+		}
+	}
+
+	return items, &web.PageInfo{
+		TotalItems: total,
+		HasNext:    int64(pagination.Page*pagination.Size) < total,
+	}, nil
+}
+
+// streamLimit maps an unset size to SQL NULL, which Postgres reads as LIMIT ALL.
+func streamLimit(pagination *web.PaginationRequest) sql.NullInt64 {
+	if pagination.Size <= 0 {
+		return sql.NullInt64{}
+	}
+
+	return sql.NullInt64{Int64: int64(pagination.Size), Valid: true}
+}
+
+// scopeFilter passes the locations a query may touch as a uuid[] parameter.
+// A scope covering every location becomes NULL so the filter is skipped.
+func scopeFilter(scope *dto.LocationScope) pq.StringArray {
+	if scope != nil && scope.All {
+		return nil
+	}
+
+	ids := pq.StringArray{}
+	if scope != nil {
+		for _, id := range scope.LocationIDs {
+			ids = append(ids, id.String())
+		}
+	}
+
+	return ids
+}
@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type PermissionRepository interface {
+	ListPermissions(ctx context.Context) ([]*dto.Permission, error)
+	ListRoles(ctx context.Context) ([]*dto.Role, error)
+	FindRole(ctx context.Context, name dto.UserRole) (*dto.Role, error)
+	SaveRoleWithTransaction(ctx context.Context, tx *sqlx.Tx, role *dto.Role) error
+	UpdateRoleWithTransaction(ctx context.Context, tx *sqlx.Tx, role *dto.Role) error
+	ReplaceRolePermissionsWithTransaction(ctx context.Context, tx *sqlx.Tx, role dto.UserRole, permissions []string) error
+	CountUsersWithRole(ctx context.Context, name dto.UserRole) (int64, error)
+	DeleteRole(ctx context.Context, name dto.UserRole) error
+}
+
+var errRoleNotFound = domain.NotFound("role_not_found", "role not found")
+
+type permissionRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewPermissionRepository(db *sqlx.DB) PermissionRepository {
+	return &permissionRepositoryImpl{
+		db: db,
+	}
+}
+
+const selectRoles = `SELECT r.name, r.description,
+	COALESCE(array_agg(rp.permission ORDER BY rp.permission) FILTER (WHERE rp.permission IS NOT NULL), '{}')
+	FROM public.roles r LEFT JOIN public.role_permissions rp ON rp.role = r.name`
+
+func (r *permissionRepositoryImpl) ListPermissions(ctx context.Context) ([]*dto.Permission, error) {
+	var permissionData []*dto.Permission
+
+	rows, err := r.db.QueryxContext(ctx, "SELECT name, description FROM public.permissions ORDER BY name")
+	if err != nil {
+		return nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var permission dto.Permission
+		if err := rows.Scan(&permission.Name, &permission.Description); err != nil {
+			return nil, logError(ctx, err)
+		}
+		permissionData = append(permissionData, &permission)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logError(ctx, err)
+	}
+
+	return permissionData, nil
+}
+
+func (r *permissionRepositoryImpl) ListRoles(ctx context.Context) ([]*dto.Role, error) {
+	var roleData []*dto.Role
+
+	rows, err := r.db.QueryxContext(ctx, selectRoles+" GROUP BY r.name, r.description ORDER BY r.name")
+	if err != nil {
+		return nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role dto.Role
+		if err := rows.Scan(&role.Name, &role.Description, (*pq.StringArray)(&role.Permissions)); err != nil {
+			return nil, logError(ctx, err)
+		}
+		roleData = append(roleData, &role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logError(ctx, err)
+	}
+
+	return roleData, nil
+}
+
+func (r *permissionRepositoryImpl) FindRole(ctx context.Context, name dto.UserRole) (*dto.Role, error) {
+	var role dto.Role
+
+	if err := r.db.QueryRowContext(ctx, selectRoles+" WHERE r.name = $1 GROUP BY r.name, r.description", name).Scan(&role.Name, &role.Description, (*pq.StringArray)(&role.Permissions)); err != nil {
+		return nil, notFoundOr(ctx, err, errRoleNotFound)
+	}
+
+	return &role, nil
+}
+
+func (r *permissionRepositoryImpl) SaveRoleWithTransaction(ctx context.Context, tx *sqlx.Tx, role *dto.Role) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO public.roles (name, description) VALUES ($1, $2)", role.Name, role.Description)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *permissionRepositoryImpl) UpdateRoleWithTransaction(ctx context.Context, tx *sqlx.Tx, role *dto.Role) error {
+	result, err := tx.ExecContext(ctx, "UPDATE public.roles SET description = $2 WHERE name = $1", role.Name, role.Description)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errRoleNotFound)
+}
+
+func (r *permissionRepositoryImpl) ReplaceRolePermissionsWithTransaction(ctx context.Context, tx *sqlx.Tx, role dto.UserRole, permissions []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM public.role_permissions WHERE role = $1", role); err != nil {
+		return logError(ctx, err)
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO public.role_permissions (role, permission) SELECT $1, unnest($2::varchar[])", role, pq.Array(permissions))
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *permissionRepositoryImpl) CountUsersWithRole(ctx context.Context, name dto.UserRole) (int64, error) {
+	var count int64
+
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM public.users WHERE role = $1", name).Scan(&count); err != nil {
+		return 0, logError(ctx, err)
+	}
+
+	return count, nil
+}
+
+func (r *permissionRepositoryImpl) DeleteRole(ctx context.Context, name dto.UserRole) error {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM public.roles WHERE name = $1", name)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errRoleNotFound)
+}
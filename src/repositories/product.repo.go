@@ -1,26 +1,47 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 )
 
 type ProductRepository interface {
-	Save(product *dto.Product) error
-	Update(product *dto.Product) error
-	FindByID(id uuid.UUID) (*dto.Product, int, error)
-	FindByName(name string) (*dto.Product, int, error)
-	FindBySKU(sku string) (*dto.Product, int, error)
-	GetAllProduct(pagination *web.PaginationRequest) ([]*dto.Product, error)
-	Delete(id uuid.UUID) (int, error)
-	IncreaseStockWithTransaction(tx *sqlx.Tx, productID uuid.UUID, quantity int64) (int, error)
-	DecreaseStockWithTransaction(tx *sqlx.Tx, productID uuid.UUID, quantity int64) (int, error)
+	SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, product *dto.Product) (*dto.Product, error)
+	UpdateWithTransaction(ctx context.Context, tx *sqlx.Tx, product *dto.Product) error
+	PatchWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, patch *dto.ProductPatch, version int64) (*dto.Product, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*dto.Product, error)
+	FindByName(ctx context.Context, name string) (*dto.Product, error)
+	FindBySKU(ctx context.Context, sku string) (*dto.Product, error)
+	FindByNamesOrSKUs(ctx context.Context, names []string, skus []string) ([]*dto.Product, error)
+	GetAllProduct(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Product, *web.PageInfo, error)
+	StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Product) error) error
+	DeleteWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, deletedBy uuid.UUID) error
+	FindDeletedByID(ctx context.Context, id uuid.UUID) (*dto.Product, error)
+	RestoreWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*dto.Product, error)
+	SaveBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, products []*dto.Product) error
+	IncreaseStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productID uuid.UUID, quantity int64) error
+	DecreaseStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productID uuid.UUID, quantity int64) error
+	LockStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productIDs []uuid.UUID) (map[uuid.UUID]*dto.Product, error)
+	AdjustStockBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, deltas map[uuid.UUID]int64) error
 }
 
+var (
+	errProductNotFound   = domain.NotFound("product_not_found", "product not found")
+	errInsufficientStock = domain.InsufficientStock("insufficient_stock", "insufficient stock")
+	errProductSKUTaken   = domain.Conflict("product_sku_taken", "product sku is exists")
+	errVersionMismatch   = domain.PreconditionFailed("version_mismatch", "product was changed since it was read")
+	errProductHasStock   = domain.Conflict("product_has_stock", "product still has stock")
+	errLocationDeleted   = domain.Conflict("location_deleted", "location is deleted")
+)
+
 type productRepositoryImpl struct {
 	db *sqlx.DB
 }
@@ -31,136 +52,375 @@ func NewProductRepository(db *sqlx.DB) ProductRepository {
 	}
 }
 
-func (r *productRepositoryImpl) Save(product *dto.Product) error {
-	uuid := uuid.New()
+func (r *productRepositoryImpl) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, product *dto.Product) (*dto.Product, error) {
+	var productData dto.Product
 
-	_, err := r.db.Exec("INSERT INTO public.products (id, name, sku, quantity, location_id) VALUES ($1, $2, $3, $4, $5)", uuid, product.Name, product.SKU, product.Quantity, product.LocationID)
+	err := tx.QueryRowContext(ctx, `INSERT INTO public.products (id, name, sku, quantity, location_id)
+		SELECT $1, $2, $3, $4, $5 WHERE EXISTS (SELECT 1 FROM public.locations WHERE id = $5 AND deleted_at IS NULL)
+		RETURNING id, name, sku, quantity, location_id, version`, uuid.New(), product.Name, product.SKU, product.Quantity, product.LocationID).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil, errProductSKUTaken
+	}
 	if err != nil {
-		return err
+		return nil, notFoundOr(ctx, err, errLocationNotFound)
 	}
 
-	return nil
+	return &productData, nil
 }
 
-func (r *productRepositoryImpl) Update(product *dto.Product) error {
-	_, err := r.db.Exec("UPDATE public.products SET name = $2, sku = $3, quantity = $4, location_id = $5 WHERE id = $1", product.ID, product.Name, product.SKU, product.Quantity, product.LocationID)
+// Update overwrites the product only while its version still equals
+// product.Version (0 matches any) and its location is live, and stores the new
+// version back in product.Version.
+func (r *productRepositoryImpl) UpdateWithTransaction(ctx context.Context, tx *sqlx.Tx, product *dto.Product) error {
+	err := tx.QueryRowContext(ctx, `UPDATE public.products SET name = $2, sku = $3, quantity = $4, location_id = $5, version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($6::int8 = 0 OR version = $6::int8)
+			AND EXISTS (SELECT 1 FROM public.locations WHERE id = $5 AND deleted_at IS NULL)
+		RETURNING version`, product.ID, product.Name, product.SKU, product.Quantity, product.LocationID, product.Version).Scan(&product.Version)
 	if err != nil {
-		return err
+		return r.writeError(ctx, tx, product.ID, &product.LocationID, err)
 	}
 
 	return nil
 }
 
-func (r *productRepositoryImpl) FindByID(id uuid.UUID) (*dto.Product, int, error) {
+// Patch sets the fields present in patch. A version of 0 applies the patch to
+// whatever version is current. A new location must be live.
+func (r *productRepositoryImpl) PatchWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, patch *dto.ProductPatch, version int64) (*dto.Product, error) {
 	var productData dto.Product
 
-	if err := r.db.QueryRow("SELECT id, name, sku, quantity, location_id FROM public.products WHERE id = $1", id).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, 404, sql.ErrNoRows
+	err := tx.QueryRowContext(ctx, `UPDATE public.products SET
+			name = COALESCE($2, name),
+			sku = COALESCE($3, sku),
+			location_id = COALESCE($4, location_id),
+			version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL AND ($5::int8 = 0 OR version = $5::int8)
+			AND ($4::uuid IS NULL OR EXISTS (SELECT 1 FROM public.locations WHERE id = $4 AND deleted_at IS NULL))
+		RETURNING id, name, sku, quantity, location_id, version`, id, patch.Name, patch.SKU, patch.LocationID, version).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version)
+	if err != nil {
+		return nil, r.writeError(ctx, tx, id, patch.LocationID, err)
+	}
+
+	return &productData, nil
+}
+
+// writeError explains a conditional write that failed: when no row matched,
+// the product is gone, its new location is gone, or it is at another version.
+func (r *productRepositoryImpl) writeError(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, locationID *uuid.UUID, err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return errProductSKUTaken
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return logError(ctx, err)
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM public.products WHERE id = $1 AND deleted_at IS NULL)", id).Scan(&exists); err != nil {
+		return logError(ctx, err)
+	}
+
+	if !exists {
+		return errProductNotFound
+	}
+
+	if locationID != nil {
+		if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM public.locations WHERE id = $1 AND deleted_at IS NULL)", *locationID).Scan(&exists); err != nil {
+			return logError(ctx, err)
 		}
 
-		return nil, 500, err
+		if !exists {
+			return errLocationNotFound
+		}
 	}
 
-	return &productData, 200, nil
+	return errVersionMismatch
 }
 
-func (r *productRepositoryImpl) FindByName(name string) (*dto.Product, int, error) {
+func (r *productRepositoryImpl) FindByID(ctx context.Context, id uuid.UUID) (*dto.Product, error) {
 	var productData dto.Product
 
-	if err := r.db.QueryRow("SELECT id, name, sku, quantity, location_id FROM public.products WHERE name = $1", name).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, 404, sql.ErrNoRows
-		}
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE id = $1 AND deleted_at IS NULL", id).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version); err != nil {
+		return nil, notFoundOr(ctx, err, errProductNotFound)
+	}
+
+	return &productData, nil
+}
+
+func (r *productRepositoryImpl) FindByName(ctx context.Context, name string) (*dto.Product, error) {
+	var productData dto.Product
 
-		return nil, 500, err
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE name = $1 AND deleted_at IS NULL", name).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version); err != nil {
+		return nil, notFoundOr(ctx, err, errProductNotFound)
 	}
 
-	return &productData, 200, nil
+	return &productData, nil
 }
 
-func (r *productRepositoryImpl) FindBySKU(sku string) (*dto.Product, int, error) {
+func (r *productRepositoryImpl) FindBySKU(ctx context.Context, sku string) (*dto.Product, error) {
 	var productData dto.Product
 
-	if err := r.db.QueryRow("SELECT id, name, sku, quantity, location_id FROM public.products WHERE sku = $1", sku).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, 404, sql.ErrNoRows
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE sku = $1 AND deleted_at IS NULL", sku).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version); err != nil {
+		return nil, notFoundOr(ctx, err, errProductNotFound)
+	}
+
+	return &productData, nil
+}
+
+func (r *productRepositoryImpl) FindByNamesOrSKUs(ctx context.Context, names []string, skus []string) ([]*dto.Product, error) {
+	var productData []*dto.Product
+
+	rows, err := r.db.QueryxContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE (name = ANY($1) OR sku = ANY($2)) AND deleted_at IS NULL", pq.Array(names), pq.Array(skus))
+	if err != nil {
+		return nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product dto.Product
+		if err := rows.Scan(&product.ID, &product.Name, &product.SKU, &product.Quantity, &product.LocationID, &product.Version); err != nil {
+			return nil, logError(ctx, err)
 		}
+		productData = append(productData, &product)
+	}
 
-		return nil, 500, err
+	if err := rows.Err(); err != nil {
+		return nil, logError(ctx, err)
 	}
 
-	return &productData, 200, nil
+	return productData, nil
 }
 
-func (r *productRepositoryImpl) GetAllProduct(pagination *web.PaginationRequest) ([]*dto.Product, error) {
+func (r *productRepositoryImpl) GetAllProduct(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Product, *web.PageInfo, error) {
 	var productData []*dto.Product
+	var total int64
 
-	offset := (pagination.Page - 1) * pagination.Size
+	var rows *sqlx.Rows
+	var err error
 
-	rows, err := r.db.Queryx("SELECT id, name, sku, quantity, location_id FROM public.products OFFSET $1 LIMIT $2", offset, pagination.Size)
+	if pagination.Keyset {
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, name, sku, quantity, location_id, version, 0 FROM public.products WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR location_id = ANY($1::uuid[])) AND id > $2 ORDER BY id LIMIT $3", scopeFilter(scope), pagination.After, pagination.Size+1)
+	} else {
+		offset := (pagination.Page - 1) * pagination.Size
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, name, sku, quantity, location_id, version, COUNT(*) OVER() FROM public.products WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR location_id = ANY($1::uuid[])) OFFSET $2 LIMIT $3", scopeFilter(scope), offset, pagination.Size)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, logError(ctx, err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var product dto.Product
-		if err := rows.Scan(&product.ID, &product.Name, &product.SKU, &product.Quantity, &product.LocationID); err != nil {
-			return nil, err
+		if err := rows.Scan(&product.ID, &product.Name, &product.SKU, &product.Quantity, &product.LocationID, &product.Version, &total); err != nil {
+			return nil, nil, logError(ctx, err)
 		}
 		productData = append(productData, &product)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, logError(ctx, err)
 	}
 
-	return productData, nil
+	return buildPage(ctx, r.db, pagination, productData, total, "SELECT COUNT(*) FROM public.products WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR location_id = ANY($1::uuid[]))", scopeFilter(scope))
+}
+
+func (r *productRepositoryImpl) StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Product) error) error {
+	rows, err := r.db.QueryxContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE deleted_at IS NULL AND ($1::uuid[] IS NULL OR location_id = ANY($1::uuid[])) AND id > $2 ORDER BY id LIMIT $3", scopeFilter(scope), pagination.After, streamLimit(pagination))
+	if err != nil {
+		return logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var product dto.Product
+		if err := rows.Scan(&product.ID, &product.Name, &product.SKU, &product.Quantity, &product.LocationID, &product.Version); err != nil {
+			return logError(ctx, err)
+		}
+
+		if err := fn(&product); err != nil {
+			return logError(ctx, err)
+		}
+	}
+
+	return logError(ctx, rows.Err())
+}
+
+// Delete hides the product, but only while it holds no stock. Its orders
+// are kept. Orders have no open state: each one moves stock in the same
+// transaction that records it, and only after locking a live product row.
+// So "no open orders" is the row lock taken here; a check for any order row
+// would make every product that ever moved stock undeletable.
+func (r *productRepositoryImpl) DeleteWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, deletedBy uuid.UUID) error {
+	var deleted, found bool
+
+	err := tx.QueryRowContext(ctx, `WITH deleted AS (
+			UPDATE public.products SET deleted_at = now(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL AND quantity = 0 RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM deleted), EXISTS (SELECT 1 FROM public.products WHERE id = $1 AND deleted_at IS NULL)`, id, deletedBy).Scan(&deleted, &found)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	if !found {
+		return errProductNotFound
+	}
+
+	if !deleted {
+		return errProductHasStock
+	}
+
+	return nil
+}
+
+func (r *productRepositoryImpl) FindDeletedByID(ctx context.Context, id uuid.UUID) (*dto.Product, error) {
+	var productData dto.Product
+
+	if err := r.db.QueryRowContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE id = $1 AND deleted_at IS NOT NULL", id).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version); err != nil {
+		return nil, notFoundOr(ctx, err, errProductNotFound)
+	}
+
+	return &productData, nil
 }
 
-func (r *productRepositoryImpl) Delete(id uuid.UUID) (int, error) {
-	result, err := r.db.Exec("DELETE FROM public.products WHERE id = $1", id)
+// Restore brings a deleted product back, as long as its location is still
+// live and no live product took its SKU in the meantime.
+func (r *productRepositoryImpl) RestoreWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*dto.Product, error) {
+	var productData dto.Product
+
+	err := tx.QueryRowContext(ctx, `UPDATE public.products p SET deleted_at = NULL, deleted_by = NULL, version = p.version + 1
+		WHERE p.id = $1 AND p.deleted_at IS NOT NULL
+			AND EXISTS (SELECT 1 FROM public.locations l WHERE l.id = p.location_id AND l.deleted_at IS NULL)
+		RETURNING p.id, p.name, p.sku, p.quantity, p.location_id, p.version`, id).Scan(&productData.ID, &productData.Name, &productData.SKU, &productData.Quantity, &productData.LocationID, &productData.Version)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return nil, errProductSKUTaken
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		if _, err := r.FindDeletedByID(ctx, id); err != nil {
+			return nil, logError(ctx, err)
+		}
+
+		return nil, errLocationDeleted
+	}
 	if err != nil {
-		return 500, err
+		return nil, logError(ctx, err)
+	}
+
+	return &productData, nil
+}
+
+// SaveBatchWithTransaction copies products in one go. COPY cannot check the
+// locations row by row, so they are checked and share-locked up front, which
+// keeps them from being deleted before the transaction commits.
+func (r *productRepositoryImpl) SaveBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, products []*dto.Product) error {
+	locationIDs := make(map[uuid.UUID]struct{}, len(products))
+	ids := make([]string, 0, len(products))
+	for _, product := range products {
+		if _, ok := locationIDs[product.LocationID]; !ok {
+			locationIDs[product.LocationID] = struct{}{}
+			ids = append(ids, product.LocationID.String())
+		}
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	var live int
+	if err := tx.QueryRowContext(ctx, "SELECT count(*) FROM (SELECT id FROM public.locations WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL FOR SHARE) AS live", pq.Array(ids)).Scan(&live); err != nil {
+		return logError(ctx, err)
+	}
+
+	if live != len(ids) {
+		return errLocationNotFound
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyInSchema("public", "products", "id", "name", "sku", "quantity", "location_id"))
 	if err != nil {
-		return 500, err
+		return logError(ctx, err)
+	}
+	defer stmt.Close()
+
+	for _, product := range products {
+		if _, err := stmt.ExecContext(ctx, uuid.New(), product.Name, product.SKU, product.Quantity, product.LocationID); err != nil {
+			return logError(ctx, err)
+		}
 	}
 
-	if rowsAffected == 0 {
-		return 404, sql.ErrNoRows
+	_, err = stmt.ExecContext(ctx)
+	return logError(ctx, err)
+}
+
+func (r *productRepositoryImpl) IncreaseStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productID uuid.UUID, quantity int64) error {
+	result, err := tx.ExecContext(ctx, "UPDATE products SET quantity = quantity + $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL", quantity, productID)
+	if err != nil {
+		return logError(ctx, err)
 	}
 
-	return 200, nil
+	return affected(ctx, result, errProductNotFound)
 }
 
-func (r *productRepositoryImpl) IncreaseStockWithTransaction(tx *sqlx.Tx, productID uuid.UUID, quantity int64) (int, error) {
-	result, err := tx.Exec("UPDATE products SET quantity = quantity + $1 WHERE id = $2", quantity, productID)
-	rowsAffected, err := result.RowsAffected()
+// DecreaseStockWithTransaction never takes the stock below zero. When no row
+// is updated it tells a missing product apart from a short one.
+func (r *productRepositoryImpl) DecreaseStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productID uuid.UUID, quantity int64) error {
+	var updated, found bool
+
+	err := tx.QueryRowContext(ctx, `WITH updated AS (
+			UPDATE public.products SET quantity = quantity - $1, version = version + 1 WHERE id = $2 AND deleted_at IS NULL AND quantity >= $1 RETURNING id
+		)
+		SELECT EXISTS (SELECT 1 FROM updated), EXISTS (SELECT 1 FROM public.products WHERE id = $2 AND deleted_at IS NULL)`, quantity, productID).Scan(&updated, &found)
 	if err != nil {
-		return 500, err
+		return logError(ctx, err)
+	}
+
+	if !found {
+		return errProductNotFound
 	}
 
-	if rowsAffected == 0 {
-		return 404, sql.ErrNoRows
+	if !updated {
+		return errInsufficientStock
 	}
 
-	return 200, nil
+	return nil
 }
 
-func (r *productRepositoryImpl) DecreaseStockWithTransaction(tx *sqlx.Tx, productID uuid.UUID, quantity int64) (int, error) {
-	result, err := tx.Exec("UPDATE products SET quantity = quantity - $1 WHERE id = $2", quantity, productID)
-	rowsAffected, err := result.RowsAffected()
+func (r *productRepositoryImpl) LockStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productIDs []uuid.UUID) (map[uuid.UUID]*dto.Product, error) {
+	ids := make([]string, len(productIDs))
+	for i, id := range productIDs {
+		ids[i] = id.String()
+	}
+
+	rows, err := tx.QueryxContext(ctx, "SELECT id, name, sku, quantity, location_id, version FROM public.products WHERE id = ANY($1::uuid[]) AND deleted_at IS NULL ORDER BY id FOR UPDATE", pq.Array(ids))
 	if err != nil {
-		return 500, err
+		return nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	productData := make(map[uuid.UUID]*dto.Product, len(productIDs))
+	for rows.Next() {
+		var product dto.Product
+		if err := rows.Scan(&product.ID, &product.Name, &product.SKU, &product.Quantity, &product.LocationID, &product.Version); err != nil {
+			return nil, logError(ctx, err)
+		}
+		productData[product.ID] = &product
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logError(ctx, err)
 	}
 
-	if rowsAffected == 0 {
-		return 404, sql.ErrNoRows
+	return productData, nil
+}
+
+func (r *productRepositoryImpl) AdjustStockBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, deltas map[uuid.UUID]int64) error {
+	if len(deltas) == 0 {
+		return nil
 	}
 
-	return 200, nil
+	ids := make([]string, 0, len(deltas))
+	amounts := make([]int64, 0, len(deltas))
+	for id, delta := range deltas {
+		ids = append(ids, id.String())
+		amounts = append(amounts, delta)
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE public.products p SET quantity = p.quantity + d.delta, version = p.version + 1
+		FROM unnest($1::uuid[], $2::int8[]) AS d(id, delta)
+		WHERE p.id = d.id`, pq.Array(ids), pq.Array(amounts))
+
+	return logError(ctx, err)
 }
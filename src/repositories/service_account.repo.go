@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type ServiceAccountRepository interface {
+	SaveServiceAccountWithTransaction(ctx context.Context, tx *sqlx.Tx, account *dto.User) error
+	SaveAPIKey(ctx context.Context, key *dto.APIKey) error
+	GetAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*dto.APIKey, error)
+	RevokeAPIKey(ctx context.Context, serviceAccountID uuid.UUID, keyID uuid.UUID) error
+	UseAPIKey(ctx context.Context, hash string) (*dto.APIKeyIdentity, error)
+}
+
+var (
+	errAPIKeyNotFound    = domain.NotFound("api_key_not_found", "api key not found")
+	errUnknownPermission = domain.Validation("unknown_permission", "permission not found")
+)
+
+type serviceAccountRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewServiceAccountRepository(db *sqlx.DB) ServiceAccountRepository {
+	return &serviceAccountRepositoryImpl{
+		db: db,
+	}
+}
+
+// SaveServiceAccountWithTransaction stores the account with an empty password, which no
+// password verifies against.
+func (r *serviceAccountRepositoryImpl) SaveServiceAccountWithTransaction(ctx context.Context, tx *sqlx.Tx, account *dto.User) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO public.users (id, email, password, name, role, service_account) VALUES ($1, $2, '', $3, $4, true)", account.ID, account.Email, account.Name, account.Role)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23503":
+				return errUnknownRole
+			case "23505":
+				return errEmailTaken
+			}
+		}
+
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *serviceAccountRepositoryImpl) SaveAPIKey(ctx context.Context, key *dto.APIKey) error {
+	_, err := r.db.ExecContext(ctx, `WITH saved AS (
+			INSERT INTO public.api_keys (id, user_id, name, prefix, key_hash, expires_at, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id
+		)
+		INSERT INTO public.api_key_permissions (api_key_id, permission)
+		SELECT saved.id, unnest($8::text[]) FROM saved`,
+		key.ID, key.ServiceAccountID, key.Name, key.Prefix, key.KeyHash, key.ExpiresAt, key.CreatedBy, pq.Array(key.Scopes))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return errUnknownPermission
+		}
+
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *serviceAccountRepositoryImpl) GetAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*dto.APIKey, error) {
+	var keys []*dto.APIKey
+
+	rows, err := r.db.QueryxContext(ctx, `SELECT id, user_id, name, prefix, expires_at, last_used_at, revoked_at, created_by, created_at,
+			ARRAY(SELECT permission FROM public.api_key_permissions WHERE api_key_id = api_keys.id ORDER BY permission)
+		FROM public.api_keys WHERE user_id = $1 ORDER BY created_at`, serviceAccountID)
+	if err != nil {
+		return nil, logError(ctx, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key dto.APIKey
+		if err := rows.Scan(&key.ID, &key.ServiceAccountID, &key.Name, &key.Prefix, &key.ExpiresAt, &key.LastUsedAt, &key.RevokedAt, &key.CreatedBy, &key.CreatedAt, pq.Array(&key.Scopes)); err != nil {
+			return nil, logError(ctx, err)
+		}
+		keys = append(keys, &key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, logError(ctx, err)
+	}
+
+	return keys, nil
+}
+
+func (r *serviceAccountRepositoryImpl) RevokeAPIKey(ctx context.Context, serviceAccountID uuid.UUID, keyID uuid.UUID) error {
+	result, err := r.db.ExecContext(ctx, "UPDATE public.api_keys SET revoked_at = now() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL", keyID, serviceAccountID)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errAPIKeyNotFound)
+}
+
+// UseAPIKey looks up a live key of an active service account and records
+// that it was used.
+func (r *serviceAccountRepositoryImpl) UseAPIKey(ctx context.Context, hash string) (*dto.APIKeyIdentity, error) {
+	var identity dto.APIKeyIdentity
+	user := &identity.User
+
+	err := r.db.QueryRowContext(ctx, `UPDATE public.api_keys SET last_used_at = now()
+		FROM public.users
+		WHERE api_keys.key_hash = $1 AND api_keys.revoked_at IS NULL AND (api_keys.expires_at IS NULL OR api_keys.expires_at > now())
+			AND users.id = api_keys.user_id AND users.service_account AND users.active
+		RETURNING api_keys.id, ARRAY(SELECT permission FROM public.api_key_permissions WHERE api_key_id = api_keys.id ORDER BY permission),
+			users.id, users.email, users.name, users.role, users.active, users.service_account, users.all_locations,
+			ARRAY(SELECT location_id FROM public.user_locations WHERE user_id = users.id ORDER BY location_id)`, hash).
+		Scan(&identity.KeyID, pq.Array(&identity.Scopes), &user.ID, &user.Email, &user.Name, &user.Role, &user.Active, &user.ServiceAccount, &user.Scope.All, pq.Array(&user.Scope.LocationIDs))
+	if err != nil {
+		return nil, notFoundOr(ctx, err, errAPIKeyNotFound)
+	}
+
+	return &identity, nil
+}
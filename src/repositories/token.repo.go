@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+type TokenRepository interface {
+	SaveRefreshToken(ctx context.Context, token *dto.RefreshToken) error
+	FindRefreshTokenByHash(ctx context.Context, hash string) (*dto.RefreshToken, error)
+	RotateRefreshToken(ctx context.Context, id uuid.UUID, replacedBy uuid.UUID) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error
+	RevokeAccessToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error
+	IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error)
+	RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error
+	SavePasswordResetToken(ctx context.Context, token *dto.PasswordResetToken) error
+	FindPasswordResetToken(ctx context.Context, hash string) (*dto.PasswordResetToken, error)
+	UsePasswordResetToken(ctx context.Context, hash string) (*dto.PasswordResetToken, error)
+}
+
+var errTokenNotFound = domain.NotFound("token_not_found", "token not found")
+
+type tokenRepositoryImpl struct {
+	db *sqlx.DB
+}
+
+func NewTokenRepository(db *sqlx.DB) TokenRepository {
+	return &tokenRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *tokenRepositoryImpl) SaveRefreshToken(ctx context.Context, token *dto.RefreshToken) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.refresh_tokens (id, user_id, family_id, token_hash, expires_at) VALUES ($1, $2, $3, $4, $5)", token.ID, token.UserID, token.FamilyID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *tokenRepositoryImpl) FindRefreshTokenByHash(ctx context.Context, hash string) (*dto.RefreshToken, error) {
+	var token dto.RefreshToken
+
+	if err := r.db.QueryRowContext(ctx, "SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, replaced_by FROM public.refresh_tokens WHERE token_hash = $1", hash).Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.RevokedAt, &token.ReplacedBy); err != nil {
+		return nil, notFoundOr(ctx, err, errTokenNotFound)
+	}
+
+	return &token, nil
+}
+
+// RotateRefreshToken marks the token as used. It reports false when the token
+// was already revoked, which callers treat as a replayed token.
+func (r *tokenRepositoryImpl) RotateRefreshToken(ctx context.Context, id uuid.UUID, replacedBy uuid.UUID) (bool, error) {
+	result, err := r.db.ExecContext(ctx, "UPDATE public.refresh_tokens SET revoked_at = now(), replaced_by = $2 WHERE id = $1 AND revoked_at IS NULL", id, replacedBy)
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, logError(ctx, err)
+	}
+
+	return rowsAffected == 1, nil
+}
+
+func (r *tokenRepositoryImpl) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE public.refresh_tokens SET revoked_at = now() WHERE family_id = $1 AND revoked_at IS NULL", familyID)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *tokenRepositoryImpl) RevokeAccessToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM public.revoked_tokens WHERE expires_at < now()"); err != nil {
+		return logError(ctx, err)
+	}
+
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.revoked_tokens (jti, expires_at) VALUES ($1, $2) ON CONFLICT (jti) DO NOTHING", jti, expiresAt)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *tokenRepositoryImpl) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	var revoked bool
+
+	if err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM public.revoked_tokens WHERE jti = $1)", jti).Scan(&revoked); err != nil {
+		return false, logError(ctx, err)
+	}
+
+	return revoked, nil
+}
+
+func (r *tokenRepositoryImpl) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE public.refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL", userID)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+func (r *tokenRepositoryImpl) SavePasswordResetToken(ctx context.Context, token *dto.PasswordResetToken) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO public.password_reset_tokens (id, user_id, token_hash, expires_at) VALUES ($1, $2, $3, $4)", token.ID, token.UserID, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+// FindPasswordResetToken returns a token that can still be redeemed.
+func (r *tokenRepositoryImpl) FindPasswordResetToken(ctx context.Context, hash string) (*dto.PasswordResetToken, error) {
+	var token dto.PasswordResetToken
+
+	err := r.db.QueryRowContext(ctx, `SELECT id, user_id, token_hash, expires_at, used_at FROM public.password_reset_tokens
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()`, hash).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt)
+	if err != nil {
+		return nil, notFoundOr(ctx, err, errTokenNotFound)
+	}
+
+	return &token, nil
+}
+
+// UsePasswordResetToken marks an unused, unexpired token as used and returns
+// it, so a token can only ever be redeemed once.
+func (r *tokenRepositoryImpl) UsePasswordResetToken(ctx context.Context, hash string) (*dto.PasswordResetToken, error) {
+	var token dto.PasswordResetToken
+
+	err := r.db.QueryRowContext(ctx, `UPDATE public.password_reset_tokens SET used_at = now()
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > now()
+		RETURNING id, user_id, token_hash, expires_at, used_at`, hash).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.ExpiresAt, &token.UsedAt)
+	if err != nil {
+		return nil, notFoundOr(ctx, err, errTokenNotFound)
+	}
+
+	return &token, nil
+}
@@ -1,80 +1,66 @@
 package repositories
 
 import (
-	"errors"
+	"context"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
 
 type TransactionRepository interface {
-	Begin() error
-	Commit() error
-	Rollback() error
-	Transaction(callback func() error) error
-	GetTx() (*sqlx.Tx, error)
+	Transaction(ctx context.Context, fn func(tx *sqlx.Tx) error) error
 }
 
 type transactionRepositoryImpl struct {
 	db *sqlx.DB
-	tx *sqlx.Tx
 }
 
 func NewTransactionRepository(db *sqlx.DB) TransactionRepository {
 	return &transactionRepositoryImpl{db: db}
 }
 
-func (r *transactionRepositoryImpl) Begin() (err error) {
-	r.tx, err = r.db.Beginx()
-	if err != nil {
-		err = errors.New("can't create transcation")
-		return
-	}
-
-	return err
+// Transaction runs fn in a transaction of its own, committing when fn
+// succeeds and rolling back otherwise. Every call gets a fresh tx, so
+// concurrent requests never share one.
+func (r *transactionRepositoryImpl) Transaction(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	return withTransaction(ctx, r.db, fn)
 }
 
-func (r *transactionRepositoryImpl) Commit() error {
-	err := r.tx.Commit()
-
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (r *transactionRepositoryImpl) Rollback() error {
-	err := r.tx.Rollback()
-
+// withTransaction is shared by the repositories that open transactions. A
+// failed commit is returned, so the caller never reports unsaved work as done.
+func withTransaction(ctx context.Context, db *sqlx.DB, fn func(tx *sqlx.Tx) error) (err error) {
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
-		return err
+		return logError(ctx, err)
 	}
 
-	return nil
-}
-
-func (r *transactionRepositoryImpl) Transaction(callback func() error) error {
-	var err error
-
 	defer func() {
 		if p := recover(); p != nil {
-			err = r.Rollback()
-		} else if err != nil {
-			err = r.Rollback()
-		} else {
-			r.Commit()
+			rollback(ctx, tx)
+			panic(p)
+		}
+
+		if err != nil {
+			rollback(ctx, tx)
+			return
 		}
-	}()
 
-	err = callback()
+		if err = tx.Commit(); err != nil {
+			err = logError(ctx, err)
+			return
+		}
 
-	return err
+		utils.DBTransactions.WithLabelValues(utils.TransactionCommit).Inc()
+	}()
+
+	return fn(tx)
 }
 
-func (r *transactionRepositoryImpl) GetTx() (*sqlx.Tx, error) {
-	if r.tx == nil {
-		return nil, errors.New("tx not created")
+func rollback(ctx context.Context, tx *sqlx.Tx) {
+	if err := tx.Rollback(); err != nil {
+		logError(ctx, err)
+		return
 	}
 
-	return r.tx, nil
+	utils.DBTransactions.WithLabelValues(utils.TransactionRollback).Inc()
 }
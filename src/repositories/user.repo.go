@@ -1,22 +1,44 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 )
 
 type UserRepository interface {
-	Save(register *dto.RegisterRequest) (err error)
-	FindByEmail(email string) (user *dto.User, code int, err error)
-	FindByID(id uuid.UUID) (user *dto.User, code int, err error)
-	GetAll(pagination *web.PaginationRequest) ([]*dto.User, error)
+	Save(ctx context.Context, register *dto.RegisterRequest) (err error)
+	SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, register *dto.RegisterRequest) error
+	SaveFirstAdmin(ctx context.Context, register *dto.RegisterRequest) error
+	FindByEmail(ctx context.Context, email string) (user *dto.User, err error)
+	FindByID(ctx context.Context, id uuid.UUID) (user *dto.User, err error)
+	GetAll(ctx context.Context, pagination *web.PaginationRequest) ([]*dto.User, *web.PageInfo, error)
+	SetLocationScopeWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, scope *dto.LocationScope) error
+	Update(ctx context.Context, user *dto.User) error
+	UpdateWithTransaction(ctx context.Context, tx *sqlx.Tx, user *dto.User) error
+	UpdatePasswordWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, password string) error
+	RehashPassword(ctx context.Context, id uuid.UUID, oldPassword string, newPassword string) error
+	GetPasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error)
+	SetActiveWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, active bool) error
+	IsActive(ctx context.Context, id uuid.UUID, role dto.UserRole) (bool, error)
 }
 
+var (
+	errUserNotFound    = domain.NotFound("user_not_found", "user not found")
+	errEmailTaken      = domain.Conflict("email_taken", "email is exists")
+	errAdminExists     = domain.Conflict("admin_exists", "admin already exists")
+	errUnknownRole     = domain.Validation("unknown_role", "role not found")
+	errUnknownLocation = domain.Validation("unknown_location", "location not found")
+)
+
 type userRepositoryImpl struct {
 	db *sqlx.DB
 }
@@ -27,67 +49,254 @@ func NewUserRepository(db *sqlx.DB) UserRepository {
 	}
 }
 
-func (r *userRepositoryImpl) GetAll(pagination *web.PaginationRequest) ([]*dto.User, error) {
+func (r *userRepositoryImpl) GetAll(ctx context.Context, pagination *web.PaginationRequest) ([]*dto.User, *web.PageInfo, error) {
 	var userData []*dto.User
+	var total int64
 
-	offset := (pagination.Page - 1) * pagination.Size
+	var rows *sqlx.Rows
+	var err error
 
-	rows, err := r.db.Queryx("SELECT id, email, name, role FROM public.users OFFSET $1 LIMIT $2", offset, pagination.Size)
+	if pagination.Keyset {
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, email, name, role, active, mfa_enabled, service_account, all_locations, ARRAY(SELECT location_id FROM public.user_locations WHERE user_id = users.id ORDER BY location_id), 0 FROM public.users WHERE id > $1 ORDER BY id LIMIT $2", pagination.After, pagination.Size+1)
+	} else {
+		offset := (pagination.Page - 1) * pagination.Size
+		rows, err = r.db.QueryxContext(ctx, "SELECT id, email, name, role, active, mfa_enabled, service_account, all_locations, ARRAY(SELECT location_id FROM public.user_locations WHERE user_id = users.id ORDER BY location_id), COUNT(*) OVER() FROM public.users OFFSET $1 LIMIT $2", offset, pagination.Size)
+	}
 	if err != nil {
-		return nil, err
+		return nil, nil, logError(ctx, err)
 	}
 	defer rows.Close()
 
 	for rows.Next() {
 		var user dto.User
-		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Role); err != nil {
-			return nil, err
+		if err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.Active, &user.MFAEnabled, &user.ServiceAccount, &user.Scope.All, pq.Array(&user.Scope.LocationIDs), &total); err != nil {
+			return nil, nil, logError(ctx, err)
 		}
 		userData = append(userData, &user)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, err
+		return nil, nil, logError(ctx, err)
 	}
 
-	return userData, nil
+	return buildPage(ctx, r.db, pagination, userData, total, "SELECT COUNT(*) FROM public.users")
 }
 
-func (r *userRepositoryImpl) Save(register *dto.RegisterRequest) (err error) {
-	uuid := uuid.New()
+func (r *userRepositoryImpl) Save(ctx context.Context, register *dto.RegisterRequest) (err error) {
+	_, err = r.db.ExecContext(ctx, "INSERT INTO public.users (id, email, password, name, role, all_locations) VALUES ($1, $2, $3, $4, $5, $6)", register.ID, register.Email, register.Password, register.Name, register.Role, register.AllLocations)
+	if err != nil {
+		return logError(ctx, err)
+	}
 
-	_, err = r.db.Exec("INSERT INTO public.users (id, email, password, name, role) VALUES ($1, $2, $3, $4, $5)", uuid, register.Email, register.Password, register.Name, register.Role)
+	return nil
+}
+
+func (r *userRepositoryImpl) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, register *dto.RegisterRequest) error {
+	_, err := tx.ExecContext(ctx, "INSERT INTO public.users (id, email, password, name, role, all_locations) VALUES ($1, $2, $3, $4, $5, $6)", register.ID, register.Email, register.Password, register.Name, register.Role, register.AllLocations)
 	if err != nil {
-		return err
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return errEmailTaken
+		}
+
+		return logError(ctx, err)
 	}
 
 	return nil
 }
 
-func (r *userRepositoryImpl) FindByEmail(email string) (user *dto.User, code int, err error) {
-	var userData dto.User
+// firstAdminLock is the advisory lock key that serializes bootstrap runs.
+const firstAdminLock = 7_245_001
 
-	if err := r.db.QueryRow("SELECT id, email, name, role, password FROM public.users WHERE email = $1", email).Scan(&userData.ID, &userData.Email, &userData.Name, &userData.Role, &userData.Password); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, 404, sql.ErrNoRows
+// SaveFirstAdmin only inserts while no admin exists, so bootstrapping can't
+// be used to mint a second admin once the system is set up. The advisory lock
+// makes concurrent runs take turns; under READ COMMITTED both would otherwise
+// see no admin and insert one each.
+func (r *userRepositoryImpl) SaveFirstAdmin(ctx context.Context, register *dto.RegisterRequest) error {
+	return withTransaction(ctx, r.db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", firstAdminLock); err != nil {
+			return logError(ctx, err)
 		}
 
-		return nil, 500, err
+		result, err := tx.ExecContext(ctx, `INSERT INTO public.users (id, email, password, name, role, all_locations)
+			SELECT $1, $2, $3, $4, $5, true
+			WHERE NOT EXISTS (SELECT 1 FROM public.users WHERE role = $5)`, register.ID, register.Email, register.Password, register.Name, dto.UserRoleAdmin)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return errEmailTaken
+			}
+
+			return logError(ctx, err)
+		}
+
+		return affected(ctx, result, errAdminExists)
+	})
+}
+
+func (r *userRepositoryImpl) FindByEmail(ctx context.Context, email string) (user *dto.User, err error) {
+	var userData dto.User
+
+	if err := r.db.QueryRowContext(ctx, "SELECT id, email, name, role, password, active, mfa_enabled, service_account, all_locations, ARRAY(SELECT location_id FROM public.user_locations WHERE user_id = users.id ORDER BY location_id) FROM public.users WHERE email = $1", email).Scan(&userData.ID, &userData.Email, &userData.Name, &userData.Role, &userData.Password, &userData.Active, &userData.MFAEnabled, &userData.ServiceAccount, &userData.Scope.All, pq.Array(&userData.Scope.LocationIDs)); err != nil {
+		return nil, notFoundOr(ctx, err, errUserNotFound)
 	}
 
-	return &userData, 200, nil
+	return &userData, nil
 }
 
-func (r *userRepositoryImpl) FindByID(id uuid.UUID) (user *dto.User, code int, err error) {
+func (r *userRepositoryImpl) FindByID(ctx context.Context, id uuid.UUID) (user *dto.User, err error) {
 	var userData dto.User
 
-	if err := r.db.QueryRow("SELECT id, email, name, role, password FROM public.users WHERE id = $1", id).Scan(&userData.ID, &userData.Email, &userData.Name, &userData.Role, &userData.Password); err != nil {
-		if sql.ErrNoRows != nil {
-			return nil, 404, sql.ErrNoRows
+	if err := r.db.QueryRowContext(ctx, "SELECT id, email, name, role, password, active, mfa_enabled, service_account, all_locations, ARRAY(SELECT location_id FROM public.user_locations WHERE user_id = users.id ORDER BY location_id) FROM public.users WHERE id = $1", id).Scan(&userData.ID, &userData.Email, &userData.Name, &userData.Role, &userData.Password, &userData.Active, &userData.MFAEnabled, &userData.ServiceAccount, &userData.Scope.All, pq.Array(&userData.Scope.LocationIDs)); err != nil {
+		return nil, notFoundOr(ctx, err, errUserNotFound)
+	}
+
+	return &userData, nil
+}
+
+// SetLocationScope replaces the locations assigned to a user in one statement
+// so a concurrent login never sees a half-written scope.
+func (r *userRepositoryImpl) SetLocationScopeWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, scope *dto.LocationScope) error {
+	ids := make([]string, len(scope.LocationIDs))
+	for i, locationID := range scope.LocationIDs {
+		ids[i] = locationID.String()
+	}
+
+	var updated int64
+	err := tx.QueryRowContext(ctx, `WITH updated AS (
+			UPDATE public.users SET all_locations = $2 WHERE id = $1 RETURNING id
+		), removed AS (
+			DELETE FROM public.user_locations WHERE user_id IN (SELECT id FROM updated) AND NOT (location_id = ANY($3::uuid[]))
+		), added AS (
+			INSERT INTO public.user_locations (user_id, location_id)
+			SELECT updated.id, unnest($3::uuid[]) FROM updated
+			ON CONFLICT DO NOTHING
+		)
+		SELECT COUNT(*) FROM updated`, id, scope.All, pq.Array(ids)).Scan(&updated)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return errUnknownLocation
+		}
+
+		return logError(ctx, err)
+	}
+
+	if updated == 0 {
+		return errUserNotFound
+	}
+
+	return nil
+}
+
+func (r *userRepositoryImpl) Update(ctx context.Context, user *dto.User) error {
+	return updateUser(ctx, r.db, user)
+}
+
+func (r *userRepositoryImpl) UpdateWithTransaction(ctx context.Context, tx *sqlx.Tx, user *dto.User) error {
+	return updateUser(ctx, tx, user)
+}
+
+func updateUser(ctx context.Context, db sqlx.ExecerContext, user *dto.User) error {
+	result, err := db.ExecContext(ctx, "UPDATE public.users SET email = $2, name = $3, role = $4, all_locations = $5 WHERE id = $1", user.ID, user.Email, user.Name, user.Role, user.Scope.All)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return errEmailTaken
+			case "23503":
+				return errUnknownRole
+			}
+		}
+
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errUserNotFound)
+}
+
+// UpdatePasswordWithTransaction moves the current hash into the password history as part of
+// the same statement.
+func (r *userRepositoryImpl) UpdatePasswordWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, password string) error {
+	result, err := tx.ExecContext(ctx, `WITH history AS (
+			INSERT INTO public.password_history (user_id, password_hash)
+			SELECT id, password FROM public.users WHERE id = $1
+		)
+		UPDATE public.users SET password = $2 WHERE id = $1`, id, password)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errUserNotFound)
+}
+
+// RehashPassword swaps a hash for an equivalent one with stronger parameters.
+// It does nothing if the password changed since oldPassword was read.
+func (r *userRepositoryImpl) RehashPassword(ctx context.Context, id uuid.UUID, oldPassword string, newPassword string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE public.users SET password = $3 WHERE id = $1 AND password = $2", id, oldPassword, newPassword)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return nil
+}
+
+// GetPasswordHistory returns the current hash followed by up to limit-1
+// previous ones, newest first.
+func (r *userRepositoryImpl) GetPasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error) {
+	var hashes []string
+
+	err := r.db.SelectContext(ctx, &hashes, `SELECT password FROM public.users WHERE id = $1
+		UNION ALL
+		(SELECT password_hash FROM public.password_history WHERE user_id = $1 ORDER BY created_at DESC LIMIT $2)`, id, max(limit-1, 0))
+	if err != nil {
+		return nil, logError(ctx, err)
+	}
+
+	return hashes, nil
+}
+
+func (r *userRepositoryImpl) SetActiveWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, active bool) error {
+	result, err := tx.ExecContext(ctx, "UPDATE public.users SET active = $2 WHERE id = $1", id, active)
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	return affected(ctx, result, errUserNotFound)
+}
+
+// IsActive is false once the account is disabled or no longer has role.
+func (r *userRepositoryImpl) IsActive(ctx context.Context, id uuid.UUID, role dto.UserRole) (bool, error) {
+	var active bool
+
+	if err := r.db.QueryRowContext(ctx, "SELECT active AND role = $2 FROM public.users WHERE id = $1", id, role).Scan(&active); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
 		}
 
-		return nil, 500, err
+		return false, logError(ctx, err)
+	}
+
+	return active, nil
+}
+
+// affected returns notFound when a statement touched no rows.
+func affected(ctx context.Context, result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return logError(ctx, err)
+	}
+
+	if rowsAffected == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// notFoundOr turns sql.ErrNoRows into the repository's not found error and
+// logs and passes anything else through.
+func notFoundOr(ctx context.Context, err error, notFound error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return notFound
 	}
 
-	return &userData, 200, nil
+	return logError(ctx, err)
 }
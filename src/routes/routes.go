@@ -2,68 +2,162 @@ package routes
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nabilwafi/warehouse-management-system/src/handlers"
 	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 type router struct {
-	router *gin.Engine
+	router       *gin.Engine
+	authenticate gin.HandlersChain
+	authorize    func(permission string) gin.HandlerFunc
+	idempotent   gin.HandlerFunc
 
-	user     handlers.UserHandler
-	product  handlers.ProductHandler
-	location handlers.LocationHandler
-	order    handlers.OrderHandler
+	user           handlers.UserHandler
+	product        handlers.ProductHandler
+	location       handlers.LocationHandler
+	order          handlers.OrderHandler
+	key            handlers.KeyHandler
+	role           handlers.RoleHandler
+	serviceAccount handlers.ServiceAccountHandler
+	oidc           handlers.OIDCHandler
+	audit          handlers.AuditHandler
 }
 
-func NewRouter(r *gin.Engine, user handlers.UserHandler, product handlers.ProductHandler, location handlers.LocationHandler, order handlers.OrderHandler) *router {
+func NewRouter(r *gin.Engine, authenticate gin.HandlersChain, authorize func(permission string) gin.HandlerFunc, idempotent gin.HandlerFunc, user handlers.UserHandler, product handlers.ProductHandler, location handlers.LocationHandler, order handlers.OrderHandler, key handlers.KeyHandler, role handlers.RoleHandler, serviceAccount handlers.ServiceAccountHandler, oidc handlers.OIDCHandler, audit handlers.AuditHandler) *router {
 	return &router{
-		router:   r,
-		user:     user,
-		product:  product,
-		location: location,
-		order:    order,
+		router:         r,
+		authenticate:   authenticate,
+		authorize:      authorize,
+		idempotent:     idempotent,
+		user:           user,
+		product:        product,
+		location:       location,
+		order:          order,
+		key:            key,
+		role:           role,
+		serviceAccount: serviceAccount,
+		oidc:           oidc,
+		audit:          audit,
 	}
 }
 
+// Start registers the API and serves it on port.
 func (r *router) Start(port string) {
+	r.Register()
+	r.router.Run(fmt.Sprintf(":%s", port))
+}
+
+// Register adds the public API routes to the engine. Metrics are not among
+// them; StartMetrics serves those.
+func (r *router) Register() {
+	r.router.GET("/.well-known/jwks.json", r.key.JWKS)
+
 	v1 := r.router.Group("/api/v1")
 	{
-		v1.Use(middlewares.JWTMiddleware())
+		v1.Use(r.authenticate...)
 
 		v1.POST("/register", r.user.Register)
 		v1.POST("/login", r.user.Login)
 
+		auth := v1.Group("/auth")
+		{
+			auth.POST("/refresh", r.user.Refresh)
+			auth.POST("/logout", middlewares.AuthMiddleware(), middlewares.UserOnlyMiddleware(), r.user.Logout)
+			auth.POST("/password-reset", r.user.ResetPassword)
+			auth.POST("/mfa/verify", r.user.VerifyMFA)
+			auth.POST("/mfa/enroll", r.user.EnrollMFAWithChallenge)
+			auth.GET("/oidc/login", r.oidc.Login)
+			auth.GET("/oidc/callback", r.oidc.Callback)
+		}
+
 		users := v1.Group("/users")
 		{
-			users.GET("/me", middlewares.RoleMiddleware("staff", "admin"), r.user.GetMe)
-			users.GET("/", middlewares.RoleMiddleware("admin"), r.user.ListUsers)
+			users.GET("/me", middlewares.AuthMiddleware(), r.user.GetMe)
+			users.PUT("/me/password", middlewares.AuthMiddleware(), middlewares.UserOnlyMiddleware(), r.user.ChangePassword)
+			users.POST("/me/mfa", middlewares.AuthMiddleware(), middlewares.UserOnlyMiddleware(), r.user.StartMFAEnrollment)
+			users.POST("/me/mfa/confirm", middlewares.AuthMiddleware(), middlewares.UserOnlyMiddleware(), r.user.ConfirmMFAEnrollment)
+			users.POST("/me/mfa/disable", middlewares.AuthMiddleware(), middlewares.UserOnlyMiddleware(), r.user.DisableMFA)
+			users.GET("/", r.authorize(dto.PermissionUserRead), r.user.ListUsers)
+			users.PUT("/:user_id", r.authorize(dto.PermissionUserWrite), r.user.UpdateUser)
+			users.POST("/:user_id/deactivate", r.authorize(dto.PermissionUserWrite), r.user.DeactivateUser)
+			users.POST("/:user_id/activate", r.authorize(dto.PermissionUserWrite), r.user.ActivateUser)
+			users.POST("/:user_id/password-reset", r.authorize(dto.PermissionUserWrite), r.user.CreatePasswordReset)
+			users.POST("/:user_id/mfa/reset", r.authorize(dto.PermissionUserWrite), r.user.ResetMFA)
+			users.PUT("/:user_id/locations", r.authorize(dto.PermissionUserWrite), r.user.SetLocationScope)
+		}
+
+		v1.POST("/invites", r.authorize(dto.PermissionUserWrite), r.user.CreateInvite)
+		v1.GET("/auth-events", r.authorize(dto.PermissionAuthEventRead), r.user.ListAuthEvents)
+		v1.GET("/audit-logs", r.authorize(dto.PermissionAuditRead), r.audit.ListAuditLogs)
+
+		serviceAccounts := v1.Group("/service-accounts")
+		{
+			serviceAccounts.POST("/", r.authorize(dto.PermissionServiceAccount), r.serviceAccount.CreateServiceAccount)
+			serviceAccounts.GET("/:service_account_id/keys", r.authorize(dto.PermissionServiceAccount), r.serviceAccount.ListAPIKeys)
+			serviceAccounts.POST("/:service_account_id/keys", r.authorize(dto.PermissionServiceAccount), r.serviceAccount.CreateAPIKey)
+			serviceAccounts.DELETE("/:service_account_id/keys/:key_id", r.authorize(dto.PermissionServiceAccount), r.serviceAccount.RevokeAPIKey)
 		}
 
+		roles := v1.Group("/roles")
+		{
+			roles.GET("/", r.authorize(dto.PermissionRoleManage), r.role.ListRoles)
+			roles.POST("/", r.authorize(dto.PermissionRoleManage), r.role.CreateRole)
+			roles.PUT("/:role", r.authorize(dto.PermissionRoleManage), r.role.UpdateRole)
+			roles.DELETE("/:role", r.authorize(dto.PermissionRoleManage), r.role.DeleteRole)
+		}
+
+		v1.GET("/permissions", r.authorize(dto.PermissionRoleManage), r.role.ListPermissions)
+
 		products := v1.Group("/products")
 		{
-			products.POST("/", middlewares.RoleMiddleware("admin"), r.product.AddProduct)
-			products.GET("/", middlewares.RoleMiddleware("staff", "admin"), r.product.GetAllProducts)
-			products.GET("/:product_id", middlewares.RoleMiddleware("staff", "admin"), r.product.GetProductByID)
-			products.PUT("/:product_id", middlewares.RoleMiddleware("admin"), r.product.UpdateProduct)
-			products.DELETE("/:product_id", middlewares.RoleMiddleware("admin"), r.product.DeleteProduct)
+			products.POST("/", r.authorize(dto.PermissionProductWrite), r.product.AddProduct)
+			products.POST("/import", r.authorize(dto.PermissionProductWrite), r.product.ImportProducts)
+			products.GET("/", r.authorize(dto.PermissionProductRead), r.product.GetAllProducts)
+			products.GET("/export", r.authorize(dto.PermissionProductExport), r.product.ExportProducts)
+			products.GET("/:product_id", r.authorize(dto.PermissionProductRead), r.product.GetProductByID)
+			products.PUT("/:product_id", r.authorize(dto.PermissionProductWrite), r.product.UpdateProduct)
+			products.PATCH("/:product_id", r.authorize(dto.PermissionProductWrite), r.product.PatchProduct)
+			products.DELETE("/:product_id", r.authorize(dto.PermissionProductDelete), r.product.DeleteProduct)
+			products.POST("/:product_id/restore", r.authorize(dto.PermissionProductDelete), r.product.RestoreProduct)
 		}
 
 		location := v1.Group("/locations")
 		{
-			location.POST("/", middlewares.RoleMiddleware("admin"), r.location.AddLocation)
-			location.GET("/", middlewares.RoleMiddleware("staff", "admin"), r.location.GetAllLocations)
+			location.POST("/", r.authorize(dto.PermissionLocationWrite), r.location.AddLocation)
+			location.GET("/", r.authorize(dto.PermissionLocationRead), r.location.GetAllLocations)
+			location.GET("/export", r.authorize(dto.PermissionLocationExport), r.location.ExportLocations)
+			location.GET("/:location_id", r.authorize(dto.PermissionLocationRead), r.location.GetLocationByID)
+			location.DELETE("/:location_id", r.authorize(dto.PermissionLocationDelete), r.location.DeleteLocation)
+			location.POST("/:location_id/restore", r.authorize(dto.PermissionLocationDelete), r.location.RestoreLocation)
 		}
 
 		orders := v1.Group("/orders")
 		{
-			orders.POST("/receive", middlewares.RoleMiddleware("staff"), r.order.ReceiveOrder)
-			orders.POST("/ship", middlewares.RoleMiddleware("staff"), r.order.ShipOrder)
-			orders.GET("/", middlewares.RoleMiddleware("admin", "staff"), r.order.GetAllOrders)
-			orders.GET("/:order_id", middlewares.RoleMiddleware("admin", "staff"), r.order.GetOrderByID)
+			orders.POST("/receive", r.authorize(dto.PermissionOrderReceive), r.idempotent, r.order.ReceiveOrder)
+			orders.POST("/ship", r.authorize(dto.PermissionOrderShip), r.idempotent, r.order.ShipOrder)
+			orders.POST("/batch", r.authorize(dto.PermissionOrderReceive), r.authorize(dto.PermissionOrderShip), r.idempotent, r.order.BatchOrders)
+			orders.GET("/", r.authorize(dto.PermissionOrderRead), r.order.GetAllOrders)
+			orders.GET("/export", r.authorize(dto.PermissionOrderExport), r.order.ExportOrders)
+			orders.GET("/:order_id", r.authorize(dto.PermissionOrderRead), r.order.GetOrderByID)
 		}
 	}
+}
 
-	r.router.Run(fmt.Sprintf(":%s", port))
+// StartMetrics serves the Prometheus metrics on a listener of their own, so
+// they stay off the public API port and only reach who can reach addr.
+func StartMetrics(addr string) error {
+	return http.ListenAndServe(addr, MetricsHandler())
+}
+
+// MetricsHandler serves /metrics and nothing else.
+func MetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	return mux
 }
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+)
+
+type AuditService interface {
+	GetAll(ctx context.Context, filter *dto.AuditLogFilter, pagination *web.PaginationRequest) ([]*dto.AuditLog, *web.PageInfo, error)
+}
+
+type auditServiceImpl struct {
+	audit repositories.AuditRepository
+}
+
+func NewAuditService(audit repositories.AuditRepository) AuditService {
+	return &auditServiceImpl{
+		audit: audit,
+	}
+}
+
+func (s *auditServiceImpl) GetAll(ctx context.Context, filter *dto.AuditLogFilter, pagination *web.PaginationRequest) ([]*dto.AuditLog, *web.PageInfo, error) {
+	return s.audit.GetAll(ctx, filter, pagination)
+}
+
+// actingAs names userID as the actor when nobody is signed in, such as a user
+// registering, resetting their password or finishing a login. The audit log
+// then shows who made the change instead of no one.
+func actingAs(actor *dto.AuditActor, userID uuid.UUID) *dto.AuditActor {
+	if actor == nil {
+		return &dto.AuditActor{UserID: userID}
+	}
+
+	if actor.UserID != uuid.Nil {
+		return actor
+	}
+
+	self := *actor
+	self.UserID = userID
+	return &self
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+)
+
+type IdempotencyService interface {
+	Begin(ctx context.Context, userID uuid.UUID, key string, requestHash string) (*dto.IdempotencyKey, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, headers map[string]string, body []byte) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+	PurgeExpired(ctx context.Context) (int64, error)
+}
+
+var (
+	errIdempotencyKeyReused  = domain.Unprocessable("idempotency_key_reused", "idempotency key was already used for a different request")
+	errIdempotencyInProgress = domain.Conflict("idempotency_request_in_progress", "a request with this idempotency key is still being processed")
+)
+
+type idempotencyServiceImpl struct {
+	idempotency repositories.IdempotencyRepository
+	ttl         time.Duration
+}
+
+func NewIdempotencyService(idempotency repositories.IdempotencyRepository, ttl time.Duration) IdempotencyService {
+	return &idempotencyServiceImpl{
+		idempotency: idempotency,
+		ttl:         ttl,
+	}
+}
+
+// Begin claims key for a request. It returns nil when the request should run,
+// or the stored key when it already ran and its response must be replayed.
+func (s *idempotencyServiceImpl) Begin(ctx context.Context, userID uuid.UUID, key string, requestHash string) (*dto.IdempotencyKey, error) {
+	reserved, err := s.idempotency.ReserveIdempotencyKey(ctx, &dto.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   time.Now().Add(s.ttl),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reserved {
+		return nil, nil
+	}
+
+	existing, err := s.idempotency.FindIdempotencyKey(ctx, userID, key)
+	if errors.Is(err, domain.ErrNotFound) {
+		// Released or expired between the two queries.
+		return nil, errIdempotencyInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if existing.RequestHash != requestHash {
+		return nil, errIdempotencyKeyReused
+	}
+
+	if existing.StatusCode == 0 {
+		return nil, errIdempotencyInProgress
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response so retries get it back.
+func (s *idempotencyServiceImpl) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, headers map[string]string, body []byte) error {
+	return s.idempotency.SaveIdempotencyResponse(ctx, &dto.IdempotencyKey{
+		UserID:       userID,
+		Key:          key,
+		StatusCode:   statusCode,
+		ContentType:  contentType,
+		Headers:      headers,
+		ResponseBody: body,
+	})
+}
+
+// Release forgets the key so the request can be tried again, for when it
+// failed without a result worth replaying.
+func (s *idempotencyServiceImpl) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	return s.idempotency.DeleteIdempotencyKey(ctx, userID, key)
+}
+
+// PurgeExpired deletes the keys whose replay window has passed.
+func (s *idempotencyServiceImpl) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.idempotency.DeleteExpiredIdempotencyKeys(ctx)
+}
@@ -1,53 +1,125 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"errors"
 
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/repositories"
 )
 
+var (
+	errLocationForbidden = domain.Forbidden("location_forbidden", "location is not assigned to you")
+	errLocationNameTaken = domain.Conflict("location_name_taken", "location name is exists")
+)
+
 type LocationService interface {
-	Save(location *dto.Location) (int, error)
-	GetAll(pagination *web.PaginationRequest) ([]*dto.Location, int, error)
+	Save(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, location *dto.Location) (*dto.Location, error)
+	GetByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Location, error)
+	Delete(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) error
+	Restore(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) (*dto.Location, error)
+	GetAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Location, *web.PageInfo, error)
+	Export(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Location) error) error
 }
 
 type locationServiceImpl struct {
 	location repositories.LocationRepository
+	audit    repositories.AuditRepository
 }
 
-func NewLocationService(location repositories.LocationRepository) LocationService {
+func NewLocationService(location repositories.LocationRepository, audit repositories.AuditRepository) LocationService {
 	return &locationServiceImpl{
 		location: location,
+		audit:    audit,
 	}
 }
 
-func (s *locationServiceImpl) Save(location *dto.Location) (int, error) {
-	locationData, code, err := s.location.FindByName(location.Name)
-	if err != nil {
-		if err != sql.ErrNoRows {
-			return code, err
-		}
+func (s *locationServiceImpl) Save(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, location *dto.Location) (*dto.Location, error) {
+	// A user tied to specific sites can't open new ones.
+	if scope == nil || !scope.All {
+		return nil, errLocationForbidden
+	}
+
+	locationData, err := s.location.FindByName(ctx, location.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
 	}
 
 	if locationData != nil {
-		return 401, errors.New("location name is exists")
+		return nil, errLocationNameTaken
+	}
+
+	var created *dto.Location
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		created, err = s.location.SaveWithTransaction(ctx, tx, location)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.location.Save(location); err != nil {
-		return 500, err
+	return created, nil
+}
+
+func (s *locationServiceImpl) GetByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Location, error) {
+	if !scope.Allows(id) {
+		return nil, errLocationForbidden
+	}
+
+	return s.location.FindByID(ctx, id)
+}
+
+// Delete soft-deletes the location; it is refused while products are still
+// stored there.
+func (s *locationServiceImpl) Delete(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) error {
+	if scope == nil || !scope.All {
+		return errLocationForbidden
 	}
 
-	return 201, nil
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.location.DeleteWithTransaction(ctx, tx, id, actor.UserID)
+	})
 }
 
-func (s *locationServiceImpl) GetAll(pagination *web.PaginationRequest) ([]*dto.Location, int, error) {
-	locations, err := s.location.GetAllLocation(pagination)
+func (s *locationServiceImpl) Restore(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) (*dto.Location, error) {
+	if scope == nil || !scope.All {
+		return nil, errLocationForbidden
+	}
+
+	locationData, err := s.location.FindDeletedByID(ctx, id)
 	if err != nil {
-		return nil, 500, err
+		return nil, err
+	}
+
+	live, err := s.location.FindByName(ctx, locationData.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
 	}
 
-	return locations, 200, nil
+	if live != nil {
+		return nil, errLocationNameTaken
+	}
+
+	var restored *dto.Location
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		restored, err = s.location.RestoreWithTransaction(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+func (s *locationServiceImpl) GetAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Location, *web.PageInfo, error) {
+	return s.location.GetAllLocation(ctx, scope, pagination)
+}
+
+func (s *locationServiceImpl) Export(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Location) error) error {
+	return s.location.StreamAll(ctx, scope, pagination, fn)
 }
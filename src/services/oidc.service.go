@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+type OIDCService interface {
+	StartLogin(ctx context.Context) (string, error)
+	CompleteLogin(ctx context.Context, actor *dto.AuditActor, code string, state string) (*dto.TokenResponse, *dto.MFAChallengeResponse, error)
+}
+
+var (
+	errSSONotConfigured = domain.NotFound("sso_not_configured", "single sign-on is not configured")
+	errInvalidSSOState  = domain.Validation("invalid_sso_state", "invalid or expired login state")
+	errSSOFailed        = domain.Unauthorized("sso_failed", "single sign-on failed")
+	errNoSSORole        = domain.Forbidden("sso_no_role", "none of your groups may use this service")
+	errUnverifiedEmail  = domain.Forbidden("sso_unverified_email", "identity provider did not return a verified email")
+)
+
+type oidcServiceImpl struct {
+	provider   utils.OIDCProvider
+	oidc       repositories.OIDCRepository
+	user       repositories.UserRepository
+	token      repositories.TokenRepository
+	authEvent  repositories.AuthEventRepository
+	mfa        repositories.MFARepository
+	audit      repositories.AuditRepository
+	mfaConfig  config.MFAConfig
+	groupRoles []config.GroupRole
+}
+
+// NewOIDCService takes a nil provider when single sign-on is off.
+func NewOIDCService(provider utils.OIDCProvider, oidc repositories.OIDCRepository, user repositories.UserRepository, token repositories.TokenRepository, authEvent repositories.AuthEventRepository, mfa repositories.MFARepository, audit repositories.AuditRepository, mfaConfig config.MFAConfig, groupRoles []config.GroupRole) OIDCService {
+	return &oidcServiceImpl{
+		provider:   provider,
+		oidc:       oidc,
+		user:       user,
+		token:      token,
+		authEvent:  authEvent,
+		mfa:        mfa,
+		audit:      audit,
+		mfaConfig:  mfaConfig,
+		groupRoles: groupRoles,
+	}
+}
+
+// StartLogin returns the identity provider URL to send the browser to. The
+// state, nonce and PKCE verifier stay on our side until the callback.
+func (s *oidcServiceImpl) StartLogin(ctx context.Context) (string, error) {
+	if s.provider == nil {
+		return "", errSSONotConfigured
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	nonce, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	codeVerifier, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.oidc.SaveLoginState(ctx, &dto.OIDCLoginState{
+		ID:           uuid.New(),
+		StateHash:    utils.HashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		ExpiresAt:    time.Now().Add(config.GetOIDCExpTime()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return s.provider.AuthCodeURL(state, nonce, codeVerifier), nil
+}
+
+// CompleteLogin finishes the flow and issues our own tokens. The identity
+// provider decides the role on every login. Local MFA is still enforced: a
+// user with MFA enabled, or whose role requires it, gets a challenge to
+// finish through VerifyMFA instead of tokens.
+func (s *oidcServiceImpl) CompleteLogin(ctx context.Context, actor *dto.AuditActor, code string, state string) (*dto.TokenResponse, *dto.MFAChallengeResponse, error) {
+	if s.provider == nil {
+		return nil, nil, errSSONotConfigured
+	}
+
+	loginState, err := s.oidc.UseLoginState(ctx, utils.HashToken(state))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, nil, errInvalidSSOState
+		}
+
+		return nil, nil, err
+	}
+
+	identity, err := s.provider.Exchange(ctx, code, loginState.CodeVerifier, loginState.Nonce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", errSSOFailed, err)
+	}
+
+	role := s.roleFor(ctx, identity.Groups)
+	if role == "" {
+		if err := recordAuthEvent(ctx, s.authEvent, dto.AuthEventSSOLogin, normalizeEmail(identity.Email), nil, actor.IP, dto.AuthReasonNoRole); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errNoSSORole
+	}
+
+	user, err := s.findOrProvision(ctx, actor, identity, role)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !user.Active {
+		if err := recordAuthEvent(ctx, s.authEvent, dto.AuthEventSSOLogin, normalizeEmail(user.Email), &user.ID, actor.IP, dto.AuthReasonDeactivated); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errUserDeactivated
+	}
+
+	if user.Role != role {
+		user.Role = role
+		user.Scope.All = role == dto.UserRoleAdmin
+		err := s.audit.Transaction(ctx, actingAs(actor, user.ID), func(tx *sqlx.Tx) error {
+			return s.user.UpdateWithTransaction(ctx, tx, user)
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if user.MFAEnabled || mfaRequired(s.mfaConfig, user.Role) {
+		challenge, err := createMFAChallenge(ctx, s.mfa, user)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, challenge, nil
+	}
+
+	tokens, err := issueTokens(ctx, s.token, user, uuid.New(), uuid.New())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordAuthEvent(ctx, s.authEvent, dto.AuthEventSSOLogin, normalizeEmail(user.Email), &user.ID, actor.IP, ""); err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, nil, nil
+}
+
+func (s *oidcServiceImpl) roleFor(ctx context.Context, groups []string) dto.UserRole {
+	for _, mapping := range s.groupRoles {
+		if slices.Contains(groups, mapping.Group) {
+			return dto.UserRole(mapping.Role)
+		}
+	}
+
+	return ""
+}
+
+// findOrProvision returns the user linked to the identity. The first login
+// links an existing account with the same verified email, or creates one
+// without a password. Only admins start with every location.
+func (s *oidcServiceImpl) findOrProvision(ctx context.Context, actor *dto.AuditActor, identity *dto.OIDCIdentity, role dto.UserRole) (*dto.User, error) {
+	userID, err := s.oidc.FindUserIDBySubject(ctx, identity.Issuer, identity.Subject)
+	if err == nil {
+		return s.user.FindByID(ctx, userID)
+	}
+
+	if !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	if identity.Email == "" || !identity.EmailVerified {
+		return nil, errUnverifiedEmail
+	}
+
+	user, err := s.user.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, err
+		}
+
+		name := identity.Name
+		if name == "" {
+			name = identity.Email
+		}
+
+		register := &dto.RegisterRequest{
+			ID:           uuid.New(),
+			Email:        identity.Email,
+			Name:         name,
+			Role:         role,
+			AllLocations: role == dto.UserRoleAdmin,
+		}
+
+		err := s.audit.Transaction(ctx, actingAs(actor, register.ID), func(tx *sqlx.Tx) error {
+			return s.user.SaveWithTransaction(ctx, tx, register)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		user = &dto.User{
+			ID:     register.ID,
+			Email:  register.Email,
+			Name:   register.Name,
+			Role:   register.Role,
+			Active: true,
+			Scope:  dto.LocationScope{All: register.AllLocations, LocationIDs: []uuid.UUID{}},
+		}
+	} else if user.ServiceAccount {
+		return nil, errServiceAccountLogin
+	}
+
+	if err := s.oidc.LinkIdentity(ctx, user.ID, identity.Issuer, identity.Subject); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -1,42 +1,49 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"sync"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 )
 
 type OrderService interface {
-	ReceiveOrder(order *dto.OrderCreateRequest) (int, error)
-	ShipOrder(order *dto.OrderCreateRequest) (int, error)
-	GetAllOrders(pagination *web.PaginationRequest) ([]*dto.Order, int, error)
-	GetOrderByID(id uuid.UUID) (*dto.Order, int, error)
+	ReceiveOrder(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, order *dto.OrderCreateRequest) (*dto.Order, error)
+	ShipOrder(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, order *dto.OrderCreateRequest) (*dto.Order, error)
+	BatchOrders(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, batch *dto.OrderBatchRequest) (*dto.OrderBatchResult, error)
+	GetAllOrders(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Order, *web.PageInfo, error)
+	ExportOrders(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Order) error) error
+	GetOrderByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Order, error)
 }
 
 type orderServiceImpl struct {
 	order       repositories.OrderRepository
 	product     repositories.ProductRepository
 	transaction repositories.TransactionRepository
+	audit       repositories.AuditRepository
 	mu          sync.Mutex
 }
 
-func NewOrderService(order repositories.OrderRepository, product repositories.ProductRepository, transaction repositories.TransactionRepository) OrderService {
+func NewOrderService(order repositories.OrderRepository, product repositories.ProductRepository, transaction repositories.TransactionRepository, audit repositories.AuditRepository) OrderService {
 	return &orderServiceImpl{
 		order:       order,
 		product:     product,
 		transaction: transaction,
+		audit:       audit,
 	}
 }
 
-func (s *orderServiceImpl) ReceiveOrder(order *dto.OrderCreateRequest) (int, error) {
-	err := s.transaction.Begin()
+func (s *orderServiceImpl) ReceiveOrder(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, order *dto.OrderCreateRequest) (*dto.Order, error) {
+	product, err := s.checkProductScope(ctx, scope, order.ProductID)
 	if err != nil {
-		return 500, errors.New("error when create tx")
+		return nil, err
 	}
 
 	orderData := &dto.Order{
@@ -45,19 +52,24 @@ func (s *orderServiceImpl) ReceiveOrder(order *dto.OrderCreateRequest) (int, err
 		Quantity:  order.Quantity,
 	}
 
+	var created *dto.Order
 	var wg sync.WaitGroup
 	errCh := make(chan error, 2)
 	var mu sync.Mutex
-	err = s.transaction.Transaction(func() error {
-		tx, _ := s.transaction.GetTx()
+	err = s.transaction.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.audit.TagWithTransaction(ctx, tx, actor); err != nil {
+			return err
+		}
 
 		wg.Add(1)
 		go func(tx *sqlx.Tx, order *dto.Order) {
 			mu.Lock()
 			defer wg.Done()
-			if err := s.order.SaveWithTransaction(tx, order); err != nil {
+			saved, err := s.order.SaveWithTransaction(ctx, tx, order)
+			if err != nil {
 				errCh <- err
 			}
+			created = saved
 			defer mu.Unlock()
 		}(tx, orderData)
 
@@ -65,7 +77,7 @@ func (s *orderServiceImpl) ReceiveOrder(order *dto.OrderCreateRequest) (int, err
 		go func(tx *sqlx.Tx, productID uuid.UUID, quantity int64) {
 			mu.Lock()
 			defer wg.Done()
-			if _, err := s.product.IncreaseStockWithTransaction(tx, productID, quantity); err != nil {
+			if err := s.product.IncreaseStockWithTransaction(ctx, tx, productID, quantity); err != nil {
 				errCh <- err
 			}
 			defer mu.Unlock()
@@ -82,18 +94,19 @@ func (s *orderServiceImpl) ReceiveOrder(order *dto.OrderCreateRequest) (int, err
 
 		return nil
 	})
-
 	if err != nil {
-		return 500, err
+		return nil, err
 	}
 
-	return 200, nil
+	recordUnits(orderData.Type, product.LocationID, orderData.Quantity)
+
+	return created, nil
 }
 
-func (s *orderServiceImpl) ShipOrder(order *dto.OrderCreateRequest) (int, error) {
-	err := s.transaction.Begin()
+func (s *orderServiceImpl) ShipOrder(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, order *dto.OrderCreateRequest) (*dto.Order, error) {
+	product, err := s.checkProductScope(ctx, scope, order.ProductID)
 	if err != nil {
-		return 500, errors.New("error when create tx")
+		return nil, err
 	}
 
 	orderData := &dto.Order{
@@ -102,19 +115,24 @@ func (s *orderServiceImpl) ShipOrder(order *dto.OrderCreateRequest) (int, error)
 		Quantity:  order.Quantity,
 	}
 
+	var created *dto.Order
 	var wg sync.WaitGroup
 	errCh := make(chan error, 2)
 	var mu sync.Mutex
-	err = s.transaction.Transaction(func() error {
-		tx, _ := s.transaction.GetTx()
+	err = s.transaction.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.audit.TagWithTransaction(ctx, tx, actor); err != nil {
+			return err
+		}
 
 		wg.Add(1)
 		go func(tx *sqlx.Tx, order *dto.Order) {
 			mu.Lock()
 			defer wg.Done()
-			if err := s.order.SaveWithTransaction(tx, order); err != nil {
+			saved, err := s.order.SaveWithTransaction(ctx, tx, order)
+			if err != nil {
 				errCh <- err
 			}
+			created = saved
 			defer mu.Unlock()
 		}(tx, orderData)
 
@@ -122,7 +140,7 @@ func (s *orderServiceImpl) ShipOrder(order *dto.OrderCreateRequest) (int, error)
 		go func(tx *sqlx.Tx, productID uuid.UUID, quantity int64) {
 			mu.Lock()
 			defer wg.Done()
-			if _, err := s.product.DecreaseStockWithTransaction(tx, productID, quantity); err != nil {
+			if err := s.product.DecreaseStockWithTransaction(ctx, tx, productID, quantity); err != nil {
 				errCh <- err
 			}
 			defer mu.Unlock()
@@ -139,28 +157,190 @@ func (s *orderServiceImpl) ShipOrder(order *dto.OrderCreateRequest) (int, error)
 
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordUnits(orderData.Type, product.LocationID, orderData.Quantity)
+
+	return created, nil
+}
+
+var errBatchRejected = domain.Unprocessable("batch_rejected", "batch has failed lines")
+
+// BatchOrders applies many receive/ship lines in one transaction. The affected
+// products are locked up front so every line can be checked against the
+// running stock before the net changes and the orders are written in bulk.
+// A rejected atomic batch returns the per-line results with errBatchRejected.
+func (s *orderServiceImpl) BatchOrders(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, batch *dto.OrderBatchRequest) (*dto.OrderBatchResult, error) {
+	mode := batch.Mode
+	if mode == "" {
+		mode = dto.OrderBatchModeAtomic
+	}
+
+	result := &dto.OrderBatchResult{
+		Mode:  mode,
+		Lines: make([]dto.OrderBatchLineResult, len(batch.Lines)),
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(batch.Lines))
+	for _, line := range batch.Lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+
+	var products map[uuid.UUID]*dto.Product
+	var applied []*dto.Order
+
+	err := s.transaction.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.audit.TagWithTransaction(ctx, tx, actor); err != nil {
+			return err
+		}
+
+		var err error
+		products, err = s.product.LockStockWithTransaction(ctx, tx, productIDs)
+		if err != nil {
+			return err
+		}
+
+		stock := make(map[uuid.UUID]int64, len(products))
+		for id, product := range products {
+			if !scope.Allows(product.LocationID) {
+				return errLocationForbidden
+			}
+			stock[id] = product.Quantity
+		}
+
+		deltas := make(map[uuid.UUID]int64)
+		var orders []*dto.Order
+
+		for i, line := range batch.Lines {
+			lineResult := dto.OrderBatchLineResult{
+				Line:      i + 1,
+				Type:      line.Type,
+				ProductID: line.ProductID,
+				Quantity:  line.Quantity,
+				Status:    dto.OrderBatchLineApplied,
+			}
+
+			delta := line.Quantity
+			if line.Type == dto.OrderTypeShipping {
+				delta = -line.Quantity
+			}
+
+			current, ok := stock[line.ProductID]
+			switch {
+			case !ok:
+				lineResult.Status = dto.OrderBatchLineFailed
+				lineResult.Error = "product not found"
+			case current+delta < 0:
+				lineResult.Status = dto.OrderBatchLineFailed
+				lineResult.Error = "insufficient stock"
+			default:
+				stock[line.ProductID] = current + delta
+				deltas[line.ProductID] += delta
+				orders = append(orders, &dto.Order{
+					Type:      line.Type,
+					ProductID: line.ProductID,
+					Quantity:  line.Quantity,
+				})
+			}
+
+			if lineResult.Status == dto.OrderBatchLineFailed {
+				result.Failed++
+			}
+			result.Lines[i] = lineResult
+		}
+
+		if mode == dto.OrderBatchModeAtomic && result.Failed > 0 {
+			return errBatchRejected
+		}
+
+		if len(orders) == 0 {
+			return nil
+		}
+
+		if err := s.product.AdjustStockBatchWithTransaction(ctx, tx, deltas); err != nil {
+			return err
+		}
+
+		applied = orders
+
+		return s.order.SaveBatchWithTransaction(ctx, tx, orders)
+	})
+
+	if errors.Is(err, errBatchRejected) {
+		for i := range result.Lines {
+			if result.Lines[i].Status == dto.OrderBatchLineApplied {
+				result.Lines[i].Status = dto.OrderBatchLineRolledBack
+			}
+		}
+
+		return result, err
+	}
 
 	if err != nil {
-		return 500, err
+		return nil, err
 	}
 
-	return 200, nil
+	result.Applied = len(batch.Lines) - result.Failed
+
+	for _, order := range applied {
+		recordUnits(order.Type, products[order.ProductID].LocationID, order.Quantity)
+	}
+
+	return result, nil
 }
 
-func (s *orderServiceImpl) GetAllOrders(pagination *web.PaginationRequest) ([]*dto.Order, int, error) {
-	users, err := s.order.FindAll(pagination)
+func (s *orderServiceImpl) GetAllOrders(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Order, *web.PageInfo, error) {
+	return s.order.FindAll(ctx, scope, pagination)
+}
+
+func (s *orderServiceImpl) ExportOrders(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Order) error) error {
+	return s.order.StreamAll(ctx, scope, pagination, fn)
+}
+
+func (s *orderServiceImpl) GetOrderByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Order, error) {
+	order, err := s.order.FindByID(ctx, id)
 	if err != nil {
-		return nil, 500, err
+		return nil, err
 	}
 
-	return users, 200, nil
+	if scope == nil || !scope.All {
+		product, err := s.product.FindByID(ctx, order.ProductID)
+		if errors.Is(err, domain.ErrNotFound) {
+			// Orders outlive their product and stay visible at its location.
+			product, err = s.product.FindDeletedByID(ctx, order.ProductID)
+		}
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errLocationForbidden
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if !scope.Allows(product.LocationID) {
+			return nil, errLocationForbidden
+		}
+	}
+
+	return order, nil
 }
 
-func (s *orderServiceImpl) GetOrderByID(id uuid.UUID) (*dto.Order, int, error) {
-	user, code, err := s.order.FindByID(id)
+// checkProductScope returns the product when it lies in the caller's scope.
+func (s *orderServiceImpl) checkProductScope(ctx context.Context, scope *dto.LocationScope, productID uuid.UUID) (*dto.Product, error) {
+	product, err := s.product.FindByID(ctx, productID)
 	if err != nil {
-		return nil, code, err
+		return nil, err
 	}
 
-	return user, 200, nil
+	if !scope.Allows(product.LocationID) {
+		return nil, errLocationForbidden
+	}
+
+	return product, nil
+}
+
+// recordUnits counts the units moved by a committed order.
+func recordUnits(orderType dto.OrderType, locationID uuid.UUID, quantity int64) {
+	utils.OrderUnits.WithLabelValues(string(orderType), locationID.String()).Add(float64(quantity))
 }
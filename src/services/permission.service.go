@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+)
+
+const permissionCacheTTL = time.Minute
+
+type PermissionService interface {
+	HasPermission(ctx context.Context, role dto.UserRole, permission string) (bool, error)
+	ListPermissions(ctx context.Context) ([]*dto.Permission, error)
+	ListRoles(ctx context.Context) ([]*dto.Role, error)
+	CreateRole(ctx context.Context, role *dto.Role) error
+	UpdateRole(ctx context.Context, name dto.UserRole, role *dto.RoleUpdateRequest) (*dto.Role, error)
+	DeleteRole(ctx context.Context, name dto.UserRole) error
+}
+
+var (
+	errRoleTaken       = domain.Conflict("role_taken", "role is exists")
+	errAdminRoleLocked = domain.Validation("admin_role_locked", "admin role can't be deleted")
+)
+
+type permissionServiceImpl struct {
+	permission  repositories.PermissionRepository
+	transaction repositories.TransactionRepository
+
+	mu       sync.RWMutex
+	grants   map[dto.UserRole]map[string]bool
+	loadedAt time.Time
+}
+
+func NewPermissionService(permission repositories.PermissionRepository, transaction repositories.TransactionRepository) PermissionService {
+	return &permissionServiceImpl{
+		permission:  permission,
+		transaction: transaction,
+	}
+}
+
+// HasPermission answers from an in-memory copy of the role mappings, which is
+// reloaded once it is older than permissionCacheTTL or after a role changes.
+func (s *permissionServiceImpl) HasPermission(ctx context.Context, role dto.UserRole, permission string) (bool, error) {
+	s.mu.RLock()
+	if s.grants != nil && time.Since(s.loadedAt) < permissionCacheTTL {
+		granted := s.grants[role][permission]
+		s.mu.RUnlock()
+		return granted, nil
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.grants == nil || time.Since(s.loadedAt) >= permissionCacheTTL {
+		roles, err := s.permission.ListRoles(ctx)
+		if err != nil {
+			return false, err
+		}
+
+		grants := make(map[dto.UserRole]map[string]bool, len(roles))
+		for _, r := range roles {
+			grants[r.Name] = make(map[string]bool, len(r.Permissions))
+			for _, p := range r.Permissions {
+				grants[r.Name][p] = true
+			}
+		}
+
+		s.grants = grants
+		s.loadedAt = time.Now()
+	}
+
+	return s.grants[role][permission], nil
+}
+
+func (s *permissionServiceImpl) ListPermissions(ctx context.Context) ([]*dto.Permission, error) {
+	return s.permission.ListPermissions(ctx)
+}
+
+func (s *permissionServiceImpl) ListRoles(ctx context.Context) ([]*dto.Role, error) {
+	return s.permission.ListRoles(ctx)
+}
+
+func (s *permissionServiceImpl) CreateRole(ctx context.Context, role *dto.Role) error {
+	roleData, err := s.permission.FindRole(ctx, role.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+
+	if roleData != nil {
+		return errRoleTaken
+	}
+
+	if err := s.checkPermissions(ctx, role.Permissions); err != nil {
+		return err
+	}
+
+	err = s.transaction.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.permission.SaveRoleWithTransaction(ctx, tx, role); err != nil {
+			return err
+		}
+
+		return s.permission.ReplaceRolePermissionsWithTransaction(ctx, tx, role.Name, role.Permissions)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.invalidate(ctx)
+
+	return nil
+}
+
+func (s *permissionServiceImpl) UpdateRole(ctx context.Context, name dto.UserRole, role *dto.RoleUpdateRequest) (*dto.Role, error) {
+	roleData, err := s.permission.FindRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if name == dto.UserRoleAdmin && !slices.Contains(role.Permissions, dto.PermissionRoleManage) {
+		return nil, domain.Validation("admin_role_locked", fmt.Sprintf("admin role must keep %s", dto.PermissionRoleManage))
+	}
+
+	if err := s.checkPermissions(ctx, role.Permissions); err != nil {
+		return nil, err
+	}
+
+	roleData.Description = role.Description
+	roleData.Permissions = role.Permissions
+
+	err = s.transaction.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.permission.UpdateRoleWithTransaction(ctx, tx, roleData); err != nil {
+			return err
+		}
+
+		return s.permission.ReplaceRolePermissionsWithTransaction(ctx, tx, roleData.Name, roleData.Permissions)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidate(ctx)
+
+	return roleData, nil
+}
+
+func (s *permissionServiceImpl) DeleteRole(ctx context.Context, name dto.UserRole) error {
+	if name == dto.UserRoleAdmin {
+		return errAdminRoleLocked
+	}
+
+	users, err := s.permission.CountUsersWithRole(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if users > 0 {
+		return domain.Conflict("role_in_use", fmt.Sprintf("role is assigned to %d users", users))
+	}
+
+	if err := s.permission.DeleteRole(ctx, name); err != nil {
+		return err
+	}
+
+	s.invalidate(ctx)
+
+	return nil
+}
+
+func (s *permissionServiceImpl) checkPermissions(ctx context.Context, names []string) error {
+	permissions, err := s.permission.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		known[permission.Name] = true
+	}
+
+	for _, name := range names {
+		if !known[name] {
+			return domain.Validation("unknown_permission", fmt.Sprintf("unknown permission %s", name))
+		}
+	}
+
+	return nil
+}
+
+func (s *permissionServiceImpl) invalidate(ctx context.Context) {
+	s.mu.Lock()
+	s.grants = nil
+	s.mu.Unlock()
+}
@@ -1,106 +1,305 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/repositories"
 )
 
 type ProductService interface {
-	Create(product *dto.Product) (int, error)
-	GetByID(id uuid.UUID) (*dto.Product, int, error)
-	GetAll(pagination *web.PaginationRequest) ([]*dto.Product, int, error)
-	Update(product *dto.Product) (int, error)
-	Delete(id uuid.UUID) (int, error)
+	Create(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, product *dto.Product) (*dto.Product, error)
+	GetByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Product, error)
+	GetAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Product, *web.PageInfo, error)
+	Export(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Product) error) error
+	Update(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, product *dto.Product) error
+	Patch(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID, patch *dto.ProductPatch, version int64) (*dto.Product, error)
+	Delete(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) error
+	Restore(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) (*dto.Product, error)
+	Import(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, rows []*dto.ProductImportRow, dryRun bool) (*dto.ProductImportReport, error)
 }
 
+var (
+	errProductNameTaken = domain.Conflict("product_name_taken", "product name is exists")
+	errProductSKUTaken  = domain.Conflict("product_sku_taken", "product sku is exists")
+	errImportRejected   = domain.Unprocessable("import_rejected", "import has invalid rows")
+	errLocationNotFound = domain.NotFound("location_not_found", "location not found")
+)
+
 type productServiceImpl struct {
-	product repositories.ProductRepository
+	product     repositories.ProductRepository
+	location    repositories.LocationRepository
+	transaction repositories.TransactionRepository
+	audit       repositories.AuditRepository
 }
 
-func NewProductService(product repositories.ProductRepository) ProductService {
+func NewProductService(product repositories.ProductRepository, location repositories.LocationRepository, transaction repositories.TransactionRepository, audit repositories.AuditRepository) ProductService {
 	return &productServiceImpl{
-		product: product,
+		product:     product,
+		location:    location,
+		transaction: transaction,
+		audit:       audit,
 	}
 }
 
-func (s *productServiceImpl) Create(product *dto.Product) (int, error) {
-	productData, code, err := s.product.FindByName(product.Name)
-	if err != nil {
-		if err != sql.ErrNoRows || code == 500 {
-			return code, err
-		}
+func (s *productServiceImpl) Create(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, product *dto.Product) (*dto.Product, error) {
+	if !scope.Allows(product.LocationID) {
+		return nil, errLocationForbidden
+	}
+
+	productData, err := s.product.FindByName(ctx, product.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
 	}
 
 	if productData != nil {
-		return 401, errors.New("product name is exists")
+		return nil, errProductNameTaken
 	}
 
-	productData, code, err = s.product.FindBySKU(product.SKU)
-	if err != nil {
-		if err != sql.ErrNoRows || code == 500 {
-			return code, err
-		}
+	productData, err = s.product.FindBySKU(ctx, product.SKU)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
 	}
 
 	if productData != nil {
-		return 401, errors.New("product sku is exists")
+		return nil, errProductSKUTaken
 	}
 
-	err = s.product.Save(product)
+	var created *dto.Product
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		created, err = s.product.SaveWithTransaction(ctx, tx, product)
+		return err
+	})
 	if err != nil {
-		return 500, err
+		return nil, err
 	}
 
-	return 201, nil
+	return created, nil
 }
 
-func (s *productServiceImpl) GetByID(id uuid.UUID) (*dto.Product, int, error) {
-	product, code, err := s.product.FindByID(id)
+func (s *productServiceImpl) GetByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Product, error) {
+	product, err := s.product.FindByID(ctx, id)
 	if err != nil {
-		return nil, code, err
+		return nil, err
+	}
+
+	if !scope.Allows(product.LocationID) {
+		return nil, errLocationForbidden
 	}
 
-	return product, 200, nil
+	return product, nil
 }
 
-func (s *productServiceImpl) GetAll(pagination *web.PaginationRequest) ([]*dto.Product, int, error) {
-	products, err := s.product.GetAllProduct(pagination)
+func (s *productServiceImpl) GetAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Product, *web.PageInfo, error) {
+	return s.product.GetAllProduct(ctx, scope, pagination)
+}
+
+func (s *productServiceImpl) Export(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Product) error) error {
+	return s.product.StreamAll(ctx, scope, pagination, fn)
+}
+
+func (s *productServiceImpl) Update(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, product *dto.Product) error {
+	productData, err := s.product.FindByID(ctx, product.ID)
 	if err != nil {
-		return nil, 500, err
+		return err
 	}
 
-	return products, 200, nil
+	if !scope.Allows(productData.LocationID) || !scope.Allows(product.LocationID) {
+		return errLocationForbidden
+	}
+
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.product.UpdateWithTransaction(ctx, tx, product)
+	})
 }
 
-func (s *productServiceImpl) Update(product *dto.Product) (int, error) {
-	_, code, err := s.product.FindByID(product.ID)
+// Patch applies a partial update. version is the one the client last read, or
+// 0 when it did not send one.
+func (s *productServiceImpl) Patch(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID, patch *dto.ProductPatch, version int64) (*dto.Product, error) {
+	productData, err := s.product.FindByID(ctx, id)
 	if err != nil {
-		return code, err
+		return nil, err
+	}
+
+	if !scope.Allows(productData.LocationID) || (patch.LocationID != nil && !scope.Allows(*patch.LocationID)) {
+		return nil, errLocationForbidden
 	}
 
-	err = s.product.Update(product)
+	var patched *dto.Product
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		patched, err = s.product.PatchWithTransaction(ctx, tx, id, patch, version)
+		return err
+	})
 	if err != nil {
-		return 500, err
+		return nil, err
 	}
 
-	return 200, nil
+	return patched, nil
 }
 
-func (s *productServiceImpl) Delete(id uuid.UUID) (int, error) {
-	_, code, err := s.product.FindByID(id)
+// Delete soft-deletes the product; it is refused while the product still
+// holds stock.
+func (s *productServiceImpl) Delete(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) error {
+	productData, err := s.product.FindByID(ctx, id)
 	if err != nil {
-		return code, err
+		return err
 	}
 
-	code, err = s.product.Delete(id)
+	if !scope.Allows(productData.LocationID) {
+		return errLocationForbidden
+	}
+
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.product.DeleteWithTransaction(ctx, tx, id, actor.UserID)
+	})
+}
+
+func (s *productServiceImpl) Restore(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) (*dto.Product, error) {
+	productData, err := s.product.FindDeletedByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !scope.Allows(productData.LocationID) {
+		return nil, errLocationForbidden
+	}
+
+	live, err := s.product.FindByName(ctx, productData.Name)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return nil, err
+	}
+
+	if live != nil {
+		return nil, errProductNameTaken
+	}
+
+	var restored *dto.Product
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		restored, err = s.product.RestoreWithTransaction(ctx, tx, id)
+		return err
+	})
 	if err != nil {
-		return code, err
+		return nil, err
+	}
+
+	return restored, nil
+}
+
+// Import returns the report together with errImportRejected when any row is
+// invalid, so the caller can show what to fix.
+func (s *productServiceImpl) Import(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, rows []*dto.ProductImportRow, dryRun bool) (*dto.ProductImportReport, error) {
+	var names, skus []string
+	var locationIDs []uuid.UUID
+	for _, row := range rows {
+		if row.Product != nil {
+			names = append(names, row.Product.Name)
+			skus = append(skus, row.Product.SKU)
+			locationIDs = append(locationIDs, row.Product.LocationID)
+		}
+	}
+
+	existing, err := s.product.FindByNamesOrSKUs(ctx, names, skus)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows pointing at a missing or deleted location are reported here
+	// instead of failing the whole insert.
+	locations, err := s.location.FindByIDs(ctx, locationIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	liveLocations := make(map[uuid.UUID]bool, len(locations))
+	for _, location := range locations {
+		liveLocations[location.ID] = true
+	}
+
+	// A value seen at row 0 already exists in the database.
+	seenNames := make(map[string]int)
+	seenSKUs := make(map[string]int)
+	for _, product := range existing {
+		seenNames[product.Name] = 0
+		seenSKUs[product.SKU] = 0
+	}
+
+	report := &dto.ProductImportReport{
+		DryRun:    dryRun,
+		TotalRows: len(rows),
+		Errors:    []dto.ProductImportRowError{},
+	}
+
+	var products []*dto.Product
+	for _, row := range rows {
+		errs := row.Errors
+
+		if row.Product != nil {
+			if !scope.Allows(row.Product.LocationID) {
+				errs = append(errs, errLocationForbidden.Error())
+			} else if !liveLocations[row.Product.LocationID] {
+				errs = append(errs, errLocationNotFound.Error())
+			}
+
+			if seen, ok := seenNames[row.Product.Name]; ok {
+				errs = append(errs, duplicateMessage("name", seen))
+			} else {
+				seenNames[row.Product.Name] = row.Row
+			}
+
+			if seen, ok := seenSKUs[row.Product.SKU]; ok {
+				errs = append(errs, duplicateMessage("sku", seen))
+			} else {
+				seenSKUs[row.Product.SKU] = row.Row
+			}
+		}
+
+		if len(errs) > 0 {
+			report.Errors = append(report.Errors, dto.ProductImportRowError{Row: row.Row, Errors: errs})
+			continue
+		}
+
+		products = append(products, row.Product)
+	}
+
+	if dryRun {
+		report.Imported = len(products)
+		return report, nil
+	}
+
+	if len(report.Errors) > 0 {
+		return report, errImportRejected
+	}
+
+	if len(products) == 0 {
+		return report, nil
+	}
+
+	err = s.transaction.Transaction(ctx, func(tx *sqlx.Tx) error {
+		if err := s.audit.TagWithTransaction(ctx, tx, actor); err != nil {
+			return err
+		}
+
+		return s.product.SaveBatchWithTransaction(ctx, tx, products)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	report.Imported = len(products)
+
+	return report, nil
+}
+
+func duplicateMessage(field string, row int) string {
+	if row == 0 {
+		return fmt.Sprintf("product %s is exists", field)
 	}
 
-	return 200, nil
+	return fmt.Sprintf("product %s is duplicated in row %d", field, row)
 }
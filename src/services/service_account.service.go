@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+type ServiceAccountService interface {
+	CreateServiceAccount(ctx context.Context, actor *dto.AuditActor, request *dto.ServiceAccountRequest) (*dto.User, error)
+	CreateAPIKey(ctx context.Context, createdBy uuid.UUID, serviceAccountID uuid.UUID, request *dto.APIKeyRequest) (*dto.APIKeyResponse, error)
+	GetAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*dto.APIKey, error)
+	RevokeAPIKey(ctx context.Context, serviceAccountID uuid.UUID, keyID uuid.UUID) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*dto.APIKeyIdentity, error)
+}
+
+var (
+	errServiceAccountNotFound = domain.NotFound("service_account_not_found", "service account not found")
+	errInvalidAPIKey          = domain.Unauthorized("invalid_api_key", "invalid or expired api key")
+	errAPIKeyExpiry           = domain.Validation("invalid_expiry", "expires_at must be in the future")
+)
+
+type serviceAccountServiceImpl struct {
+	serviceAccount repositories.ServiceAccountRepository
+	user           repositories.UserRepository
+	permission     repositories.PermissionRepository
+	audit          repositories.AuditRepository
+}
+
+func NewServiceAccountService(serviceAccount repositories.ServiceAccountRepository, user repositories.UserRepository, permission repositories.PermissionRepository, audit repositories.AuditRepository) ServiceAccountService {
+	return &serviceAccountServiceImpl{
+		serviceAccount: serviceAccount,
+		user:           user,
+		permission:     permission,
+		audit:          audit,
+	}
+}
+
+// CreateServiceAccount adds an account for a machine integration. It gets an
+// address under the reserved .invalid domain since it never receives mail,
+// and no locations until an admin grants them.
+func (s *serviceAccountServiceImpl) CreateServiceAccount(ctx context.Context, actor *dto.AuditActor, request *dto.ServiceAccountRequest) (*dto.User, error) {
+	id := uuid.New()
+
+	account := &dto.User{
+		ID:             id,
+		Email:          fmt.Sprintf("%s@service-accounts.invalid", id),
+		Name:           request.Name,
+		Role:           request.Role,
+		Active:         true,
+		ServiceAccount: true,
+		Scope:          dto.LocationScope{LocationIDs: []uuid.UUID{}},
+	}
+
+	err := s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.serviceAccount.SaveServiceAccountWithTransaction(ctx, tx, account)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// CreateAPIKey only allows scopes the account's role grants, so a key never
+// promises more than it can do. Only the key's hash is stored.
+func (s *serviceAccountServiceImpl) CreateAPIKey(ctx context.Context, createdBy uuid.UUID, serviceAccountID uuid.UUID, request *dto.APIKeyRequest) (*dto.APIKeyResponse, error) {
+	account, err := s.findServiceAccount(ctx, serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, errAPIKeyExpiry
+	}
+
+	role, err := s.permission.FindRole(ctx, account.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := slices.Clone(request.Scopes)
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+
+	for _, scope := range scopes {
+		if !slices.Contains(role.Permissions, scope) {
+			return nil, domain.Validation("scope_not_granted", fmt.Sprintf("role %s does not grant %s", account.Role, scope))
+		}
+	}
+
+	key, prefix, err := utils.GenerateAPIKey()
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey := dto.APIKey{
+		ID:               uuid.New(),
+		ServiceAccountID: account.ID,
+		Name:             request.Name,
+		Prefix:           prefix,
+		KeyHash:          utils.HashToken(key),
+		Scopes:           scopes,
+		ExpiresAt:        request.ExpiresAt,
+		CreatedBy:        &createdBy,
+		CreatedAt:        time.Now(),
+	}
+
+	if err := s.serviceAccount.SaveAPIKey(ctx, &apiKey); err != nil {
+		return nil, err
+	}
+
+	return &dto.APIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *serviceAccountServiceImpl) GetAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*dto.APIKey, error) {
+	if _, err := s.findServiceAccount(ctx, serviceAccountID); err != nil {
+		return nil, err
+	}
+
+	return s.serviceAccount.GetAPIKeys(ctx, serviceAccountID)
+}
+
+func (s *serviceAccountServiceImpl) RevokeAPIKey(ctx context.Context, serviceAccountID uuid.UUID, keyID uuid.UUID) error {
+	return s.serviceAccount.RevokeAPIKey(ctx, serviceAccountID, keyID)
+}
+
+func (s *serviceAccountServiceImpl) AuthenticateAPIKey(ctx context.Context, key string) (*dto.APIKeyIdentity, error) {
+	identity, err := s.serviceAccount.UseAPIKey(ctx, utils.HashToken(key))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errInvalidAPIKey
+		}
+
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+func (s *serviceAccountServiceImpl) findServiceAccount(ctx context.Context, id uuid.UUID) (*dto.User, error) {
+	account, err := s.user.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errServiceAccountNotFound
+		}
+
+		return nil, err
+	}
+
+	if !account.ServiceAccount {
+		return nil, errServiceAccountNotFound
+	}
+
+	return account, nil
+}
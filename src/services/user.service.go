@@ -1,10 +1,17 @@
 package services
 
 import (
-	"database/sql"
+	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/repositories"
@@ -12,89 +19,992 @@ import (
 )
 
 type UserService interface {
-	Login(login *dto.LoginRequest) (string, int, error)
-	Register(register *dto.RegisterRequest) (int, error)
-	GetUserByID(id uuid.UUID) (*dto.User, int, error)
-	GetAllUser(pagination *web.PaginationRequest) ([]*dto.User, int, error)
+	Login(ctx context.Context, login *dto.LoginRequest, ip string) (*dto.TokenResponse, *dto.MFAChallengeResponse, error)
+	VerifyMFA(ctx context.Context, actor *dto.AuditActor, request *dto.MFAVerifyRequest) (*dto.TokenResponse, error)
+	Refresh(ctx context.Context, refreshToken string) (*dto.TokenResponse, error)
+	Logout(ctx context.Context, claims *utils.CustomClaims, refreshToken string) error
+	IsTokenRevoked(ctx context.Context, jti string) (bool, error)
+	Register(ctx context.Context, actor *dto.AuditActor, register *dto.RegisterRequest) error
+	GetUserByID(ctx context.Context, id uuid.UUID) (*dto.User, error)
+	GetAllUser(ctx context.Context, pagination *web.PaginationRequest) ([]*dto.User, *web.PageInfo, error)
+	SetLocationScope(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, scope *dto.LocationScope) (*dto.User, error)
+	UpdateUser(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, user *dto.UserUpdateRequest) (*dto.User, error)
+	SetActive(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, active bool) error
+	IsUserActive(ctx context.Context, id uuid.UUID, role dto.UserRole) (bool, error)
+	ChangePassword(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, request *dto.PasswordChangeRequest) error
+	CreatePasswordReset(ctx context.Context, id uuid.UUID) (*dto.PasswordResetResponse, error)
+	ResetPassword(ctx context.Context, actor *dto.AuditActor, request *dto.PasswordResetRequest) error
+	CreateInvite(ctx context.Context, createdBy uuid.UUID, request *dto.InviteRequest) (*dto.InviteResponse, error)
+	BootstrapAdmin(ctx context.Context, register *dto.RegisterRequest) error
+	GetAuthEvents(ctx context.Context, filter *dto.AuthEventFilter, pagination *web.PaginationRequest) ([]*dto.AuthEvent, *web.PageInfo, error)
+	StartMFAEnrollment(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) (*dto.MFAEnrollmentResponse, error)
+	StartMFAEnrollmentWithChallenge(ctx context.Context, actor *dto.AuditActor, mfaToken string) (*dto.MFAEnrollmentResponse, error)
+	ConfirmMFAEnrollment(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, code string) (*dto.MFAEnrollmentResult, error)
+	DisableMFA(ctx context.Context, actor *dto.AuditActor, claims *utils.CustomClaims, code string) error
+	ResetMFA(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) error
 }
 
+var (
+	errUserDeactivated      = domain.Forbidden("user_deactivated", "user is deactivated")
+	errRegistrationDisabled = domain.Forbidden("registration_disabled", "registration is disabled")
+	errInviteRequired       = domain.Forbidden("invite_required", "invite code is required")
+	errInvalidInvite        = domain.Validation("invalid_invite", "invalid or expired invite code")
+	errNotInviteOnly        = domain.Validation("registration_not_invite_only", "registration is not invite-only")
+	errEmailTaken           = domain.Conflict("email_taken", "email is exists")
+	errInvalidCredentials   = domain.Unauthorized("invalid_credentials", "invalid email or password")
+	errTooManyAttempts      = domain.TooManyRequests("too_many_attempts", "too many failed login attempts, try again later")
+	errInvalidRefreshToken  = domain.Unauthorized("invalid_refresh_token", "invalid refresh token")
+	errRefreshTokenRevoked  = domain.Unauthorized("refresh_token_revoked", "refresh token is revoked")
+	errRefreshTokenExpired  = domain.Unauthorized("refresh_token_expired", "refresh token is expired")
+	errInvalidTokenID       = domain.Validation("invalid_token_id", "invalid token id")
+	errForeignRefreshToken  = domain.Forbidden("refresh_token_forbidden", "refresh token belongs to another user")
+	errSelfDeactivation     = domain.Validation("self_deactivation", "you can't deactivate yourself")
+	errWrongPassword        = domain.Validation("wrong_password", "wrong password")
+	errInvalidResetToken    = domain.Validation("invalid_reset_token", "invalid or expired reset token")
+	errInvalidMFAToken      = domain.Unauthorized("invalid_mfa_token", "invalid or expired mfa token")
+	errInvalidMFACode       = domain.Unauthorized("invalid_mfa_code", "invalid two-factor code")
+	errRejectedMFACode      = domain.Validation("invalid_mfa_code", "invalid two-factor code")
+	errMFANotEnrolled       = domain.Validation("mfa_not_enrolled", "enrol in two-factor authentication first")
+	errMFANotStarted        = domain.Validation("mfa_not_started", "start two-factor enrolment first")
+	errMFAAlreadyEnabled    = domain.Conflict("mfa_already_enabled", "two-factor authentication is already enabled")
+	errMFANotEnabled        = domain.Validation("mfa_not_enabled", "two-factor authentication is not enabled")
+	errMFARequired          = domain.Validation("mfa_required", "two-factor authentication is required for your role")
+	errServiceAccountLogin  = domain.Validation("service_account_login", "service accounts authenticate with api keys")
+)
+
+// Failed logins are counted per account and per client address. Past the
+// limit each further failure doubles the lockout, up to loginLockoutMax.
+const (
+	loginFailureWindow  = 24 * time.Hour
+	accountFailureLimit = 5
+	addressFailureLimit = 20
+	loginLockoutBase    = time.Minute
+	loginLockoutMax     = time.Hour
+	maxLockoutDoublings = 6
+)
+
+const (
+	mfaMaxAttempts    = 5
+	recoveryCodeCount = 10
+)
+
+// dummyPasswordHash is compared against when the email is unknown.
+const dummyPasswordHash = "$argon2id$v=19$m=65536,t=3,p=2$ayR9JbQ0n6VFUE9exhaZYQ$9ietvU9ydws+vpF5UM4M5mkvU2uopXvgwG1zHu7LFWo"
+
 type UserServiceImpl struct {
-	user repositories.UserRepository
+	user         repositories.UserRepository
+	token        repositories.TokenRepository
+	invite       repositories.InviteRepository
+	authEvent    repositories.AuthEventRepository
+	mfa          repositories.MFARepository
+	audit        repositories.AuditRepository
+	registration config.RegistrationMode
+	mfaConfig    config.MFAConfig
 }
 
-func NewUserService(user repositories.UserRepository) UserService {
+func NewUserService(user repositories.UserRepository, token repositories.TokenRepository, invite repositories.InviteRepository, authEvent repositories.AuthEventRepository, mfa repositories.MFARepository, audit repositories.AuditRepository, registration config.RegistrationMode, mfaConfig config.MFAConfig) UserService {
 	return &UserServiceImpl{
-		user: user,
+		user:         user,
+		token:        token,
+		invite:       invite,
+		authEvent:    authEvent,
+		mfa:          mfa,
+		audit:        audit,
+		registration: registration,
+		mfaConfig:    mfaConfig,
 	}
 }
 
-func (s *UserServiceImpl) Login(login *dto.LoginRequest) (string, int, error) {
-	user, code, err := s.user.FindByEmail(login.Email)
+// Login answers every bad email/password combination with the same error,
+// and still runs a password comparison for unknown emails so response time
+// doesn't reveal which accounts exist. Users with MFA get a challenge
+// instead of tokens.
+func (s *UserServiceImpl) Login(ctx context.Context, login *dto.LoginRequest, ip string) (*dto.TokenResponse, *dto.MFAChallengeResponse, error) {
+	// Attempts are counted per normalized address, so changing the case or
+	// padding of the email doesn't buy a fresh set of guesses.
+	email := normalizeEmail(login.Email)
+
+	failures, err := s.authEvent.CountLoginFailures(ctx, email, ip, time.Now().Add(-loginFailureWindow))
 	if err != nil {
-		return "", code, err
+		return nil, nil, err
+	}
+
+	// Rejected attempts are only logged, not stored, so hammering a locked
+	// account can't grow the table or extend the lock.
+	if loginLocked(failures) {
+		utils.Logger(ctx).Warn("login rejected while locked", "email", email, "ip", ip)
+
+		return nil, nil, errTooManyAttempts
 	}
 
+	user, err := s.user.FindByEmail(ctx, login.Email)
+	if err != nil {
+		if !errors.Is(err, domain.ErrNotFound) {
+			return nil, nil, err
+		}
+
+		utils.VerifyPassword(dummyPasswordHash, login.Password)
+
+		if err := recordLogin(ctx, s.authEvent, email, nil, ip, dto.AuthReasonUnknownEmail); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errInvalidCredentials
+	}
+
+	// Service accounts only authenticate with API keys.
 	err = utils.VerifyPassword(user.Password, login.Password)
+	if err != nil || user.ServiceAccount {
+		if err := recordLogin(ctx, s.authEvent, email, &user.ID, ip, dto.AuthReasonWrongPassword); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errInvalidCredentials
+	}
+
+	if !user.Active {
+		if err := recordLogin(ctx, s.authEvent, email, &user.ID, ip, dto.AuthReasonDeactivated); err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, errUserDeactivated
+	}
+
+	// The plaintext is only available here, so this is where old hashes get
+	// upgraded. A failed upgrade is retried on the next login.
+	if utils.NeedsRehash(user.Password) {
+		if hashedPassword, err := utils.HashPassword(login.Password); err == nil {
+			if err := s.user.RehashPassword(ctx, user.ID, user.Password, hashedPassword); err != nil {
+				utils.Logger(ctx).Warn("password rehash failed", "user_id", user.ID, "error", err)
+			}
+		}
+	}
+
+	// The login is only recorded as successful once the second factor is in.
+	if user.MFAEnabled || mfaRequired(s.mfaConfig, user.Role) {
+		challenge, err := createMFAChallenge(ctx, s.mfa, user)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, challenge, nil
+	}
+
+	tokens, err := issueTokens(ctx, s.token, user, uuid.New(), uuid.New())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := recordLogin(ctx, s.authEvent, email, &user.ID, ip, ""); err != nil {
+		return nil, nil, err
+	}
+
+	return tokens, nil, nil
+}
+
+// recordLogin stores a login attempt; an empty reason means it succeeded.
+func recordLogin(ctx context.Context, authEvents repositories.AuthEventRepository, email string, userID *uuid.UUID, ip string, reason string) error {
+	return recordAuthEvent(ctx, authEvents, dto.AuthEventLogin, email, userID, ip, reason)
+}
+
+func recordAuthEvent(ctx context.Context, authEvents repositories.AuthEventRepository, event dto.AuthEventType, email string, userID *uuid.UUID, ip string, reason string) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	return authEvents.SaveAuthEvent(ctx, &dto.AuthEvent{
+		ID:      id,
+		Event:   event,
+		Email:   email,
+		UserID:  userID,
+		IP:      ip,
+		Success: reason == "",
+		Reason:  reason,
+	})
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func loginLocked(failures *dto.LoginFailures) bool {
+	now := time.Now()
+
+	return now.Before(lockedUntil(failures.Account, failures.AccountLast, accountFailureLimit)) ||
+		now.Before(lockedUntil(failures.IP, failures.IPLast, addressFailureLimit))
+}
+
+func lockedUntil(failures int, last time.Time, limit int) time.Time {
+	if failures < limit {
+		return time.Time{}
+	}
+
+	lockout := loginLockoutBase << min(failures-limit, maxLockoutDoublings)
+	return last.Add(min(lockout, loginLockoutMax))
+}
+
+func (s *UserServiceImpl) GetAuthEvents(ctx context.Context, filter *dto.AuthEventFilter, pagination *web.PaginationRequest) ([]*dto.AuthEvent, *web.PageInfo, error) {
+	return s.authEvent.GetAll(ctx, filter, pagination)
+}
+
+func (s *UserServiceImpl) Refresh(ctx context.Context, refreshToken string) (*dto.TokenResponse, error) {
+	token, err := s.token.FindRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errInvalidRefreshToken
+		}
+
+		return nil, err
+	}
+
+	// A revoked token being presented again means it was copied; kill every
+	// token issued from the same login.
+	if token.RevokedAt != nil {
+		if err := s.token.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+
+		return nil, errRefreshTokenRevoked
+	}
+
+	if token.ExpiresAt.Before(time.Now()) {
+		return nil, errRefreshTokenExpired
+	}
+
+	user, err := s.user.FindByID(ctx, token.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active {
+		return nil, errUserDeactivated
+	}
+
+	nextID := uuid.New()
+	rotated, err := s.token.RotateRefreshToken(ctx, token.ID, nextID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rotated {
+		if err := s.token.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+			return nil, err
+		}
+
+		return nil, errRefreshTokenRevoked
+	}
+
+	tokens, err := issueTokens(ctx, s.token, user, nextID, token.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *UserServiceImpl) Logout(ctx context.Context, claims *utils.CustomClaims, refreshToken string) error {
+	if claims.RegisteredClaims.ID != "" {
+		jti, err := uuid.Parse(claims.RegisteredClaims.ID)
+		if err != nil {
+			return errInvalidTokenID
+		}
+
+		expiresAt := time.Now().Add(config.GetExpTime())
+		if claims.ExpiresAt != nil {
+			expiresAt = claims.ExpiresAt.Time
+		}
+
+		if err := s.token.RevokeAccessToken(ctx, jti, expiresAt); err != nil {
+			return err
+		}
+	}
+
+	if refreshToken == "" {
+		return nil
+	}
+
+	token, err := s.token.FindRefreshTokenByHash(ctx, utils.HashToken(refreshToken))
 	if err != nil {
-		return "", 400, errors.New("wrong password")
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil
+		}
+
+		return err
+	}
+
+	if token.UserID != claims.ID {
+		return errForeignRefreshToken
 	}
 
+	if err := s.token.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserServiceImpl) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	// Tokens issued before jti was added cannot be revoked individually.
+	if jti == "" {
+		return false, nil
+	}
+
+	id, err := uuid.Parse(jti)
+	if err != nil {
+		return true, nil
+	}
+
+	return s.token.IsAccessTokenRevoked(ctx, id)
+}
+
+func issueTokens(ctx context.Context, tokens repositories.TokenRepository, user *dto.User, refreshID uuid.UUID, familyID uuid.UUID) (*dto.TokenResponse, error) {
 	claims := &utils.CustomClaims{
 		ID:    user.ID,
 		Name:  user.Name,
 		Email: user.Email,
 		Role:  user.Role,
+		Scope: user.Scope,
 	}
 
-	tokenString, err := utils.GenerateToken(claims)
+	accessToken, err := utils.GenerateToken(claims)
 	if err != nil {
-		return "", 500, err
+		return nil, err
 	}
 
-	return tokenString, 200, nil
-}
+	refreshToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
 
-func (s *UserServiceImpl) Register(register *dto.RegisterRequest) (int, error) {
-	user, code, err := s.user.FindByEmail(register.Email)
+	err = tokens.SaveRefreshToken(ctx, &dto.RefreshToken{
+		ID:        refreshID,
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(config.GetRefreshExpTime()),
+	})
 	if err != nil {
-		if err != sql.ErrNoRows || code == 500 {
-			return code, err
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(config.GetExpTime().Seconds()),
+	}, nil
+}
+
+// Register creates an account according to the configured registration mode.
+// Open registration always yields staff; invites carry the role an admin
+// picked when issuing them.
+func (s *UserServiceImpl) Register(ctx context.Context, actor *dto.AuditActor, register *dto.RegisterRequest) error {
+	switch s.registration {
+	case config.RegistrationStaff:
+		register.Role = dto.UserRoleStaff
+	case config.RegistrationInvite:
+		if register.InviteCode == "" {
+			return errInviteRequired
 		}
+	default:
+		return errRegistrationDisabled
+	}
+
+	if err := checkPasswordPolicy(register.Password, register.Email, register.Name); err != nil {
+		return err
+	}
+
+	user, err := s.user.FindByEmail(ctx, register.Email)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return err
 	}
 
 	if user != nil {
-		return 401, errors.New("email is exists")
+		return errEmailTaken
 	}
 
 	hashedPassword, err := utils.HashPassword(register.Password)
 	if err != nil {
-		return 500, err
+		return err
 	}
 
+	register.ID = uuid.New()
 	register.Password = hashedPassword
 
-	err = s.user.Save(register)
+	actor = actingAs(actor, register.ID)
+
+	if s.registration == config.RegistrationInvite {
+		return s.registerWithInvite(ctx, actor, register)
+	}
+
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.user.SaveWithTransaction(ctx, tx, register)
+	})
+}
+
+// registerWithInvite redeems the invite and creates the user together, so a
+// failed insert doesn't burn the code.
+func (s *UserServiceImpl) registerWithInvite(ctx context.Context, actor *dto.AuditActor, register *dto.RegisterRequest) error {
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		invite, err := s.invite.UseInviteWithTransaction(ctx, tx, utils.HashToken(register.InviteCode), register.ID)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				return errInvalidInvite
+			}
+
+			return err
+		}
+
+		// Admins manage every location, like the bootstrapped admin; other
+		// roles start without any and are assigned locations afterwards.
+		register.Role = invite.Role
+		register.AllLocations = invite.Role == dto.UserRoleAdmin
+
+		return s.user.SaveWithTransaction(ctx, tx, register)
+	})
+}
+
+// CreateInvite issues a one-time invite code for the given role. Only its
+// hash is stored.
+func (s *UserServiceImpl) CreateInvite(ctx context.Context, createdBy uuid.UUID, request *dto.InviteRequest) (*dto.InviteResponse, error) {
+	if s.registration != config.RegistrationInvite {
+		return nil, errNotInviteOnly
+	}
+
+	inviteCode, err := utils.GenerateOpaqueToken()
 	if err != nil {
-		return 500, err
+		return nil, err
 	}
 
-	return 201, nil
+	expiresAt := time.Now().Add(config.GetInviteExpTime())
+
+	err = s.invite.SaveInvite(ctx, &dto.Invite{
+		ID:        uuid.New(),
+		CodeHash:  utils.HashToken(inviteCode),
+		Role:      request.Role,
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.InviteResponse{
+		InviteCode: inviteCode,
+		Role:       request.Role,
+		ExpiresAt:  expiresAt,
+	}, nil
 }
 
-func (s *UserServiceImpl) GetUserByID(id uuid.UUID) (*dto.User, int, error) {
-	user, code, err := s.user.FindByID(id)
+// BootstrapAdmin creates the first admin regardless of the registration
+// mode. It refuses once any admin exists.
+func (s *UserServiceImpl) BootstrapAdmin(ctx context.Context, register *dto.RegisterRequest) error {
+	if err := checkPasswordPolicy(register.Password, register.Email, register.Name); err != nil {
+		return err
+	}
+
+	hashedPassword, err := utils.HashPassword(register.Password)
 	if err != nil {
-		return nil, code, err
+		return err
 	}
 
-	return user, 200, nil
+	register.ID = uuid.New()
+	register.Password = hashedPassword
+	register.Role = dto.UserRoleAdmin
+
+	return s.user.SaveFirstAdmin(ctx, register)
+}
+
+func (s *UserServiceImpl) GetUserByID(ctx context.Context, id uuid.UUID) (*dto.User, error) {
+	return s.user.FindByID(ctx, id)
 }
 
-func (s *UserServiceImpl) GetAllUser(pagination *web.PaginationRequest) ([]*dto.User, int, error) {
-	users, err := s.user.GetAll(pagination)
+func (s *UserServiceImpl) GetAllUser(ctx context.Context, pagination *web.PaginationRequest) ([]*dto.User, *web.PageInfo, error) {
+	return s.user.GetAll(ctx, pagination)
+}
+
+// SetLocationScope changes where a user may work. Tokens already issued keep
+// the old scope until they are refreshed.
+func (s *UserServiceImpl) SetLocationScope(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, scope *dto.LocationScope) (*dto.User, error) {
+	err := s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.user.SetLocationScopeWithTransaction(ctx, tx, id, scope)
+	})
 	if err != nil {
-		return nil, 500, err
+		return nil, err
+	}
+
+	return s.GetUserByID(ctx, id)
+}
+
+// UpdateUser changes a user's profile and role. A role change signs the
+// user out: refresh tokens are revoked and the JWT middleware rejects access
+// tokens carrying the old role. Promotion to admin grants every location.
+func (s *UserServiceImpl) UpdateUser(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, request *dto.UserUpdateRequest) (*dto.User, error) {
+	user, err := s.user.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	roleChanged := user.Role != request.Role
+	user.Email = request.Email
+	user.Name = request.Name
+	user.Role = request.Role
+	if roleChanged && user.Role == dto.UserRoleAdmin {
+		user.Scope.All = true
+	}
+
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.user.UpdateWithTransaction(ctx, tx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if roleChanged {
+		if err := s.token.RevokeUserRefreshTokens(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+// SetActive enables or disables an account. Disabling it also revokes every
+// refresh token; access tokens are rejected by the JWT middleware.
+func (s *UserServiceImpl) SetActive(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, active bool) error {
+	if !active && actor != nil && actor.UserID == id {
+		return errSelfDeactivation
+	}
+
+	err := s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.user.SetActiveWithTransaction(ctx, tx, id, active)
+	})
+	if err != nil {
+		return err
+	}
+
+	if !active {
+		if err := s.token.RevokeUserRefreshTokens(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsUserActive also checks the role an access token was issued with, so a
+// role change retires the user's outstanding access tokens.
+func (s *UserServiceImpl) IsUserActive(ctx context.Context, id uuid.UUID, role dto.UserRole) (bool, error) {
+	return s.user.IsActive(ctx, id, role)
+}
+
+func (s *UserServiceImpl) ChangePassword(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, request *dto.PasswordChangeRequest) error {
+	user, err := s.user.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.VerifyPassword(user.Password, request.CurrentPassword); err != nil {
+		return errWrongPassword
+	}
+
+	if err := s.checkNewPassword(ctx, user, request.NewPassword); err != nil {
+		return err
 	}
 
-	return users, 200, nil
+	return s.setPassword(ctx, actor, user, request.NewPassword)
+}
+
+// CreatePasswordReset issues a one-time token an admin hands to the user.
+// Only its hash is stored.
+func (s *UserServiceImpl) CreatePasswordReset(ctx context.Context, id uuid.UUID) (*dto.PasswordResetResponse, error) {
+	user, err := s.user.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ServiceAccount {
+		return nil, errServiceAccountLogin
+	}
+
+	resetToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(config.GetResetExpTime())
+
+	err = s.token.SavePasswordResetToken(ctx, &dto.PasswordResetToken{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(resetToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.PasswordResetResponse{
+		ResetToken: resetToken,
+		ExpiresAt:  expiresAt,
+	}, nil
+}
+
+// ResetPassword checks the new password before redeeming the token, so a
+// password the policy rejects doesn't use it up.
+func (s *UserServiceImpl) ResetPassword(ctx context.Context, actor *dto.AuditActor, request *dto.PasswordResetRequest) error {
+	hash := utils.HashToken(request.ResetToken)
+
+	token, err := s.token.FindPasswordResetToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errInvalidResetToken
+		}
+
+		return err
+	}
+
+	user, err := s.user.FindByID(ctx, token.UserID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkNewPassword(ctx, user, request.NewPassword); err != nil {
+		return err
+	}
+
+	if _, err := s.token.UsePasswordResetToken(ctx, hash); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return errInvalidResetToken
+		}
+
+		return err
+	}
+
+	return s.setPassword(ctx, actingAs(actor, user.ID), user, request.NewPassword)
+}
+
+// checkNewPassword applies the password policy and refuses any of the
+// user's recent passwords.
+func (s *UserServiceImpl) checkNewPassword(ctx context.Context, user *dto.User, password string) error {
+	if err := checkPasswordPolicy(password, user.Email, user.Name); err != nil {
+		return err
+	}
+
+	size := utils.PasswordHistorySize()
+	if size == 0 {
+		return nil
+	}
+
+	hashes, err := s.user.GetPasswordHistory(ctx, user.ID, size)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if utils.VerifyPassword(hash, password) == nil {
+			return domain.Validation("password_reused", fmt.Sprintf("password must differ from your last %d passwords", size))
+		}
+	}
+
+	return nil
+}
+
+// checkPasswordPolicy reports a password the policy rejects as a
+// validation error.
+func checkPasswordPolicy(password string, identifiers ...string) error {
+	if err := utils.CheckPassword(password, identifiers...); err != nil {
+		return domain.Validation("weak_password", err.Error())
+	}
+
+	return nil
+}
+
+// setPassword stores a password that already passed checkNewPassword and
+// signs the user out everywhere.
+func (s *UserServiceImpl) setPassword(ctx context.Context, actor *dto.AuditActor, user *dto.User, password string) error {
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.user.UpdatePasswordWithTransaction(ctx, tx, user.ID, hashedPassword)
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.token.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func mfaRequired(mfaConfig config.MFAConfig, role dto.UserRole) bool {
+	return slices.Contains(mfaConfig.RequiredRoles, string(role))
+}
+
+// createMFAChallenge is shared by password and single sign-on logins, which
+// both finish through VerifyMFA.
+func createMFAChallenge(ctx context.Context, mfa repositories.MFARepository, user *dto.User) (*dto.MFAChallengeResponse, error) {
+	mfaToken, err := utils.GenerateOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = mfa.SaveChallenge(ctx, &dto.MFAChallenge{
+		ID:        uuid.New(),
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(mfaToken),
+		ExpiresAt: time.Now().Add(config.GetMFAExpTime()),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAChallengeResponse{
+		MFAToken:           mfaToken,
+		ExpiresIn:          int64(config.GetMFAExpTime().Seconds()),
+		EnrollmentRequired: !user.MFAEnabled,
+	}, nil
+}
+
+func (s *UserServiceImpl) findMFAChallenge(ctx context.Context, mfaToken string) (*dto.MFAChallenge, error) {
+	challenge, err := s.mfa.FindChallenge(ctx, utils.HashToken(mfaToken), mfaMaxAttempts)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return nil, errInvalidMFAToken
+		}
+
+		return nil, err
+	}
+
+	return challenge, nil
+}
+
+// VerifyMFA finishes a login with a TOTP or recovery code. A user whose role
+// requires MFA and who enrolled through the challenge is switched on here
+// and gets their recovery codes with the tokens.
+func (s *UserServiceImpl) VerifyMFA(ctx context.Context, actor *dto.AuditActor, request *dto.MFAVerifyRequest) (*dto.TokenResponse, error) {
+	challenge, err := s.findMFAChallenge(ctx, request.MFAToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.user.FindByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.Active {
+		return nil, errUserDeactivated
+	}
+
+	state, err := s.mfa.GetTOTP(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Secret == nil {
+		return nil, errMFANotEnrolled
+	}
+
+	ok, err := s.checkSecondFactor(ctx, user.ID, state, request.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		if err := s.mfa.FailChallenge(ctx, challenge.ID); err != nil {
+			return nil, err
+		}
+
+		if err := recordLogin(ctx, s.authEvent, normalizeEmail(user.Email), &user.ID, actor.IP, dto.AuthReasonWrongMFACode); err != nil {
+			return nil, err
+		}
+
+		return nil, errInvalidMFACode
+	}
+
+	used, err := s.mfa.UseChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !used {
+		return nil, errInvalidMFAToken
+	}
+
+	var recoveryCodes []string
+	if !state.Enabled {
+		if recoveryCodes, err = s.enableMFA(ctx, actingAs(actor, user.ID), user.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	tokens, err := issueTokens(ctx, s.token, user, uuid.New(), uuid.New())
+	if err != nil {
+		return nil, err
+	}
+
+	tokens.RecoveryCodes = recoveryCodes
+
+	if err := recordLogin(ctx, s.authEvent, normalizeEmail(user.Email), &user.ID, actor.IP, ""); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// checkSecondFactor accepts a TOTP code for an unused time step, or, once
+// MFA is on, an unused recovery code.
+func (s *UserServiceImpl) checkSecondFactor(ctx context.Context, userID uuid.UUID, state *dto.TOTPState, code string) (bool, error) {
+	if step, ok := utils.ValidateTOTP(*state.Secret, code, time.Now()); ok {
+		return s.mfa.UseTOTPStep(ctx, userID, step)
+	}
+
+	if !state.Enabled {
+		return false, nil
+	}
+
+	return s.mfa.UseRecoveryCode(ctx, userID, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+}
+
+func (s *UserServiceImpl) StartMFAEnrollment(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) (*dto.MFAEnrollmentResponse, error) {
+	user, err := s.user.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.ServiceAccount {
+		return nil, errServiceAccountLogin
+	}
+
+	if user.MFAEnabled {
+		return nil, errMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.mfa.SetPendingTOTPSecretWithTransaction(ctx, tx, user.ID, secret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAEnrollmentResponse{
+		Secret: secret,
+		URI:    utils.TOTPURI(secret, s.mfaConfig.Issuer, user.Email),
+	}, nil
+}
+
+// StartMFAEnrollmentWithChallenge lets a user who must use MFA but hasn't
+// set it up enrol before they can get a token.
+func (s *UserServiceImpl) StartMFAEnrollmentWithChallenge(ctx context.Context, actor *dto.AuditActor, mfaToken string) (*dto.MFAEnrollmentResponse, error) {
+	challenge, err := s.findMFAChallenge(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.StartMFAEnrollment(ctx, actingAs(actor, challenge.UserID), challenge.UserID)
+}
+
+func (s *UserServiceImpl) ConfirmMFAEnrollment(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, code string) (*dto.MFAEnrollmentResult, error) {
+	state, err := s.mfa.GetTOTP(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.Enabled {
+		return nil, errMFAAlreadyEnabled
+	}
+
+	if state.Secret == nil {
+		return nil, errMFANotStarted
+	}
+
+	ok, err := s.checkSecondFactor(ctx, id, state, code)
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, errRejectedMFACode
+	}
+
+	recoveryCodes, err := s.enableMFA(ctx, actor, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAEnrollmentResult{RecoveryCodes: recoveryCodes}, nil
+}
+
+// enableMFA switches MFA on and issues fresh recovery codes. Only their
+// hashes are stored.
+func (s *UserServiceImpl) enableMFA(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) ([]string, error) {
+	recoveryCodes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range recoveryCodes {
+		recoveryCode, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		recoveryCodes[i] = recoveryCode
+		hashes[i] = utils.HashToken(recoveryCode)
+	}
+
+	err := s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.mfa.EnableMFAWithTransaction(ctx, tx, id, hashes)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+func (s *UserServiceImpl) DisableMFA(ctx context.Context, actor *dto.AuditActor, claims *utils.CustomClaims, code string) error {
+	if mfaRequired(s.mfaConfig, claims.Role) {
+		return errMFARequired
+	}
+
+	state, err := s.mfa.GetTOTP(ctx, claims.ID)
+	if err != nil {
+		return err
+	}
+
+	if !state.Enabled {
+		return errMFANotEnabled
+	}
+
+	ok, err := s.checkSecondFactor(ctx, claims.ID, state, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return errRejectedMFACode
+	}
+
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.mfa.DisableMFAWithTransaction(ctx, tx, claims.ID)
+	})
+}
+
+// ResetMFA is the admin path for a user who lost both their device and
+// their recovery codes.
+func (s *UserServiceImpl) ResetMFA(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) error {
+	return s.audit.Transaction(ctx, actor, func(tx *sqlx.Tx) error {
+		return s.mfa.DisableMFAWithTransaction(ctx, tx, id)
+	})
 }
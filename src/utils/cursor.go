@@ -0,0 +1,30 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+func EncodeCursor(id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString(id[:])
+}
+
+func DecodeCursor(cursor string) (uuid.UUID, error) {
+	if cursor == "" {
+		return uuid.Nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid cursor")
+	}
+
+	id, err := uuid.FromBytes(raw)
+	if err != nil {
+		return uuid.Nil, errors.New("invalid cursor")
+	}
+
+	return id, nil
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,30 +16,63 @@ type CustomClaims struct {
 	Name  string
 	Email string
 	Role  dto.UserRole
+	Scope dto.LocationScope
 	jwt.RegisteredClaims
+
+	// APIKeyID and KeyScopes are only set for requests made with an API
+	// key, whose permissions are limited to KeyScopes.
+	APIKeyID  uuid.UUID `json:"-"`
+	KeyScopes []string  `json:"-"`
 }
 
 func GenerateToken(user *CustomClaims) (string, error) {
-	exp := time.Now().Add(config.GetExpTime())
+	now := time.Now()
+	exp := now.Add(config.GetExpTime())
 
 	claims := CustomClaims{
 		ID:    user.ID,
 		Name:  user.Name,
 		Email: user.Email,
 		Role:  user.Role,
+		Scope: user.Scope,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(exp),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(config.GetJwtSecretKey())
+	if keys == nil {
+		return "", errors.New("signing keys are not loaded")
+	}
+
+	token := jwt.NewWithClaims(keys.method, claims)
+	if keys.signingKID != "" {
+		token.Header["kid"] = keys.signingKID
+	}
+
+	return token.SignedString(keys.signingKey)
 }
 
 func VerifyToken(tokenString string) (user *CustomClaims, err error) {
+	if keys == nil {
+		return nil, errors.New("signing keys are not loaded")
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return config.GetJwtSecretKey(), nil
-	})
+		kid, _ := t.Header["kid"].(string)
+
+		key, ok := keys.verify[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+
+		return key.key, nil
+	}, jwt.WithValidMethods(keys.algorithms))
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+)
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+type keySet struct {
+	method     jwt.SigningMethod
+	signingKID string
+	signingKey any
+	verify     map[string]verificationKey
+	algorithms []string
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+var keys *keySet
+
+// LoadKeys prepares the signing key and every key accepted for verification.
+// HS256 keeps using SECRET_KEY; RS256 and EdDSA read PEM files so other
+// services can verify tokens through the JWKS endpoint. Extra verification
+// keys are given as "kid=path" (or just a path) and keep tokens signed by a
+// retired key valid until they expire.
+func LoadKeys(conf config.JWTConfig) error {
+	set := &keySet{verify: make(map[string]verificationKey)}
+
+	switch strings.ToUpper(conf.Algorithm) {
+	case "", "HS256":
+		if conf.SecretKey == "" {
+			return errors.New("SECRET_KEY is required for HS256")
+		}
+
+		set.method = jwt.SigningMethodHS256
+		set.signingKID = conf.SigningKeyID
+		set.signingKey = []byte(conf.SecretKey)
+		set.verify[conf.SigningKeyID] = verificationKey{method: set.method, key: set.signingKey}
+	case "RS256", "EDDSA":
+		method, private, err := readPrivateKey(conf.SigningKeyFile)
+		if err != nil {
+			return err
+		}
+
+		public := private.(crypto.Signer).Public()
+		kid := conf.SigningKeyID
+		if kid == "" {
+			if kid, err = keyID(public); err != nil {
+				return err
+			}
+		}
+
+		set.method = method
+		set.signingKID = kid
+		set.signingKey = private
+		set.verify[kid] = verificationKey{method: method, key: public}
+	default:
+		return fmt.Errorf("unsupported JWT algorithm %s", conf.Algorithm)
+	}
+
+	for _, entry := range conf.VerificationKeyFiles {
+		kid, path, found := strings.Cut(entry, "=")
+		if !found {
+			kid, path = "", entry
+		}
+
+		method, public, err := readPublicKey(path)
+		if err != nil {
+			return err
+		}
+
+		if kid == "" {
+			if kid, err = keyID(public); err != nil {
+				return err
+			}
+		}
+
+		set.verify[kid] = verificationKey{method: method, key: public}
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range set.verify {
+		if alg := key.method.Alg(); !seen[alg] {
+			seen[alg] = true
+			set.algorithms = append(set.algorithms, alg)
+		}
+	}
+
+	keys = set
+	return nil
+}
+
+func GetJWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if keys == nil {
+		return jwks
+	}
+
+	for kid, key := range keys.verify {
+		switch public := key.key.(type) {
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(public.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{
+				Kty: "OKP",
+				Kid: kid,
+				Use: "sig",
+				Alg: key.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(public),
+			})
+		}
+	}
+
+	return jwks
+}
+
+func readPrivateKey(path string) (jwt.SigningMethod, any, error) {
+	if path == "" {
+		return nil, nil, errors.New("JWT_SIGNING_KEY_FILE is required")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return jwt.SigningMethodRS256, key, nil
+	}
+
+	if key, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		return jwt.SigningMethodEdDSA, key, nil
+	}
+
+	return nil, nil, fmt.Errorf("%s is not an RSA or Ed25519 private key", path)
+}
+
+func readPublicKey(path string) (jwt.SigningMethod, any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return jwt.SigningMethodRS256, key, nil
+	}
+
+	if key, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return jwt.SigningMethodEdDSA, key, nil
+	}
+
+	return nil, nil, fmt.Errorf("%s is not an RSA or Ed25519 public key", path)
+}
+
+func keyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(der)
+	return base64.RawURLEncoding.EncodeToString(sum[:16]), nil
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+// NewLogger writes JSON lines at level and above.
+func NewLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}))
+}
+
+// WithLogger stores the logger for everything running on behalf of ctx.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// Logger returns the logger stored in ctx, or the default logger when the
+// work is not tied to a request.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+
+	return slog.Default()
+}
+
+// WithRequestID stores the ID the request is known by in logs and audit entries.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID stored in ctx, or "" outside a request.
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"database/sql"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics are registered with the default Prometheus registry, which also
+// carries the Go runtime and process collectors, and served at /metrics on
+// the internal metrics listener.
+var (
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "wms",
+		Name:      "http_request_duration_seconds",
+		Help:      "Time taken to answer HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	DBTransactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wms",
+		Name:      "db_transactions_total",
+		Help:      "Database transactions finished, by outcome (commit or rollback).",
+	}, []string{"outcome"})
+
+	OrderUnits = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "wms",
+		Name:      "order_units_total",
+		Help:      "Units moved by committed orders, by order type and location.",
+	}, []string{"type", "location_id"})
+)
+
+const (
+	TransactionCommit   = "commit"
+	TransactionRollback = "rollback"
+)
+
+// RegisterDBStats exposes the connection pool statistics of db.
+func RegisterDBStats(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
@@ -0,0 +1,324 @@
+package utils
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+)
+
+// OIDCProvider signs users in through an OpenID Connect identity provider
+// with the authorization code flow and PKCE.
+type OIDCProvider interface {
+	AuthCodeURL(state string, nonce string, codeVerifier string) string
+	Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*dto.OIDCIdentity, error)
+}
+
+type oidcMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type oidcProviderImpl struct {
+	config   config.OIDCConfig
+	client   *http.Client
+	metadata oidcMetadata
+
+	mu         sync.Mutex
+	keys       map[string]any
+	keysLoaded time.Time
+}
+
+// oidcKeyRefresh limits how often an unknown key id makes us fetch the
+// provider's keys again.
+const oidcKeyRefresh = time.Minute
+
+// NewOIDCProvider reads the provider's discovery document, which must name
+// the configured issuer.
+func NewOIDCProvider(ctx context.Context, conf config.OIDCConfig) (OIDCProvider, error) {
+	p := &oidcProviderImpl{
+		config: conf,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+
+	if err := p.getJSON(ctx, conf.IssuerURL+"/.well-known/openid-configuration", &p.metadata); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	if p.metadata.Issuer != conf.IssuerURL {
+		return nil, fmt.Errorf("oidc discovery returned issuer %q, expected %q", p.metadata.Issuer, conf.IssuerURL)
+	}
+
+	if p.metadata.AuthorizationEndpoint == "" || p.metadata.TokenEndpoint == "" || p.metadata.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is missing endpoints")
+	}
+
+	return p, nil
+}
+
+// PKCEChallenge derives the S256 code challenge sent with the authorization
+// request from the verifier kept by the client.
+func PKCEChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (p *oidcProviderImpl) AuthCodeURL(state string, nonce string, codeVerifier string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {p.config.RedirectURL},
+		"scope":                 {"openid email profile"},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {PKCEChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(p.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return p.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange redeems the authorization code and returns the claims of the
+// verified ID token.
+func (p *oidcProviderImpl) Exchange(ctx context.Context, code string, codeVerifier string, nonce string) (*dto.OIDCIdentity, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.config.RedirectURL},
+		"client_id":     {p.config.ClientID},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, err
+	}
+
+	if tokens.IDToken == "" {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, tokens.IDToken, nonce)
+}
+
+func (p *oidcProviderImpl) verifyIDToken(ctx context.Context, raw string, nonce string) (*dto.OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.key(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256"}),
+		jwt.WithIssuer(p.metadata.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("id token nonce does not match")
+	}
+
+	identity := &dto.OIDCIdentity{Issuer: p.metadata.Issuer}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+
+	if identity.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	if identity.Name == "" {
+		identity.Name, _ = claims["preferred_username"].(string)
+	}
+
+	switch groups := claims[p.config.GroupsClaim].(type) {
+	case []interface{}:
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+
+	return identity, nil
+}
+
+// key returns the provider's verification key. The key set is fetched again
+// when an unknown key id shows up, so rotated keys are picked up.
+func (p *oidcProviderImpl) key(ctx context.Context, kid string) (any, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	if p.keys != nil && time.Since(p.keysLoaded) < oidcKeyRefresh {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+
+	keys, err := p.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p.keys = keys
+	p.keysLoaded = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// lookupKey also accepts a token without a key id when the provider
+// publishes a single key.
+func (p *oidcProviderImpl) lookupKey(kid string) (any, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+
+	return nil, false
+}
+
+func (p *oidcProviderImpl) fetchKeys(ctx context.Context) (map[string]any, error) {
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			Crv string `json:"crv"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		} `json:"keys"`
+	}
+
+	if err := p.getJSON(ctx, p.metadata.JWKSURI, &set); err != nil {
+		return nil, fmt.Errorf("oidc keys: %w", err)
+	}
+
+	keys := make(map[string]any, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		switch {
+		case jwk.Kty == "RSA":
+			n, errN := decodeBigInt(jwk.N)
+			e, errE := decodeBigInt(jwk.E)
+			if errN != nil || errE != nil || !e.IsInt64() {
+				continue
+			}
+
+			keys[jwk.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case jwk.Kty == "EC" && jwk.Crv == "P-256":
+			x, errX := decodeBigInt(jwk.X)
+			y, errY := decodeBigInt(jwk.Y)
+			if errX != nil || errY != nil {
+				continue
+			}
+
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+
+	return keys, nil
+}
+
+func (p *oidcProviderImpl) getJSON(ctx context.Context, target string, value any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(value)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  int
+	keyLength   uint32
+}
+
+// New hashes use Argon2id with the parameters below, encoded into the hash
+// as $argon2id$v=19$m=...,t=...,p=...$salt$key so they can change later
+// without breaking stored passwords.
+var currentArgon2 = argon2Params{
+	memory:      64 * 1024,
+	iterations:  3,
+	parallelism: 2,
+	saltLength:  16,
+	keyLength:   32,
+}
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+func HashPassword(password string) (string, error) {
+	salt := make([]byte, currentArgon2.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, currentArgon2.iterations, currentArgon2.memory, currentArgon2.parallelism, currentArgon2.keyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, currentArgon2.memory, currentArgon2.iterations, currentArgon2.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// VerifyPassword checks a password against an Argon2id hash or a bcrypt
+// hash left over from before the switch.
+func VerifyPassword(hashedPassword, password string) error {
+	if !strings.HasPrefix(hashedPassword, "$argon2id$") {
+		if err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password)); err != nil {
+			return ErrPasswordMismatch
+		}
+
+		return nil
+	}
+
+	params, salt, key, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether a stored hash uses bcrypt or older Argon2id
+// parameters and should be replaced after the next successful login.
+func NeedsRehash(hashedPassword string) bool {
+	params, salt, key, err := decodeArgon2(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.memory != currentArgon2.memory ||
+		params.iterations != currentArgon2.iterations ||
+		params.parallelism != currentArgon2.parallelism ||
+		len(salt) != currentArgon2.saltLength ||
+		uint32(len(key)) != currentArgon2.keyLength
+}
+
+func decodeArgon2(hashedPassword string) (*argon2Params, []byte, []byte, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, errors.New("not an argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil || params.memory == 0 || params.iterations == 0 || params.parallelism == 0 {
+		return nil, nil, nil, errors.New("invalid argon2 parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errors.New("invalid argon2 salt")
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2 key")
+	}
+
+	return &params, salt, key, nil
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+)
+
+// MaxPasswordLength bounds the work a single login can cause.
+const MaxPasswordLength = 128
+
+type passwordPolicy struct {
+	minLength   int
+	historySize int
+	breached    map[string]struct{}
+}
+
+var policy = &passwordPolicy{minLength: 8}
+
+var ErrPasswordBreached = errors.New("password appears in a list of breached passwords")
+
+// LoadPasswordPolicy applies the configured rules. The breached list is a
+// text file with one password per line and is matched case-insensitively.
+func LoadPasswordPolicy(conf config.PasswordConfig) error {
+	loaded := &passwordPolicy{
+		minLength:   conf.MinLength,
+		historySize: conf.HistorySize,
+	}
+
+	if conf.BreachedListFile != "" {
+		file, err := os.Open(conf.BreachedListFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		loaded.breached = make(map[string]struct{})
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" {
+				loaded.breached[strings.ToLower(line)] = struct{}{}
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+	}
+
+	policy = loaded
+	return nil
+}
+
+// CheckPassword validates a new password against the policy. identifiers
+// are values the password must not equal, such as the account's email.
+func CheckPassword(password string, identifiers ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.minLength {
+		return fmt.Errorf("password must be at least %d characters", policy.minLength)
+	}
+
+	if length > MaxPasswordLength {
+		return fmt.Errorf("password must be at most %d characters", MaxPasswordLength)
+	}
+
+	lowered := strings.ToLower(password)
+
+	for _, identifier := range identifiers {
+		if identifier != "" && lowered == strings.ToLower(identifier) {
+			return errors.New("password must not match your account details")
+		}
+	}
+
+	if _, ok := policy.breached[lowered]; ok {
+		return ErrPasswordBreached
+	}
+
+	return nil
+}
+
+// PasswordHistorySize is how many recent passwords, the current one
+// included, may not be reused.
+func PasswordHistorySize() int {
+	return policy.historySize
+}
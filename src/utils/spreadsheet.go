@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func ReadCSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	return reader.ReadAll()
+}
+
+func ReadXLSX(r io.Reader) ([][]string, error) {
+	file, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	sheets := file.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("workbook has no sheets")
+	}
+
+	return file.GetRows(sheets[0])
+}
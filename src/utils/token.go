@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+func GenerateOpaqueToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// APIKeyPrefix starts every API key so keys are easy to recognise in
+// headers and secret scanners.
+const APIKeyPrefix = "wms_"
+
+// GenerateAPIKey returns a new key and the short prefix that is kept in the
+// clear to tell keys apart.
+func GenerateAPIKey() (key string, prefix string, err error) {
+	token, err := GenerateOpaqueToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	key = APIKeyPrefix + token
+
+	return key, key[:len(APIKeyPrefix)+8], nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,25 +1,124 @@
 package utils
 
 import (
+	"encoding/json"
 	"errors"
-	"net/http"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
 
+	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 )
 
-func Validate(obj any) (int, string) {
-	validate := validator.New()
+func init() {
+	// Report fields by the names clients send, not the Go struct names.
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonFieldName)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return field.Name
+	}
 
-	if err := validate.Struct(obj); err != nil {
-		var ve validator.ValidationErrors
-		var msg = ""
+	return name
+}
+
+// Validate checks obj against its binding tags, the same rules gin applies
+// when binding a request body.
+func Validate(obj any) error {
+	if err := binding.Validator.ValidateStruct(obj); err != nil {
+		return BindingError(err)
+	}
+
+	return nil
+}
+
+// BindingError turns an error from gin's ShouldBind* or Validate into a
+// validation error. Every failing field is listed; decoder internals are not
+// passed on to the client.
+func BindingError(err error) error {
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
+		fields := make([]domain.FieldError, 0, len(ve))
+		for _, fe := range ve {
+			fields = append(fields, domain.FieldError{
+				Field:   fieldPath(fe),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: fieldMessage(fe),
+			})
+		}
+
+		return domain.InvalidFields("invalid_data", "data not valid : "+fields[0].Field, fields)
+	}
 
-		if errors.As(err, &ve) {
-			msg = "data not valid : " + ve[0].Field()
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		field := domain.FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: "must be of type " + typeErr.Type.String(),
 		}
 
-		return http.StatusBadRequest, msg
+		return domain.InvalidFields("invalid_data", "data not valid : "+field.Field, []domain.FieldError{field})
+	}
+
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.Is(err, io.EOF):
+		return domain.Validation("empty_body", "request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return domain.Validation("malformed_body", "request body is not valid JSON")
+	}
+
+	return domain.Validation("malformed_body", "request body could not be decoded")
+}
+
+// fieldPath drops the root struct name from the namespace, so a nested
+// failure reads "lines[0].quantity".
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+
+	return fe.Field()
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	unit := ""
+	switch fe.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "uuid", "uuid4":
+		return "must be a uuid"
+	case "min", "gte":
+		return fmt.Sprintf("must be at least %s%s", fe.Param(), unit)
+	case "max", "lte":
+		return fmt.Sprintf("must be at most %s%s", fe.Param(), unit)
+	case "len":
+		return fmt.Sprintf("must be exactly %s%s", fe.Param(), unit)
+	case "oneof":
+		return "must be one of: " + strings.Join(strings.Fields(fe.Param()), ", ")
 	}
 
-	return 0, ""
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
 }
@@ -0,0 +1,65 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuditHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockAuditService := new(mocks.MockAuditService)
+	handler := handlers.NewAuditHandler(mockAuditService)
+
+	t.Run("ListAuditLogs_Filter", func(t *testing.T) {
+		actorID := uuid.New()
+		entityID := uuid.New()
+		from := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+		filter := &dto.AuditLogFilter{ActorID: &actorID, Entity: "product", EntityID: &entityID, Action: dto.AuditActionDelete, From: &from}
+		logs := []*dto.AuditLog{{ID: uuid.New(), ActorID: &actorID, Action: dto.AuditActionDelete, Entity: "product", EntityID: entityID, Changes: []byte(`{"deleted_at":{"before":null,"after":"2024-05-02T00:00:00Z"}}`)}}
+		mockAuditService.On("GetAll", filter, mock.Anything).Return(logs, &web.PageInfo{TotalItems: 1}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/audit-logs?actor_id="+actorID.String()+"&entity=product&entity_id="+entityID.String()+"&action=delete&from=2024-05-01T00:00:00Z", nil)
+
+		handler.ListAuditLogs(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"deleted_at":{"before":null`)
+		mockAuditService.AssertExpectations(t)
+	})
+
+	t.Run("ListAuditLogs_BadActor", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/audit-logs?actor_id=nope", nil)
+
+		handler.ListAuditLogs(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("ListAuditLogs_BadTime", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/audit-logs?to=yesterday", nil)
+
+		handler.ListAuditLogs(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
@@ -11,11 +11,14 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/handlers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 	"github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestLocationHandler(t *testing.T) {
@@ -30,7 +33,8 @@ func TestLocationHandler(t *testing.T) {
 			Name: "Main Warehouse",
 		}
 
-		mockLocationService.On("Save", &location).Return(201, nil).Once()
+		created := &dto.Location{ID: uuid.New(), Name: location.Name}
+		mockLocationService.On("Save", mock.Anything, mock.Anything, &location).Return(created, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -44,7 +48,8 @@ func TestLocationHandler(t *testing.T) {
 		handler.AddLocation(ctx)
 
 		assert.Equal(t, http.StatusCreated, recorder.Code)
-		assert.Contains(t, recorder.Body.String(), "CREATED")
+		assert.Equal(t, "/api/v1/locations/"+created.ID.String(), recorder.Header().Get("Location"))
+		assert.Contains(t, recorder.Body.String(), created.ID.String())
 		mockLocationService.AssertExpectations(t)
 	})
 
@@ -60,7 +65,81 @@ func TestLocationHandler(t *testing.T) {
 		handler.AddLocation(ctx)
 
 		assert.Equal(t, http.StatusBadRequest, recorder.Code)
-		assert.Contains(t, recorder.Body.String(), "invalid character")
+		assert.Contains(t, recorder.Body.String(), `"error_code":"malformed_body"`)
+		assert.NotContains(t, recorder.Body.String(), "invalid character")
+	})
+
+	t.Run("GetLocationByID_Success", func(t *testing.T) {
+		location := &dto.Location{ID: uuid.New(), Name: "Main Warehouse"}
+		mockLocationService.On("GetByID", mock.Anything, location.ID).Return(location, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "location_id", Value: location.ID.String()}}
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/locations/"+location.ID.String(), nil)
+
+		handler.GetLocationByID(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Main Warehouse")
+	})
+
+	t.Run("GetLocationByID_InvalidID", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "location_id", Value: "nope"}}
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/locations/nope", nil)
+
+		handler.GetLocationByID(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("DeleteLocation_Success", func(t *testing.T) {
+		locationID := uuid.New()
+		adminID := uuid.New()
+		mockLocationService.On("Delete", mock.Anything, mock.MatchedBy(func(actor *dto.AuditActor) bool { return actor.UserID == adminID }), locationID).Return(nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Set("user", &utils.CustomClaims{ID: adminID})
+		ctx.Params = gin.Params{{Key: "location_id", Value: locationID.String()}}
+		ctx.Request, _ = http.NewRequest(http.MethodDelete, "/api/v1/locations/"+locationID.String(), nil)
+
+		handler.DeleteLocation(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("DeleteLocation_NotEmpty", func(t *testing.T) {
+		locationID := uuid.New()
+		mockLocationService.On("Delete", mock.Anything, mock.Anything, locationID).Return(domain.Conflict("location_not_empty", "location still has products")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Set("user", &utils.CustomClaims{ID: uuid.New()})
+		ctx.Params = gin.Params{{Key: "location_id", Value: locationID.String()}}
+		ctx.Request, _ = http.NewRequest(http.MethodDelete, "/api/v1/locations/"+locationID.String(), nil)
+
+		handler.DeleteLocation(ctx)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"error_code":"location_not_empty"`)
+	})
+
+	t.Run("RestoreLocation_Success", func(t *testing.T) {
+		location := &dto.Location{ID: uuid.New(), Name: "Main Warehouse"}
+		mockLocationService.On("Restore", mock.Anything, mock.Anything, location.ID).Return(location, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "location_id", Value: location.ID.String()}}
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/locations/"+location.ID.String()+"/restore", nil)
+
+		handler.RestoreLocation(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Main Warehouse")
 	})
 
 	t.Run("GetAllLocations_Success", func(t *testing.T) {
@@ -74,7 +153,7 @@ func TestLocationHandler(t *testing.T) {
 			{ID: uuid.New(), Name: "Secondary Warehouse"},
 		}
 
-		mockLocationService.On("GetAll", pagination).Return(mockLocations, 200, nil).Once()
+		mockLocationService.On("GetAll", mock.Anything, pagination).Return(mockLocations, &web.PageInfo{TotalItems: int64(len(mockLocations))}, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -96,7 +175,7 @@ func TestLocationHandler(t *testing.T) {
 			Size: 10,
 		}
 
-		mockLocationService.On("GetAll", pagination).Return(([]*dto.Location)(nil), 500, errors.New("internal server error")).Once()
+		mockLocationService.On("GetAll", mock.Anything, pagination).Return(([]*dto.Location)(nil), (*web.PageInfo)(nil), errors.New("internal server error")).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
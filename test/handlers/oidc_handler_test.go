@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestOIDCHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockOIDCService := new(mocks.MockOIDCService)
+	handler := handlers.NewOIDCHandler(mockOIDCService)
+
+	t.Run("Login_Redirects", func(t *testing.T) {
+		mockOIDCService.On("StartLogin").Return("https://idp.example.com/authorize?state=abc", nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil)
+
+		handler.Login(ctx)
+
+		assert.Equal(t, http.StatusFound, recorder.Code)
+		assert.Equal(t, "https://idp.example.com/authorize?state=abc", recorder.Header().Get("Location"))
+	})
+
+	t.Run("Login_NotConfigured", func(t *testing.T) {
+		mockOIDCService.On("StartLogin").Return("", domain.NotFound("sso_not_configured", "single sign-on is not configured")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil)
+
+		handler.Login(ctx)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Callback_Success", func(t *testing.T) {
+		tokens := &dto.TokenResponse{AccessToken: "access", RefreshToken: "refresh", TokenType: "Bearer"}
+		mockOIDCService.On("CompleteLogin", mock.Anything, mock.Anything, "the-code", "the-state").Return(tokens, nil, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code=the-code&state=the-state", nil)
+
+		handler.Callback(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "access")
+		mockOIDCService.AssertExpectations(t)
+	})
+
+	t.Run("Callback_MFAChallenge", func(t *testing.T) {
+		challenge := &dto.MFAChallengeResponse{MFAToken: "the-mfa-token", ExpiresIn: 300}
+		mockOIDCService.On("CompleteLogin", mock.Anything, mock.Anything, "the-code", "the-state").Return(nil, challenge, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?code=the-code&state=the-state", nil)
+
+		handler.Callback(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "the-mfa-token")
+		assert.NotContains(t, recorder.Body.String(), "access_token")
+		mockOIDCService.AssertExpectations(t)
+	})
+
+	t.Run("Callback_ProviderError", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?error=access_denied&state=the-state", nil)
+
+		handler.Callback(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "access_denied")
+	})
+}
@@ -1,7 +1,6 @@
 package handlers_test
 
 import (
-	"database/sql"
 	"errors"
 	"net/http"
 	"net/http/httptest"
@@ -11,8 +10,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/handlers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -33,12 +34,14 @@ func TestOrderHandler(t *testing.T) {
 		c, _ := gin.CreateTestContext(w)
 		c.Request = req
 
-		orderService.On("ReceiveOrder", mock.Anything).Return(200, nil).Once()
+		created := &dto.Order{ID: uuid.New(), Type: dto.OrderTypeReceiving, ProductID: uuid.MustParse("e4c2c817-0e3d-4a87-9e4f-70856d3120a8")}
+		orderService.On("ReceiveOrder", mock.Anything, mock.Anything, mock.Anything).Return(created, nil).Once()
 
 		orderHandler.ReceiveOrder(c)
 
-		assert.Equal(t, http.StatusOK, w.Code)
-		assert.Contains(t, w.Body.String(), "Successfully Receive Data")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/api/v1/orders/"+created.ID.String(), w.Header().Get("Location"))
+		assert.Contains(t, w.Body.String(), created.ID.String())
 	})
 
 	t.Run("ReceiveOrder - Failure", func(t *testing.T) {
@@ -49,7 +52,7 @@ func TestOrderHandler(t *testing.T) {
 		c, _ := gin.CreateTestContext(w)
 		c.Request = req
 
-		orderService.On("ReceiveOrder", mock.Anything).Return(500, errors.New("internal server error")).Once()
+		orderService.On("ReceiveOrder", mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("internal server error")).Once()
 
 		orderHandler.ReceiveOrder(c)
 
@@ -65,12 +68,14 @@ func TestOrderHandler(t *testing.T) {
 		c, _ := gin.CreateTestContext(w)
 		c.Request = req
 
-		orderService.On("ShipOrder", mock.Anything).Return(200, nil).Once()
+		created := &dto.Order{ID: uuid.New(), Type: dto.OrderTypeShipping, ProductID: uuid.MustParse("e4c2c817-0e3d-4a87-9e4f-70856d3120a8")}
+		orderService.On("ShipOrder", mock.Anything, mock.Anything, mock.Anything).Return(created, nil).Once()
 
 		orderHandler.ShipOrder(c)
 
-		assert.Equal(t, http.StatusOK, w.Code)
-		assert.Contains(t, w.Body.String(), "Successfully Shipping Data")
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/api/v1/orders/"+created.ID.String(), w.Header().Get("Location"))
+		assert.Contains(t, w.Body.String(), created.ID.String())
 	})
 
 	t.Run("GetAllOrders - Success", func(t *testing.T) {
@@ -82,7 +87,7 @@ func TestOrderHandler(t *testing.T) {
 		mockOrders := []*dto.Order{
 			{ID: uuid.New(), ProductID: uuid.New(), Quantity: 5},
 		}
-		orderService.On("GetAllOrders", mock.Anything).Return(mockOrders, 200, nil).Once()
+		orderService.On("GetAllOrders", mock.Anything, mock.Anything).Return(mockOrders, &web.PageInfo{TotalItems: int64(len(mockOrders))}, nil).Once()
 
 		orderHandler.GetAllOrders(c)
 
@@ -104,7 +109,7 @@ func TestOrderHandler(t *testing.T) {
 			ProductID: uuid.New(),
 			Quantity:  5,
 		}
-		orderService.On("GetOrderByID", orderID).Return(mockOrder, 200, nil).Once()
+		orderService.On("GetOrderByID", mock.Anything, orderID).Return(mockOrder, nil).Once()
 
 		orderHandler.GetOrderByID(c)
 
@@ -121,11 +126,11 @@ func TestOrderHandler(t *testing.T) {
 		ctx.Request = req
 		ctx.Params = gin.Params{{Key: "order_id", Value: orderID.String()}}
 
-		orderService.On("GetOrderByID", orderID).Return(nil, 404, sql.ErrNoRows).Once()
+		orderService.On("GetOrderByID", mock.Anything, orderID).Return(nil, domain.NotFound("order_not_found", "order not found")).Once()
 
 		orderHandler.GetOrderByID(ctx)
 
 		assert.Equal(t, http.StatusNotFound, w.Code)
-		assert.Contains(t, w.Body.String(), "sql: no rows in result set")
+		assert.Contains(t, w.Body.String(), `"error_code":"order_not_found"`)
 	})
 }
@@ -2,19 +2,28 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/helpers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 	"github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestProductHandler(t *testing.T) {
@@ -31,7 +40,10 @@ func TestProductHandler(t *testing.T) {
 			LocationID: uuid.New(),
 		}
 
-		mockProductService.On("Create", &product).Return(201, nil).Once()
+		created := product
+		created.ID = uuid.New()
+		created.Version = 1
+		mockProductService.On("Create", mock.Anything, mock.Anything, &product).Return(&created, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -44,10 +56,58 @@ func TestProductHandler(t *testing.T) {
 		handler.AddProduct(ctx)
 
 		assert.Equal(t, http.StatusCreated, recorder.Code)
-		assert.Contains(t, recorder.Body.String(), "CREATED")
+		assert.Equal(t, "/api/v1/products/"+created.ID.String(), recorder.Header().Get("Location"))
+		assert.Contains(t, recorder.Body.String(), created.ID.String())
 		mockProductService.AssertExpectations(t)
 	})
 
+	t.Run("AddProduct_ProblemDetails", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewBufferString(`{"name":"Product A","quantity":5}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/problem+json")
+		ctx.Request = req
+
+		handler.AddProduct(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+		var problem helpers.Problem
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+		assert.Equal(t, "urn:warehouse:problem:invalid_data", problem.Type)
+		assert.Equal(t, "Bad Request", problem.Title)
+		assert.Equal(t, http.StatusBadRequest, problem.Status)
+		assert.Equal(t, "/api/v1/products", problem.Instance)
+		assert.Equal(t, []domain.FieldError{
+			{Field: "sku", Rule: "required", Message: "is required"},
+			{Field: "location_id", Rule: "required", Message: "is required"},
+		}, problem.Errors)
+	})
+
+	t.Run("AddProduct_LegacyEnvelope", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/products", bytes.NewBufferString(`{"name":"Product A","quantity":"five"}`))
+		req.Header.Set("Content-Type", "application/json")
+		ctx.Request = req
+
+		handler.AddProduct(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+		assert.JSONEq(t, `{
+			"status": "BAD REQUEST",
+			"status_code": 400,
+			"error_message": "data not valid : quantity",
+			"error_code": "invalid_data",
+			"errors": [{"field": "quantity", "rule": "type", "param": "int64", "message": "must be of type int64"}]
+		}`, recorder.Body.String())
+	})
+
 	t.Run("GetAllProducts_Success", func(t *testing.T) {
 		pagination := &web.PaginationRequest{Page: 1, Size: 10}
 		mockProducts := []*dto.Product{
@@ -55,7 +115,7 @@ func TestProductHandler(t *testing.T) {
 			{ID: uuid.New(), Name: "Product B"},
 		}
 
-		mockProductService.On("GetAll", pagination).Return(mockProducts, 200, nil).Once()
+		mockProductService.On("GetAll", mock.Anything, pagination).Return(mockProducts, &web.PageInfo{TotalItems: int64(len(mockProducts))}, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -68,14 +128,106 @@ func TestProductHandler(t *testing.T) {
 		assert.Equal(t, http.StatusOK, recorder.Code)
 		assert.Contains(t, recorder.Body.String(), "Product A")
 		assert.Contains(t, recorder.Body.String(), "Product B")
+		assert.Contains(t, recorder.Body.String(), `"total_items":2,"total_pages":1,"has_next":false`)
+		assert.Contains(t, recorder.Header().Get("Link"), `rel="last"`)
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("GetAllProducts_Cursor", func(t *testing.T) {
+		after := uuid.New()
+		pagination := &web.PaginationRequest{Size: 2, Keyset: true, After: after}
+		mockProducts := []*dto.Product{
+			{ID: uuid.New(), Name: "Product C"},
+			{ID: uuid.New(), Name: "Product D"},
+		}
+
+		mockProductService.On("GetAll", mock.Anything, pagination).Return(mockProducts, &web.PageInfo{HasNext: true}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?size=2&cursor="+utils.EncodeCursor(after), nil)
+		ctx.Request = req
+
+		handler.GetAllProducts(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"next_cursor":"`+utils.EncodeCursor(mockProducts[1].ID)+`"`)
+		assert.Contains(t, recorder.Header().Get("Link"), `rel="next"`)
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("GetAllProducts_InvalidCursor", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?cursor=not-a-cursor", nil)
+		ctx.Request = req
+
+		handler.GetAllProducts(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("GetAllProducts_InvalidPagination", func(t *testing.T) {
+		mockProductService := new(mocks.MockProductService)
+		handler := handlers.NewProductHandler(mockProductService, validator)
+
+		for _, query := range []string{"size=-1", "size=0", "size=101", "size=1000000", "page=0", "page=-3", "page=abc"} {
+			recorder := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(recorder)
+
+			req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?"+query, nil)
+			ctx.Request = req
+
+			handler.GetAllProducts(ctx)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+		}
+		mockProductService.AssertNotCalled(t, "GetAll", mock.Anything, mock.Anything)
+	})
+
+	t.Run("GetAllProducts_MaxPageSize", func(t *testing.T) {
+		pagination := &web.PaginationRequest{Page: 2, Size: 100}
+		mockProductService.On("GetAll", mock.Anything, pagination).Return([]*dto.Product{}, &web.PageInfo{}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products?page=2&size=100", nil)
+		ctx.Request = req
+
+		handler.GetAllProducts(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("GetProductByID_UsesTokenScope", func(t *testing.T) {
+		productID := uuid.New()
+		scope := dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}
+
+		mockProductService.On("GetByID", &scope, productID).Return(nil, domain.Forbidden("location_forbidden", "location is not assigned to you")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/"+productID.String(), nil)
+		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
+		ctx.Request = req
+		ctx.Set("user", &utils.CustomClaims{Role: dto.UserRoleStaff, Scope: scope})
+
+		handler.GetProductByID(ctx)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
 		mockProductService.AssertExpectations(t)
 	})
 
 	t.Run("GetProductByID_Success", func(t *testing.T) {
 		productID := uuid.New()
-		product := &dto.Product{ID: productID, Name: "Product A"}
+		product := &dto.Product{ID: productID, Name: "Product A", Version: 1}
 
-		mockProductService.On("GetByID", productID).Return(product, 200, nil).Once()
+		mockProductService.On("GetByID", mock.Anything, productID).Return(product, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -87,15 +239,18 @@ func TestProductHandler(t *testing.T) {
 		handler.GetProductByID(ctx)
 
 		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"1"`, recorder.Header().Get("ETag"))
 		assert.Contains(t, recorder.Body.String(), "Product A")
 		mockProductService.AssertExpectations(t)
 	})
 
 	t.Run("UpdateProduct_Success", func(t *testing.T) {
 		productID := uuid.New()
-		product := dto.Product{ID: productID, Name: "Updated Product", SKU: "SK-1000", Quantity: 6, LocationID: uuid.New()}
+		product := dto.Product{ID: productID, Name: "Updated Product", SKU: "SK-1000", Quantity: 6, LocationID: uuid.New(), Version: 3}
 
-		mockProductService.On("Update", &product).Return(200, nil).Once()
+		mockProductService.On("Update", mock.Anything, mock.Anything, &product).Run(func(args mock.Arguments) {
+			args.Get(2).(*dto.Product).Version = 4
+		}).Return(nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -103,25 +258,97 @@ func TestProductHandler(t *testing.T) {
 		body, _ := json.Marshal(product)
 		req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/"+productID.String(), bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
 		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
 		ctx.Request = req
 
 		handler.UpdateProduct(ctx)
 
 		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
 		assert.Contains(t, recorder.Body.String(), "Successfully Updated Data")
 		mockProductService.AssertExpectations(t)
 	})
 
+	t.Run("UpdateProduct_RequiresIfMatch", func(t *testing.T) {
+		productID := uuid.New()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/"+productID.String(), bytes.NewBufferString(`{}`))
+		req.Header.Set("Content-Type", "application/json")
+		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
+		ctx.Request = req
+
+		handler.UpdateProduct(ctx)
+
+		assert.Equal(t, http.StatusPreconditionRequired, recorder.Code)
+	})
+
+	t.Run("UpdateProduct_StaleVersion", func(t *testing.T) {
+		productID := uuid.New()
+		product := dto.Product{ID: productID, Name: "Updated Product", SKU: "SK-1000", Quantity: 6, LocationID: uuid.New()}
+
+		mockProductService.On("Update", mock.Anything, mock.Anything, mock.MatchedBy(func(p *dto.Product) bool {
+			return p.ID == productID && p.Version == -1
+		})).Return(domain.PreconditionFailed("version_mismatch", "product was changed since it was read")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(product)
+		req, _ := http.NewRequest(http.MethodPut, "/api/v1/products/"+productID.String(), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `W/"3"`)
+		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
+		ctx.Request = req
+
+		handler.UpdateProduct(ctx)
+
+		assert.Equal(t, http.StatusPreconditionFailed, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"error_code":"version_mismatch"`)
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("PatchProduct_Success", func(t *testing.T) {
+		productID := uuid.New()
+		sku := "SK-2000"
+		patched := &dto.Product{ID: productID, Name: "Product A", SKU: sku, Quantity: 6, Version: 8}
+
+		mockProductService.On("Patch", mock.Anything, mock.Anything, productID, &dto.ProductPatch{SKU: &sku}, int64(7)).Return(patched, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/api/v1/products/"+productID.String(), bytes.NewBufferString(`{"sku":"SK-2000","quantity":0}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"7"`)
+		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
+		ctx.Request = req
+
+		handler.PatchProduct(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"8"`, recorder.Header().Get("ETag"))
+		assert.Contains(t, recorder.Body.String(), `"quantity":6`)
+		mockProductService.AssertExpectations(t)
+	})
+
 	t.Run("DeleteProduct_Success", func(t *testing.T) {
 		productID := uuid.New()
 
-		mockProductService.On("Delete", productID).Return(200, nil).Once()
+		adminID := uuid.New()
+
+		mockProductService.On("Delete", mock.Anything, mock.MatchedBy(func(actor *dto.AuditActor) bool {
+			return actor.UserID == adminID && actor.RequestID == "request-1"
+		}), productID).Return(nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Set("user", &utils.CustomClaims{ID: adminID})
 
-		req, _ := http.NewRequest(http.MethodDelete, "/api/v1/products/"+productID.String(), nil)
+		req, _ := http.NewRequestWithContext(utils.WithRequestID(context.Background(), "request-1"), http.MethodDelete, "/api/v1/products/"+productID.String(), nil)
 		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
 		ctx.Request = req
 
@@ -131,4 +358,204 @@ func TestProductHandler(t *testing.T) {
 		assert.Contains(t, recorder.Body.String(), "Successfully delete product")
 		mockProductService.AssertExpectations(t)
 	})
+
+	t.Run("DeleteProduct_HasStock", func(t *testing.T) {
+		productID := uuid.New()
+
+		mockProductService.On("Delete", mock.Anything, mock.Anything, productID).Return(domain.Conflict("product_has_stock", "product still has stock")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Set("user", &utils.CustomClaims{ID: uuid.New()})
+		ctx.Params = gin.Params{{Key: "product_id", Value: productID.String()}}
+		ctx.Request, _ = http.NewRequest(http.MethodDelete, "/api/v1/products/"+productID.String(), nil)
+
+		handler.DeleteProduct(ctx)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"error_code":"product_has_stock"`)
+	})
+
+	t.Run("RestoreProduct_Success", func(t *testing.T) {
+		product := &dto.Product{ID: uuid.New(), Name: "Product A", Version: 4}
+
+		mockProductService.On("Restore", mock.Anything, mock.Anything, product.ID).Return(product, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "product_id", Value: product.ID.String()}}
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/products/"+product.ID.String()+"/restore", nil)
+
+		handler.RestoreProduct(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, `"4"`, recorder.Header().Get("ETag"))
+		assert.Contains(t, recorder.Body.String(), "Product A")
+	})
+
+	t.Run("ImportProducts_DryRun", func(t *testing.T) {
+		locationID := uuid.New()
+		rows := []*dto.ProductImportRow{
+			{Row: 2, Product: &dto.Product{Name: "Product A", SKU: "SKU-A", Quantity: 3, LocationID: locationID}},
+			{Row: 3, Product: &dto.Product{Name: "Product B", SKU: "SKU-B", LocationID: locationID}, Errors: []string{"quantity must be an integer"}},
+		}
+		report := &dto.ProductImportReport{DryRun: true, TotalRows: 2, Imported: 1}
+
+		mockProductService.On("Import", mock.Anything, mock.Anything, mock.MatchedBy(func(got []*dto.ProductImportRow) bool {
+			return len(got) == 2 && assert.ObjectsAreEqual(rows[0], got[0]) && got[1].Errors[0] == rows[1].Errors[0]
+		}), true).Return(report, nil).Once()
+
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "products.csv")
+		part.Write([]byte("name,sku,quantity,location_id\nProduct A,SKU-A,3," + locationID.String() + "\nProduct B,SKU-B,many," + locationID.String() + "\n"))
+		writer.Close()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/products/import?dry_run=true", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx.Request = req
+
+		handler.ImportProducts(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"dry_run":true`)
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("ImportProducts_MissingColumn", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "products.csv")
+		part.Write([]byte("name,sku\nProduct A,SKU-A\n"))
+		writer.Close()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/products/import", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx.Request = req
+
+		handler.ImportProducts(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "missing column quantity")
+	})
+
+	t.Run("ImportProducts_TooLarge", func(t *testing.T) {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "products.csv")
+		part.Write(bytes.Repeat([]byte("Product A,SKU-A,1,x\n"), 600_000))
+		writer.Close()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodPost, "/api/v1/products/import", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx.Request = req
+
+		handler.ImportProducts(ctx)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "file must not exceed 10 MB")
+	})
+
+	t.Run("ExportProducts_CSV", func(t *testing.T) {
+		pagination := &web.PaginationRequest{Keyset: true}
+		product := &dto.Product{ID: uuid.New(), Name: "Product A", SKU: "SKU-A", Quantity: 3, LocationID: uuid.New()}
+
+		mockProductService.On("Export", mock.Anything, pagination, mock.Anything).Return([]*dto.Product{product}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export", nil)
+		ctx.Request = req
+
+		handler.ExportProducts(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, "id,name,sku,quantity,location_id\n"+product.ID.String()+",Product A,SKU-A,3,"+product.LocationID.String()+"\n", recorder.Body.String())
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("ExportProducts_CSVEscapesFormulas", func(t *testing.T) {
+		pagination := &web.PaginationRequest{Keyset: true}
+		product := &dto.Product{ID: uuid.New(), Name: "=HYPERLINK(\"http://evil\")", SKU: "@SUM(A1)", Quantity: 3, LocationID: uuid.New()}
+
+		mockProductService.On("Export", mock.Anything, pagination, mock.Anything).Return([]*dto.Product{product}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export", nil)
+		ctx.Request = req
+
+		handler.ExportProducts(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "id,name,sku,quantity,location_id\n"+product.ID.String()+`,"'=HYPERLINK(""http://evil"")",'@SUM(A1),3,`+product.LocationID.String()+"\n", recorder.Body.String())
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("ExportProducts_NegativeSize", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export?size=-1", nil)
+		ctx.Request = req
+
+		handler.ExportProducts(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "size must be a number of at least 0")
+	})
+
+	t.Run("ExportProducts_NDJSON", func(t *testing.T) {
+		pagination := &web.PaginationRequest{Size: 2, Keyset: true}
+		products := []*dto.Product{
+			{ID: uuid.New(), Name: "Product A"},
+			{ID: uuid.New(), Name: "Product B"},
+		}
+
+		mockProductService.On("Export", mock.Anything, pagination, mock.Anything).Return(products, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export?format=ndjson&size=2", nil)
+		ctx.Request = req
+
+		handler.ExportProducts(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+		assert.Equal(t, 2, strings.Count(recorder.Body.String(), "\n"))
+		mockProductService.AssertExpectations(t)
+	})
+
+	t.Run("ExportProducts_Error", func(t *testing.T) {
+		pagination := &web.PaginationRequest{Keyset: true}
+
+		mockProductService.On("Export", mock.Anything, pagination, mock.Anything).Return(nil, errors.New("query failed")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		req, _ := http.NewRequest(http.MethodGet, "/api/v1/products/export", nil)
+		ctx.Request = req
+
+		handler.ExportProducts(ctx)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"error_code":"internal_error"`)
+		assert.NotContains(t, recorder.Body.String(), "query failed")
+		mockProductService.AssertExpectations(t)
+	})
 }
@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoleHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockPermissionService := new(mocks.MockPermissionService)
+	handler := handlers.NewRoleHandler(mockPermissionService)
+
+	t.Run("ListRoles_Success", func(t *testing.T) {
+		roles := []*dto.Role{{Name: dto.UserRoleStaff, Permissions: []string{dto.PermissionOrderShip}}}
+		mockPermissionService.On("ListRoles").Return(roles, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/roles", nil)
+
+		handler.ListRoles(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), dto.PermissionOrderShip)
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("CreateRole_Success", func(t *testing.T) {
+		role := dto.Role{Name: "auditor", Permissions: []string{dto.PermissionProductRead}}
+		mockPermissionService.On("CreateRole", &role).Return(nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(role)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/roles", bytes.NewBuffer(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.CreateRole(ctx)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("CreateRole_BadRequest", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/roles", bytes.NewBufferString(`{"description":"no name"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.CreateRole(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("UpdateRole_Success", func(t *testing.T) {
+		request := dto.RoleUpdateRequest{Description: "Ships stock", Permissions: []string{dto.PermissionOrderShip}}
+		role := &dto.Role{Name: dto.UserRoleStaff, Description: request.Description, Permissions: request.Permissions}
+		mockPermissionService.On("UpdateRole", dto.UserRoleStaff, &request).Return(role, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(request)
+		ctx.Request, _ = http.NewRequest(http.MethodPut, "/api/v1/roles/staff", bytes.NewBuffer(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Params = gin.Params{{Key: "role", Value: "staff"}}
+
+		handler.UpdateRole(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Ships stock")
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("DeleteRole_Conflict", func(t *testing.T) {
+		mockPermissionService.On("DeleteRole", dto.UserRoleStaff).Return(domain.Conflict("role_in_use", "role is assigned to 2 users")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Request, _ = http.NewRequest(http.MethodDelete, "/api/v1/roles/staff", nil)
+		ctx.Params = gin.Params{{Key: "role", Value: "staff"}}
+
+		handler.DeleteRole(ctx)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		mockPermissionService.AssertExpectations(t)
+	})
+}
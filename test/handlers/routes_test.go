@@ -0,0 +1,61 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/routes"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("Not On The Public Router", func(t *testing.T) {
+		validator := validator.New()
+		pass := func(c *gin.Context) { c.Next() }
+
+		r := gin.New()
+		routes.NewRouter(r, gin.HandlersChain{pass}, func(string) gin.HandlerFunc { return pass }, pass,
+			handlers.NewUserHandlerImpl(new(mocks.MockUserService)),
+			handlers.NewProductHandler(new(mocks.MockProductService), validator),
+			handlers.NewLocationHandler(new(mocks.MockLocationService), validator),
+			handlers.NewOrderHandler(new(mocks.MockOrderService), validator),
+			handlers.NewKeyHandler(),
+			handlers.NewRoleHandler(new(mocks.MockPermissionService)),
+			handlers.NewServiceAccountHandler(new(mocks.MockServiceAccountService)),
+			handlers.NewOIDCHandler(new(mocks.MockOIDCService)),
+			handlers.NewAuditHandler(new(mocks.MockAuditService)),
+		).Register()
+
+		for _, route := range r.Routes() {
+			assert.NotEqual(t, "/metrics", route.Path)
+		}
+
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Served By The Metrics Listener", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
+		routes.MetricsHandler().ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "go_goroutines")
+
+		recorder = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, "/api/v1/products", nil)
+		routes.MetricsHandler().ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
@@ -0,0 +1,71 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/handlers"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAccountHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockServiceAccountService := new(mocks.MockServiceAccountService)
+	handler := handlers.NewServiceAccountHandler(mockServiceAccountService)
+
+	adminID := uuid.New()
+	accountID := uuid.New()
+
+	t.Run("CreateAPIKey_Success", func(t *testing.T) {
+		request := &dto.APIKeyRequest{Name: "conveyor", Scopes: []string{dto.PermissionOrderReceive}}
+		key := &dto.APIKeyResponse{APIKey: dto.APIKey{ID: uuid.New(), Prefix: "wms_abcdefgh"}, Key: "wms_abcdefgh-secret"}
+		mockServiceAccountService.On("CreateAPIKey", adminID, accountID, request).Return(key, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "service_account_id", Value: accountID.String()}}
+		ctx.Set("user", &utils.CustomClaims{ID: adminID, Role: dto.UserRoleAdmin})
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/service-accounts/"+accountID.String()+"/keys", bytes.NewBufferString(`{"name":"conveyor","scopes":["order:receive"]}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.CreateAPIKey(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "wms_abcdefgh-secret")
+		assert.NotContains(t, recorder.Body.String(), "key_hash")
+		mockServiceAccountService.AssertExpectations(t)
+	})
+
+	t.Run("CreateAPIKey_NoScopes", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "service_account_id", Value: accountID.String()}}
+		ctx.Set("user", &utils.CustomClaims{ID: adminID, Role: dto.UserRoleAdmin})
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/service-accounts/"+accountID.String()+"/keys", bytes.NewBufferString(`{"name":"conveyor","scopes":[]}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.CreateAPIKey(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("RevokeAPIKey_NotUUID", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Params = gin.Params{{Key: "service_account_id", Value: accountID.String()}, {Key: "key_id", Value: "nope"}}
+		ctx.Request, _ = http.NewRequest(http.MethodDelete, "/api/v1/service-accounts/"+accountID.String()+"/keys/nope", nil)
+
+		handler.RevokeAPIKey(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
@@ -9,12 +9,14 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/handlers"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/utils"
 	"github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestUserHandler(t *testing.T) {
@@ -27,10 +29,9 @@ func TestUserHandler(t *testing.T) {
 			Name:     "testuser",
 			Password: "password123",
 			Email:    "test@example.com",
-			Role:     dto.UserRoleAdmin,
 		}
 
-		mockUserService.On("Register", &reqBody).Return(201, nil).Once()
+		mockUserService.On("Register", mock.Anything, &reqBody).Return(nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -54,7 +55,7 @@ func TestUserHandler(t *testing.T) {
 		}
 		mockToken := "mock_token"
 
-		mockUserService.On("Login", &reqBody).Return(mockToken, 200, nil).Once()
+		mockUserService.On("Login", &reqBody, mock.Anything).Return(&dto.TokenResponse{AccessToken: mockToken, RefreshToken: "mock_refresh"}, nil, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -75,7 +76,7 @@ func TestUserHandler(t *testing.T) {
 		userID := uuid.New()
 		userResponse := &dto.User{ID: userID, Name: "testuser", Email: "test@test.com"}
 
-		mockUserService.On("GetUserByID", userID).Return(userResponse, 200, nil).Once()
+		mockUserService.On("GetUserByID", userID).Return(userResponse, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -99,7 +100,7 @@ func TestUserHandler(t *testing.T) {
 			{ID: uuid.New(), Name: "user2"},
 		}
 
-		mockUserService.On("GetAllUser", pagination).Return(mockUsers, 200, nil).Once()
+		mockUserService.On("GetAllUser", pagination).Return(mockUsers, &web.PageInfo{TotalItems: int64(len(mockUsers))}, nil).Once()
 
 		recorder := httptest.NewRecorder()
 		ctx, _ := gin.CreateTestContext(recorder)
@@ -114,4 +115,248 @@ func TestUserHandler(t *testing.T) {
 		assert.Contains(t, recorder.Body.String(), "user2")
 		mockUserService.AssertExpectations(t)
 	})
+
+	t.Run("SetLocationScope_Success", func(t *testing.T) {
+		userID := uuid.New()
+		scope := &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}
+		user := &dto.User{ID: userID, Name: "picker", Scope: *scope}
+
+		mockUserService.On("SetLocationScope", mock.Anything, userID, scope).Return(user, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(scope)
+		req, _ := http.NewRequest(http.MethodPut, "/api/v1/users/"+userID.String()+"/locations", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		ctx.Request = req
+		ctx.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
+
+		handler.SetLocationScope(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), scope.LocationIDs[0].String())
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("SetLocationScope_NotUUID", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPut, "/api/v1/users/abc/locations", bytes.NewBufferString(`{}`))
+		ctx.Params = gin.Params{{Key: "user_id", Value: "abc"}}
+
+		handler.SetLocationScope(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("UpdateUser_Success", func(t *testing.T) {
+		userID := uuid.New()
+		request := &dto.UserUpdateRequest{Email: "lead@example.com", Name: "Lead", Role: dto.UserRoleAdmin}
+		mockUserService.On("UpdateUser", mock.Anything, userID, request).Return(&dto.User{ID: userID, Name: "Lead", Role: dto.UserRoleAdmin}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(request)
+		ctx.Request, _ = http.NewRequest(http.MethodPut, "/api/v1/users/"+userID.String(), bytes.NewBuffer(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
+
+		handler.UpdateUser(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "Lead")
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("UpdateUser_InvalidEmail", func(t *testing.T) {
+		userID := uuid.New()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPut, "/api/v1/users/"+userID.String(), bytes.NewBufferString(`{"email":"nope","name":"x","role":"staff"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
+
+		handler.UpdateUser(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("DeactivateUser_Success", func(t *testing.T) {
+		userID := uuid.New()
+		admin := &utils.CustomClaims{ID: uuid.New(), Role: dto.UserRoleAdmin}
+		mockUserService.On("SetActive", mock.MatchedBy(func(actor *dto.AuditActor) bool { return actor.UserID == admin.ID }), userID, false).Return(nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/users/"+userID.String()+"/deactivate", nil)
+		ctx.Params = gin.Params{{Key: "user_id", Value: userID.String()}}
+		ctx.Set("user", admin)
+
+		handler.DeactivateUser(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("ChangePassword_Unauthorized", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewBufferString(`{}`))
+
+		handler.ChangePassword(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("ChangePassword_Success", func(t *testing.T) {
+		claims := &utils.CustomClaims{ID: uuid.New()}
+		request := &dto.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "new-password"}
+		mockUserService.On("ChangePassword", mock.Anything, claims.ID, request).Return(nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(request)
+		ctx.Request, _ = http.NewRequest(http.MethodPut, "/api/v1/users/me/password", bytes.NewBuffer(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Set("user", claims)
+
+		handler.ChangePassword(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("ResetPassword_MissingToken", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/auth/password-reset", bytes.NewBufferString(`{"new_password":"correct-horse-battery"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.ResetPassword(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Register_IgnoresRole", func(t *testing.T) {
+		mockUserService.On("Register", mock.Anything, &dto.RegisterRequest{Email: "sneaky@example.com", Password: "password123", Name: "sneaky"}).Return(nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/register", bytes.NewBufferString(`{"email":"sneaky@example.com","password":"password123","name":"sneaky","role":"admin"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Register(ctx)
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("CreateInvite_Success", func(t *testing.T) {
+		admin := &utils.CustomClaims{ID: uuid.New(), Role: dto.UserRoleAdmin}
+		request := &dto.InviteRequest{Role: dto.UserRoleStaff}
+		mockUserService.On("CreateInvite", admin.ID, request).Return(&dto.InviteResponse{InviteCode: "invite-code", Role: dto.UserRoleStaff}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		body, _ := json.Marshal(request)
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/invites", bytes.NewBuffer(body))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Set("user", admin)
+
+		handler.CreateInvite(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "invite-code")
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("Login_TooManyAttempts", func(t *testing.T) {
+		mockUserService.On("Login", mock.Anything, "198.51.100.4").Return(nil, nil, domain.TooManyRequests("too_many_attempts", "too many failed login attempts, try again later")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewBufferString(`{"email":"a@example.com","password":"guess"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+		ctx.Request.RemoteAddr = "198.51.100.4:51234"
+
+		handler.Login(ctx)
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("ListAuthEvents_Filter", func(t *testing.T) {
+		success := false
+		filter := &dto.AuthEventFilter{Email: "a@example.com", Success: &success}
+		events := []*dto.AuthEvent{{ID: uuid.New(), Event: dto.AuthEventLogin, Email: "a@example.com", Reason: dto.AuthReasonWrongPassword}}
+		mockUserService.On("GetAuthEvents", filter, mock.Anything).Return(events, &web.PageInfo{TotalItems: 1}, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth-events?email=a@example.com&success=false", nil)
+
+		handler.ListAuthEvents(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "wrong_password")
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("ListAuthEvents_BadSuccess", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodGet, "/api/v1/auth-events?success=maybe", nil)
+
+		handler.ListAuthEvents(ctx)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Login_MFAChallenge", func(t *testing.T) {
+		challenge := &dto.MFAChallengeResponse{MFAToken: "mfa-token", ExpiresIn: 300}
+		mockUserService.On("Login", mock.Anything, mock.Anything).Return(nil, challenge, nil).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/login", bytes.NewBufferString(`{"email":"a@example.com","password":"password123"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.Login(ctx)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "mfa-token")
+		assert.NotContains(t, recorder.Body.String(), "access_token")
+		mockUserService.AssertExpectations(t)
+	})
+
+	t.Run("VerifyMFA_WrongCode", func(t *testing.T) {
+		request := &dto.MFAVerifyRequest{MFAToken: "mfa-token", Code: "000000"}
+		mockUserService.On("VerifyMFA", mock.Anything, request).Return(nil, domain.Unauthorized("invalid_mfa_code", "invalid two-factor code")).Once()
+
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+
+		ctx.Request, _ = http.NewRequest(http.MethodPost, "/api/v1/auth/mfa/verify", bytes.NewBufferString(`{"mfa_token":"mfa-token","code":"000000"}`))
+		ctx.Request.Header.Set("Content-Type", "application/json")
+
+		handler.VerifyMFA(ctx)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		mockUserService.AssertExpectations(t)
+	})
 }
@@ -0,0 +1,93 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockServiceAccounts := new(mocks.MockServiceAccountService)
+	mockPermissionService := new(mocks.MockPermissionService)
+	authorize := middlewares.PermissionMiddleware(mockPermissionService)
+
+	account := dto.User{ID: uuid.New(), Name: "ERP", Role: dto.UserRoleStaff, ServiceAccount: true}
+	identity := &dto.APIKeyIdentity{KeyID: uuid.New(), Scopes: []string{dto.PermissionOrderReceive}, User: account}
+
+	var actor *utils.CustomClaims
+
+	r := gin.New()
+	r.Use(middlewares.APIKeyMiddleware(mockServiceAccounts))
+	r.POST("/orders/receive", authorize(dto.PermissionOrderReceive), func(c *gin.Context) {
+		user, _ := c.Get("user")
+		actor = user.(*utils.CustomClaims)
+		c.Status(http.StatusOK)
+	})
+	r.POST("/orders/ship", authorize(dto.PermissionOrderShip), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	r.PUT("/users/me/password", middlewares.AuthMiddleware(), middlewares.UserOnlyMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(method string, path string, header string, value string) int {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		r.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	t.Run("Header Key Is Attributed To The Account", func(t *testing.T) {
+		mockServiceAccounts.On("AuthenticateAPIKey", "wms_key").Return(identity, nil).Once()
+		mockPermissionService.On("HasPermission", dto.UserRoleStaff, dto.PermissionOrderReceive).Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/orders/receive", "X-API-Key", "wms_key"))
+		assert.Equal(t, account.ID, actor.ID)
+		assert.Equal(t, identity.KeyID, actor.APIKeyID)
+	})
+
+	t.Run("Bearer Key", func(t *testing.T) {
+		mockServiceAccounts.On("AuthenticateAPIKey", "wms_key").Return(identity, nil).Once()
+		mockPermissionService.On("HasPermission", dto.UserRoleStaff, dto.PermissionOrderReceive).Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/orders/receive", "Authorization", "Bearer wms_key"))
+	})
+
+	t.Run("Outside Key Scope", func(t *testing.T) {
+		mockServiceAccounts.On("AuthenticateAPIKey", "wms_key").Return(identity, nil).Once()
+		mockPermissionService.On("HasPermission", dto.UserRoleStaff, dto.PermissionOrderShip).Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPost, "/orders/ship", "X-API-Key", "wms_key"))
+	})
+
+	t.Run("Revoked Key", func(t *testing.T) {
+		mockServiceAccounts.On("AuthenticateAPIKey", "wms_revoked").Return(nil, domain.ErrNotFound).Once()
+
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/orders/receive", "X-API-Key", "wms_revoked"))
+	})
+
+	t.Run("Not Allowed To Change Password", func(t *testing.T) {
+		mockServiceAccounts.On("AuthenticateAPIKey", "wms_key").Return(identity, nil).Once()
+
+		assert.Equal(t, http.StatusForbidden, serve(http.MethodPut, "/users/me/password", "X-API-Key", "wms_key"))
+	})
+
+	t.Run("Other Bearer Tokens Are Ignored", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, "/orders/receive", "Authorization", "Bearer eyJhbGciOi"))
+		mockServiceAccounts.AssertExpectations(t)
+		mockPermissionService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,143 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockIdempotency := new(mocks.MockIdempotencyService)
+
+	userID := uuid.New()
+	calls := 0
+	status := http.StatusCreated
+
+	r := gin.New()
+	r.Use(gin.CustomRecovery(func(c *gin.Context, _ any) {
+		c.AbortWithStatus(http.StatusInternalServerError)
+	}))
+	r.Use(func(c *gin.Context) {
+		c.Set("user", &utils.CustomClaims{ID: userID})
+	})
+	r.POST("/orders/receive", middlewares.IdempotencyMiddleware(mockIdempotency), func(c *gin.Context) {
+		calls++
+		if status == http.StatusTeapot {
+			panic("boom")
+		}
+		c.Header("Location", "/orders/1")
+		c.JSON(status, gin.H{"calls": calls})
+	})
+
+	serve := func(key string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/orders/receive", strings.NewReader(`{"quantity":1}`))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		r.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("No Key Runs Normally", func(t *testing.T) {
+		calls = 0
+
+		recorder := serve("")
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("First Request Stores Its Response", func(t *testing.T) {
+		calls = 0
+		mockIdempotency.On("Begin", userID, "first", mock.Anything).Return(nil, nil).Once()
+		mockIdempotency.On("Complete", userID, "first", http.StatusCreated, "application/json; charset=utf-8", map[string]string{"Location": "/orders/1"}, []byte(`{"calls":1}`)).Return(nil).Once()
+
+		recorder := serve("first")
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Retry Is Replayed Without Running The Handler", func(t *testing.T) {
+		calls = 0
+		stored := &dto.IdempotencyKey{StatusCode: http.StatusCreated, ContentType: "application/json; charset=utf-8", Headers: map[string]string{"Location": "/orders/1"}, ResponseBody: []byte(`{"calls":1}`)}
+		mockIdempotency.On("Begin", userID, "first", mock.Anything).Return(stored, nil).Once()
+
+		recorder := serve("first")
+
+		assert.Equal(t, http.StatusCreated, recorder.Code)
+		assert.Equal(t, `{"calls":1}`, recorder.Body.String())
+		assert.Equal(t, "true", recorder.Header().Get("Idempotent-Replayed"))
+		assert.Equal(t, "/orders/1", recorder.Header().Get("Location"))
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("Reused Key Is Rejected", func(t *testing.T) {
+		calls = 0
+		mockIdempotency.On("Begin", userID, "reused", mock.Anything).Return(nil, domain.Unprocessable("idempotency_key_reused", "reused")).Once()
+
+		recorder := serve("reused")
+
+		assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+		assert.Equal(t, 0, calls)
+	})
+
+	t.Run("Server Error Releases The Key", func(t *testing.T) {
+		calls = 0
+		status = http.StatusInternalServerError
+		defer func() { status = http.StatusCreated }()
+		mockIdempotency.On("Begin", userID, "failed", mock.Anything).Return(nil, nil).Once()
+		mockIdempotency.On("Release", userID, "failed").Return(nil).Once()
+
+		recorder := serve("failed")
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Panic Releases The Key", func(t *testing.T) {
+		calls = 0
+		status = http.StatusTeapot
+		defer func() { status = http.StatusCreated }()
+		mockIdempotency.On("Begin", userID, "panicked", mock.Anything).Return(nil, nil).Once()
+		mockIdempotency.On("Release", userID, "panicked").Return(nil).Once()
+
+		recorder := serve("panicked")
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("Key Too Long", func(t *testing.T) {
+		recorder := serve(strings.Repeat("k", 256))
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("Body Too Large", func(t *testing.T) {
+		calls = 0
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/orders/receive", strings.NewReader(`{"note":"`+strings.Repeat("x", 1<<20)+`"}`))
+		req.Header.Set("Idempotency-Key", "key-large")
+		r.ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+		assert.Equal(t, 0, calls)
+		mockIdempotency.AssertNotCalled(t, "Begin", userID, "key-large", mock.Anything)
+	})
+
+	mockIdempotency.AssertExpectations(t)
+}
@@ -0,0 +1,72 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestJWTMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	assert.NoError(t, utils.LoadKeys(config.JWTConfig{SecretKey: "test-secret"}))
+
+	mockUserService := new(mocks.MockUserService)
+
+	r := gin.New()
+	r.Use(middlewares.JWTMiddleware(mockUserService))
+	r.GET("/me", middlewares.AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(userID uuid.UUID) int {
+		token, err := utils.GenerateToken(&utils.CustomClaims{ID: userID, Role: dto.UserRoleStaff})
+		assert.NoError(t, err)
+
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		r.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	t.Run("Active User", func(t *testing.T) {
+		userID := uuid.New()
+		mockUserService.On("IsTokenRevoked", mock.Anything).Return(false, nil).Once()
+		mockUserService.On("IsUserActive", userID, dto.UserRoleStaff).Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusOK, serve(userID))
+	})
+
+	t.Run("Deactivated User", func(t *testing.T) {
+		userID := uuid.New()
+		mockUserService.On("IsTokenRevoked", mock.Anything).Return(false, nil).Once()
+		mockUserService.On("IsUserActive", userID, dto.UserRoleStaff).Return(false, nil).Once()
+
+		assert.Equal(t, http.StatusUnauthorized, serve(userID))
+	})
+
+	t.Run("Role Changed Since Token Was Issued", func(t *testing.T) {
+		userID := uuid.New()
+		mockUserService.On("IsTokenRevoked", mock.Anything).Return(false, nil).Once()
+		mockUserService.On("IsUserActive", userID, dto.UserRoleStaff).Return(false, nil).Once()
+
+		assert.Equal(t, http.StatusUnauthorized, serve(userID))
+	})
+
+	t.Run("Revoked Token", func(t *testing.T) {
+		mockUserService.On("IsTokenRevoked", mock.Anything).Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusUnauthorized, serve(uuid.New()))
+		mockUserService.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,52 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	clientmodel "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(middlewares.MetricsMiddleware())
+	r.GET("/metrics-test/:id", func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	serve := func(path string) {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	serve("/metrics-test/1")
+	serve("/metrics-test/2")
+	serve("/metrics-test/missing")
+	serve("/metrics-test")
+
+	sampleCount := func(route string, status string) uint64 {
+		var metric clientmodel.Metric
+		utils.HTTPRequestDuration.WithLabelValues(http.MethodGet, route, status).(prometheus.Histogram).Write(&metric)
+		return metric.GetHistogram().GetSampleCount()
+	}
+
+	t.Run("Requests Are Grouped By Route And Status", func(t *testing.T) {
+		assert.Equal(t, uint64(2), sampleCount("/metrics-test/:id", "200"))
+		assert.Equal(t, uint64(1), sampleCount("/metrics-test/:id", "404"))
+	})
+
+	t.Run("Unknown Paths Share One Route", func(t *testing.T) {
+		assert.Equal(t, uint64(1), sampleCount("unmatched", "404"))
+	})
+}
@@ -0,0 +1,71 @@
+package middlewares_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	mockPermissionService := new(mocks.MockPermissionService)
+	authorize := middlewares.PermissionMiddleware(mockPermissionService)
+
+	newRouter := func(user *utils.CustomClaims) *gin.Engine {
+		r := gin.New()
+		r.Use(func(c *gin.Context) {
+			if user != nil {
+				c.Set("user", user)
+			}
+		})
+		r.POST("/orders/ship", authorize(dto.PermissionOrderShip), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		return r
+	}
+
+	serve := func(r *gin.Engine) int {
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/orders/ship", nil)
+		r.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	t.Run("Granted", func(t *testing.T) {
+		mockPermissionService.On("HasPermission", dto.UserRoleStaff, dto.PermissionOrderShip).Return(true, nil).Once()
+
+		assert.Equal(t, http.StatusOK, serve(newRouter(&utils.CustomClaims{Role: dto.UserRoleStaff})))
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("Denied", func(t *testing.T) {
+		mockPermissionService.On("HasPermission", dto.UserRoleAdmin, dto.PermissionOrderShip).Return(false, nil).Once()
+
+		assert.Equal(t, http.StatusForbidden, serve(newRouter(&utils.CustomClaims{Role: dto.UserRoleAdmin})))
+		mockPermissionService.AssertExpectations(t)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, serve(newRouter(nil)))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		mockPermissionService.On("HasPermission", dto.UserRoleStaff, dto.PermissionOrderShip).Return(false, errors.New("db error")).Once()
+
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/orders/ship", nil)
+		newRouter(&utils.CustomClaims{Role: dto.UserRoleStaff}).ServeHTTP(recorder, req)
+
+		assert.Equal(t, http.StatusInternalServerError, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), "db error")
+		assert.Contains(t, recorder.Body.String(), "internal_error")
+	})
+}
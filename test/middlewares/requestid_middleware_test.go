@@ -0,0 +1,83 @@
+package middlewares_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/middlewares"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var output bytes.Buffer
+	var requestID string
+
+	r := gin.New()
+	r.Use(middlewares.RequestIDMiddleware(utils.NewLogger(&output, slog.LevelInfo)), middlewares.LoggerMiddleware())
+	r.GET("/products/:product_id", func(c *gin.Context) {
+		requestID = utils.RequestID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	r.GET("/fail", func(c *gin.Context) {
+		c.Error(assert.AnError)
+		c.Status(http.StatusInternalServerError)
+	})
+
+	serve := func(path string, header string) (*httptest.ResponseRecorder, map[string]any) {
+		output.Reset()
+		recorder := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if header != "" {
+			req.Header.Set(middlewares.RequestIDHeader, header)
+		}
+		r.ServeHTTP(recorder, req)
+
+		var line map[string]any
+		json.Unmarshal(output.Bytes(), &line)
+		return recorder, line
+	}
+
+	t.Run("Client ID Is Kept", func(t *testing.T) {
+		recorder, line := serve("/products/42", "trace-1:abc")
+
+		assert.Equal(t, "trace-1:abc", recorder.Header().Get(middlewares.RequestIDHeader))
+		assert.Equal(t, "trace-1:abc", requestID)
+		assert.Equal(t, "trace-1:abc", line["request_id"])
+		assert.Equal(t, "INFO", line["level"])
+		assert.Equal(t, "/products/:product_id", line["route"])
+		assert.Equal(t, float64(http.StatusOK), line["status"])
+	})
+
+	t.Run("Missing ID Is Generated", func(t *testing.T) {
+		recorder, line := serve("/products/42", "")
+
+		_, err := uuid.Parse(requestID)
+		assert.NoError(t, err)
+		assert.Equal(t, requestID, recorder.Header().Get(middlewares.RequestIDHeader))
+		assert.Equal(t, requestID, line["request_id"])
+	})
+
+	t.Run("Unsafe ID Is Replaced", func(t *testing.T) {
+		recorder, _ := serve("/products/42", "bad id\"}")
+
+		assert.NotEqual(t, "bad id\"}", requestID)
+		assert.Equal(t, requestID, recorder.Header().Get(middlewares.RequestIDHeader))
+	})
+
+	t.Run("Server Error Is Logged With Its Cause", func(t *testing.T) {
+		_, line := serve("/fail", "trace-2")
+
+		assert.Equal(t, "ERROR", line["level"])
+		assert.Equal(t, "trace-2", line["request_id"])
+		assert.Equal(t, []any{assert.AnError.Error()}, line["errors"])
+	})
+}
@@ -0,0 +1,47 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+// Transaction runs fn with a nil tx unless the expectation returns an error,
+// so the repository calls inside it can be asserted.
+func (m *MockAuditRepository) Transaction(ctx context.Context, actor *dto.AuditActor, fn func(tx *sqlx.Tx) error) error {
+	args := m.Called(actor, fn)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(nil)
+}
+
+func (m *MockAuditRepository) TagWithTransaction(ctx context.Context, tx *sqlx.Tx, actor *dto.AuditActor) error {
+	args := m.Called(tx, actor)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) GetAll(ctx context.Context, filter *dto.AuditLogFilter, pagination *web.PaginationRequest) ([]*dto.AuditLog, *web.PageInfo, error) {
+	args := m.Called(filter, pagination)
+	logs, _ := args.Get(0).([]*dto.AuditLog)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return logs, page, args.Error(2)
+}
+
+func (m *MockAuditService) GetAll(ctx context.Context, filter *dto.AuditLogFilter, pagination *web.PaginationRequest) ([]*dto.AuditLog, *web.PageInfo, error) {
+	args := m.Called(filter, pagination)
+	logs, _ := args.Get(0).([]*dto.AuditLog)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return logs, page, args.Error(2)
+}
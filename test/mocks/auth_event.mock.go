@@ -0,0 +1,33 @@
+package mocks
+
+import (
+	"context"
+
+	"time"
+
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuthEventRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuthEventRepository) SaveAuthEvent(ctx context.Context, event *dto.AuthEvent) error {
+	args := m.Called(event)
+	return args.Error(0)
+}
+
+func (m *MockAuthEventRepository) CountLoginFailures(ctx context.Context, email string, ip string, since time.Time) (*dto.LoginFailures, error) {
+	args := m.Called(email, ip, since)
+	failures, _ := args.Get(0).(*dto.LoginFailures)
+	return failures, args.Error(1)
+}
+
+func (m *MockAuthEventRepository) GetAll(ctx context.Context, filter *dto.AuthEventFilter, pagination *web.PaginationRequest) ([]*dto.AuthEvent, *web.PageInfo, error) {
+	args := m.Called(filter, pagination)
+	events, _ := args.Get(0).([]*dto.AuthEvent)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return events, page, args.Error(2)
+}
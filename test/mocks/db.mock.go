@@ -0,0 +1,99 @@
+package mocks
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// FailingDB is a database whose statements run but fail with err once their
+// results are read: RowsAffected and the first row both report it. It checks
+// how a real repository reports errors that surface after the call, and keeps
+// the statements it was sent.
+type FailingDB struct {
+	*sqlx.DB
+	Queries []string
+	err     error
+}
+
+func NewFailingDB(err error) *FailingDB {
+	db := &FailingDB{err: err}
+	db.DB = sqlx.NewDb(sql.OpenDB(failingConnector{db: db}), "postgres")
+	return db
+}
+
+type failingConnector struct {
+	db *FailingDB
+}
+
+func (c failingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return failingConn{db: c.db}, nil
+}
+
+func (c failingConnector) Driver() driver.Driver {
+	return failingDriver{db: c.db}
+}
+
+type failingDriver struct {
+	db *FailingDB
+}
+
+func (d failingDriver) Open(name string) (driver.Conn, error) {
+	return failingConn{db: d.db}, nil
+}
+
+type failingConn struct {
+	db *FailingDB
+}
+
+func (c failingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, c.db.err
+}
+
+func (c failingConn) Close() error {
+	return nil
+}
+
+func (c failingConn) Begin() (driver.Tx, error) {
+	return nil, c.db.err
+}
+
+func (c failingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.Queries = append(c.db.Queries, query)
+	return failingResult{err: c.db.err}, nil
+}
+
+func (c failingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.db.Queries = append(c.db.Queries, query)
+	return failingRows{err: c.db.err}, nil
+}
+
+type failingResult struct {
+	err error
+}
+
+func (r failingResult) LastInsertId() (int64, error) {
+	return 0, r.err
+}
+
+func (r failingResult) RowsAffected() (int64, error) {
+	return 0, r.err
+}
+
+type failingRows struct {
+	err error
+}
+
+func (r failingRows) Columns() []string {
+	return nil
+}
+
+func (r failingRows) Close() error {
+	return nil
+}
+
+func (r failingRows) Next(dest []driver.Value) error {
+	return r.err
+}
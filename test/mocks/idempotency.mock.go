@@ -0,0 +1,64 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) ReserveIdempotencyKey(ctx context.Context, key *dto.IdempotencyKey) (bool, error) {
+	args := m.Called(key)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) FindIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) (*dto.IdempotencyKey, error) {
+	args := m.Called(userID, key)
+	stored, _ := args.Get(0).(*dto.IdempotencyKey)
+	return stored, args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) SaveIdempotencyResponse(ctx context.Context, key *dto.IdempotencyKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteIdempotencyKey(ctx context.Context, userID uuid.UUID, key string) error {
+	args := m.Called(userID, key)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+type MockIdempotencyService struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyService) Begin(ctx context.Context, userID uuid.UUID, key string, requestHash string) (*dto.IdempotencyKey, error) {
+	args := m.Called(userID, key, requestHash)
+	stored, _ := args.Get(0).(*dto.IdempotencyKey)
+	return stored, args.Error(1)
+}
+
+func (m *MockIdempotencyService) Complete(ctx context.Context, userID uuid.UUID, key string, statusCode int, contentType string, headers map[string]string, body []byte) error {
+	args := m.Called(userID, key, statusCode, contentType, headers, body)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	args := m.Called(userID, key)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
@@ -0,0 +1,209 @@
+package mocks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+)
+
+// StandInIdP is a minimal OpenID Connect provider for tests. Its authorize
+// endpoint signs the configured user in straight away, and its token
+// endpoint checks the PKCE verifier before handing out an ID token.
+type StandInIdP struct {
+	Server   *httptest.Server
+	ClientID string
+
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+
+	// Overrides is applied to ID tokens from the token endpoint, to hand
+	// out tokens a client should reject.
+	Overrides jwt.MapClaims
+
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]standInGrant
+}
+
+type standInGrant struct {
+	challenge   string
+	nonce       string
+	redirectURI string
+}
+
+const standInKeyID = "stand-in"
+
+func NewStandInIdP(clientID string) *StandInIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+
+	idp := &StandInIdP{
+		ClientID:      clientID,
+		Subject:       "stand-in-user",
+		Email:         "jane@example.com",
+		EmailVerified: true,
+		Name:          "Jane",
+		key:           key,
+		codes:         make(map[string]standInGrant),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	mux.HandleFunc("/jwks", idp.jwks)
+	idp.Server = httptest.NewServer(mux)
+
+	return idp
+}
+
+func (idp *StandInIdP) URL() string {
+	return idp.Server.URL
+}
+
+func (idp *StandInIdP) Close() {
+	idp.Server.Close()
+}
+
+// Authorize follows an authorization URL the way a browser would and returns
+// the code and state from the redirect back to the client.
+func (idp *StandInIdP) Authorize(authURL string) (code string, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", "", err
+	}
+
+	return location.Query().Get("code"), location.Query().Get("state"), nil
+}
+
+// IDToken signs an ID token for the configured user with the given claims
+// overriding the defaults.
+func (idp *StandInIdP) IDToken(overrides jwt.MapClaims) string {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            idp.URL(),
+		"aud":            idp.ClientID,
+		"sub":            idp.Subject,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"email":          idp.Email,
+		"email_verified": idp.EmailVerified,
+		"name":           idp.Name,
+		"groups":         idp.Groups,
+	}
+
+	for name, value := range overrides {
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = standInKeyID
+
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		panic(err)
+	}
+
+	return signed
+}
+
+func (idp *StandInIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 idp.URL(),
+		"authorization_endpoint": idp.URL() + "/authorize",
+		"token_endpoint":         idp.URL() + "/token",
+		"jwks_uri":               idp.URL() + "/jwks",
+	})
+}
+
+func (idp *StandInIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != idp.ClientID || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code, _ := utils.GenerateOpaqueToken()
+
+	idp.mu.Lock()
+	idp.codes[code] = standInGrant{
+		challenge:   query.Get("code_challenge"),
+		nonce:       query.Get("nonce"),
+		redirectURI: query.Get("redirect_uri"),
+	}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {query.Get("state")}}.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (idp *StandInIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	idp.mu.Unlock()
+
+	if !ok || r.PostForm.Get("redirect_uri") != grant.redirectURI || utils.PKCEChallenge(r.PostForm.Get("code_verifier")) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := jwt.MapClaims{"nonce": grant.nonce}
+	for name, value := range idp.Overrides {
+		claims[name] = value
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "stand-in-access-token",
+		"token_type":   "Bearer",
+		"id_token":     idp.IDToken(claims),
+	})
+}
+
+func (idp *StandInIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": standInKeyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}},
+	})
+}
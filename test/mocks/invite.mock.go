@@ -0,0 +1,25 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockInviteRepository struct {
+	mock.Mock
+}
+
+func (m *MockInviteRepository) SaveInvite(ctx context.Context, invite *dto.Invite) error {
+	args := m.Called(invite)
+	return args.Error(0)
+}
+
+func (m *MockInviteRepository) UseInviteWithTransaction(ctx context.Context, tx *sqlx.Tx, hash string, userID uuid.UUID) (*dto.Invite, error) {
+	args := m.Called(tx, hash, userID)
+	invite, _ := args.Get(0).(*dto.Invite)
+	return invite, args.Error(1)
+}
@@ -1,6 +1,10 @@
 package mocks
 
 import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/stretchr/testify/mock"
@@ -14,27 +18,94 @@ type MockLocationService struct {
 	mock.Mock
 }
 
-func (m *MockLocationRepository) Save(location *dto.Location) error {
-	args := m.Called(location)
-	return args.Error(0)
+func (m *MockLocationRepository) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, location *dto.Location) (*dto.Location, error) {
+	args := m.Called(tx, location)
+	saved, _ := args.Get(0).(*dto.Location)
+	return saved, args.Error(1)
+}
+
+func (m *MockLocationRepository) FindByID(ctx context.Context, id uuid.UUID) (*dto.Location, error) {
+	args := m.Called(id)
+	location, _ := args.Get(0).(*dto.Location)
+	return location, args.Error(1)
 }
 
-func (m *MockLocationRepository) FindByName(name string) (*dto.Location, int, error) {
+func (m *MockLocationRepository) FindByName(ctx context.Context, name string) (*dto.Location, error) {
 	args := m.Called(name)
-	return args.Get(0).(*dto.Location), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.Location), args.Error(1)
 }
 
-func (m *MockLocationRepository) GetAllLocation(pagination *web.PaginationRequest) ([]*dto.Location, error) {
-	args := m.Called(pagination)
-	return args.Get(0).([]*dto.Location), args.Error(1)
+func (m *MockLocationRepository) GetAllLocation(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Location, *web.PageInfo, error) {
+	args := m.Called(scope, pagination)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.Location), page, args.Error(2)
+}
+
+func (m *MockLocationService) Save(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, location *dto.Location) (*dto.Location, error) {
+	args := m.Called(scope, actor, location)
+	saved, _ := args.Get(0).(*dto.Location)
+	return saved, args.Error(1)
+}
+
+func (m *MockLocationService) GetByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Location, error) {
+	args := m.Called(scope, id)
+	location, _ := args.Get(0).(*dto.Location)
+	return location, args.Error(1)
+}
+
+func (m *MockLocationService) GetAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Location, *web.PageInfo, error) {
+	args := m.Called(scope, pagination)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.Location), page, args.Error(2)
+}
+
+func (m *MockLocationRepository) StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Location) error) error {
+	args := m.Called(scope, pagination, fn)
+	return args.Error(0)
 }
 
-func (m *MockLocationService) Save(location *dto.Location) (int, error) {
-	args := m.Called(location)
-	return args.Int(0), args.Error(1)
+func (m *MockLocationService) Export(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Location) error) error {
+	args := m.Called(scope, pagination, fn)
+	if items, ok := args.Get(0).([]*dto.Location); ok {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockLocationRepository) DeleteWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, deletedBy uuid.UUID) error {
+	args := m.Called(tx, id, deletedBy)
+	return args.Error(0)
+}
+
+func (m *MockLocationRepository) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]*dto.Location, error) {
+	args := m.Called(ids)
+	locations, _ := args.Get(0).([]*dto.Location)
+	return locations, args.Error(1)
+}
+
+func (m *MockLocationRepository) FindDeletedByID(ctx context.Context, id uuid.UUID) (*dto.Location, error) {
+	args := m.Called(id)
+	location, _ := args.Get(0).(*dto.Location)
+	return location, args.Error(1)
+}
+
+func (m *MockLocationRepository) RestoreWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*dto.Location, error) {
+	args := m.Called(tx, id)
+	location, _ := args.Get(0).(*dto.Location)
+	return location, args.Error(1)
+}
+
+func (m *MockLocationService) Delete(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) error {
+	args := m.Called(scope, actor, id)
+	return args.Error(0)
 }
 
-func (m *MockLocationService) GetAll(pagination *web.PaginationRequest) ([]*dto.Location, int, error) {
-	args := m.Called(pagination)
-	return args.Get(0).([]*dto.Location), args.Int(1), args.Error(2)
+func (m *MockLocationService) Restore(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) (*dto.Location, error) {
+	args := m.Called(scope, actor, id)
+	location, _ := args.Get(0).(*dto.Location)
+	return location, args.Error(1)
 }
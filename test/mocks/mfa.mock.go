@@ -0,0 +1,66 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) GetTOTP(ctx context.Context, userID uuid.UUID) (*dto.TOTPState, error) {
+	args := m.Called(userID)
+	state, _ := args.Get(0).(*dto.TOTPState)
+	return state, args.Error(1)
+}
+
+func (m *MockMFARepository) SetPendingTOTPSecretWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, secret string) error {
+	args := m.Called(tx, userID, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) EnableMFAWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, recoveryCodeHashes []string) error {
+	args := m.Called(tx, userID, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) DisableMFAWithTransaction(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) error {
+	args := m.Called(tx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	args := m.Called(userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, hash string) (bool, error) {
+	args := m.Called(userID, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) SaveChallenge(ctx context.Context, challenge *dto.MFAChallenge) error {
+	args := m.Called(challenge)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) FindChallenge(ctx context.Context, hash string, maxAttempts int) (*dto.MFAChallenge, error) {
+	args := m.Called(hash, maxAttempts)
+	challenge, _ := args.Get(0).(*dto.MFAChallenge)
+	return challenge, args.Error(1)
+}
+
+func (m *MockMFARepository) FailChallenge(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(id)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,50 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockOIDCRepository struct {
+	mock.Mock
+}
+
+func (m *MockOIDCRepository) SaveLoginState(ctx context.Context, state *dto.OIDCLoginState) error {
+	args := m.Called(state)
+	return args.Error(0)
+}
+
+func (m *MockOIDCRepository) UseLoginState(ctx context.Context, hash string) (*dto.OIDCLoginState, error) {
+	args := m.Called(hash)
+	state, _ := args.Get(0).(*dto.OIDCLoginState)
+	return state, args.Error(1)
+}
+
+func (m *MockOIDCRepository) FindUserIDBySubject(ctx context.Context, issuer string, subject string) (uuid.UUID, error) {
+	args := m.Called(issuer, subject)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockOIDCRepository) LinkIdentity(ctx context.Context, userID uuid.UUID, issuer string, subject string) error {
+	args := m.Called(userID, issuer, subject)
+	return args.Error(0)
+}
+
+type MockOIDCService struct {
+	mock.Mock
+}
+
+func (m *MockOIDCService) StartLogin(ctx context.Context) (string, error) {
+	args := m.Called()
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockOIDCService) CompleteLogin(ctx context.Context, actor *dto.AuditActor, code string, state string) (*dto.TokenResponse, *dto.MFAChallengeResponse, error) {
+	args := m.Called(ctx, actor, code, state)
+	tokens, _ := args.Get(0).(*dto.TokenResponse)
+	challenge, _ := args.Get(1).(*dto.MFAChallengeResponse)
+	return tokens, challenge, args.Error(2)
+}
@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
@@ -16,40 +18,73 @@ type MockOrderService struct {
 	mock.Mock
 }
 
-func (m *MockOrderRepository) SaveWithTransaction(tx *sqlx.Tx, order *dto.Order) error {
+func (m *MockOrderRepository) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, order *dto.Order) (*dto.Order, error) {
 	args := m.Called(tx, order)
+	saved, _ := args.Get(0).(*dto.Order)
+	return saved, args.Error(1)
+}
+
+func (m *MockOrderRepository) SaveBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, orders []*dto.Order) error {
+	args := m.Called(tx, orders)
 	return args.Error(0)
 }
 
-func (m *MockOrderRepository) FindAll(pagination *web.PaginationRequest) ([]*dto.Order, error) {
-	args := m.Called(pagination)
-	return args.Get(0).([]*dto.Order), args.Error(1)
+func (m *MockOrderRepository) FindAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Order, *web.PageInfo, error) {
+	args := m.Called(scope, pagination)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.Order), page, args.Error(2)
 }
 
-func (m *MockOrderRepository) FindByID(id uuid.UUID) (*dto.Order, int, error) {
+func (m *MockOrderRepository) FindByID(ctx context.Context, id uuid.UUID) (*dto.Order, error) {
 	args := m.Called(id)
-	return args.Get(0).(*dto.Order), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.Order), args.Error(1)
 }
 
-func (m *MockOrderService) ReceiveOrder(order *dto.OrderCreateRequest) (int, error) {
-	args := m.Called(order)
-	return args.Int(0), args.Error(1)
+func (m *MockOrderService) ReceiveOrder(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, order *dto.OrderCreateRequest) (*dto.Order, error) {
+	args := m.Called(scope, actor, order)
+	created, _ := args.Get(0).(*dto.Order)
+	return created, args.Error(1)
 }
 
-func (m *MockOrderService) ShipOrder(order *dto.OrderCreateRequest) (int, error) {
-	args := m.Called(order)
-	return args.Int(0), args.Error(1)
+func (m *MockOrderService) ShipOrder(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, order *dto.OrderCreateRequest) (*dto.Order, error) {
+	args := m.Called(scope, actor, order)
+	created, _ := args.Get(0).(*dto.Order)
+	return created, args.Error(1)
 }
 
-func (m *MockOrderService) GetAllOrders(pagination *web.PaginationRequest) ([]*dto.Order, int, error) {
-	args := m.Called(pagination)
-	return args.Get(0).([]*dto.Order), args.Int(1), args.Error(2)
+func (m *MockOrderService) BatchOrders(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, batch *dto.OrderBatchRequest) (*dto.OrderBatchResult, error) {
+	args := m.Called(scope, actor, batch)
+	result, _ := args.Get(0).(*dto.OrderBatchResult)
+	return result, args.Error(1)
 }
 
-func (m *MockOrderService) GetOrderByID(id uuid.UUID) (*dto.Order, int, error) {
-	args := m.Called(id)
+func (m *MockOrderService) GetAllOrders(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Order, *web.PageInfo, error) {
+	args := m.Called(scope, pagination)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.Order), page, args.Error(2)
+}
+
+func (m *MockOrderService) GetOrderByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Order, error) {
+	args := m.Called(scope, id)
 	if args.Get(0) != nil {
-		return args.Get(0).(*dto.Order), args.Int(1), args.Error(2)
+		return args.Get(0).(*dto.Order), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockOrderRepository) StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Order) error) error {
+	args := m.Called(scope, pagination, fn)
+	return args.Error(0)
+}
+
+func (m *MockOrderService) ExportOrders(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Order) error) error {
+	args := m.Called(scope, pagination, fn)
+	if items, ok := args.Get(0).([]*dto.Order); ok {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
 	}
-	return nil, args.Int(1), args.Error(2)
+	return args.Error(1)
 }
@@ -0,0 +1,94 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPermissionRepository struct {
+	mock.Mock
+}
+
+func (m *MockPermissionRepository) ListPermissions(ctx context.Context) ([]*dto.Permission, error) {
+	args := m.Called()
+	permissions, _ := args.Get(0).([]*dto.Permission)
+	return permissions, args.Error(1)
+}
+
+func (m *MockPermissionRepository) ListRoles(ctx context.Context) ([]*dto.Role, error) {
+	args := m.Called()
+	roles, _ := args.Get(0).([]*dto.Role)
+	return roles, args.Error(1)
+}
+
+func (m *MockPermissionRepository) FindRole(ctx context.Context, name dto.UserRole) (*dto.Role, error) {
+	args := m.Called(name)
+	role, _ := args.Get(0).(*dto.Role)
+	return role, args.Error(1)
+}
+
+func (m *MockPermissionRepository) SaveRoleWithTransaction(ctx context.Context, tx *sqlx.Tx, role *dto.Role) error {
+	args := m.Called(tx, role)
+	return args.Error(0)
+}
+
+func (m *MockPermissionRepository) UpdateRoleWithTransaction(ctx context.Context, tx *sqlx.Tx, role *dto.Role) error {
+	args := m.Called(tx, role)
+	return args.Error(0)
+}
+
+func (m *MockPermissionRepository) ReplaceRolePermissionsWithTransaction(ctx context.Context, tx *sqlx.Tx, role dto.UserRole, permissions []string) error {
+	args := m.Called(tx, role, permissions)
+	return args.Error(0)
+}
+
+func (m *MockPermissionRepository) CountUsersWithRole(ctx context.Context, name dto.UserRole) (int64, error) {
+	args := m.Called(name)
+	count, _ := args.Get(0).(int64)
+	return count, args.Error(1)
+}
+
+func (m *MockPermissionRepository) DeleteRole(ctx context.Context, name dto.UserRole) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
+
+type MockPermissionService struct {
+	mock.Mock
+}
+
+func (m *MockPermissionService) HasPermission(ctx context.Context, role dto.UserRole, permission string) (bool, error) {
+	args := m.Called(role, permission)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPermissionService) ListPermissions(ctx context.Context) ([]*dto.Permission, error) {
+	args := m.Called()
+	permissions, _ := args.Get(0).([]*dto.Permission)
+	return permissions, args.Error(1)
+}
+
+func (m *MockPermissionService) ListRoles(ctx context.Context) ([]*dto.Role, error) {
+	args := m.Called()
+	roles, _ := args.Get(0).([]*dto.Role)
+	return roles, args.Error(1)
+}
+
+func (m *MockPermissionService) CreateRole(ctx context.Context, role *dto.Role) error {
+	args := m.Called(role)
+	return args.Error(0)
+}
+
+func (m *MockPermissionService) UpdateRole(ctx context.Context, name dto.UserRole, role *dto.RoleUpdateRequest) (*dto.Role, error) {
+	args := m.Called(name, role)
+	roleData, _ := args.Get(0).(*dto.Role)
+	return roleData, args.Error(1)
+}
+
+func (m *MockPermissionService) DeleteRole(ctx context.Context, name dto.UserRole) error {
+	args := m.Called(name)
+	return args.Error(0)
+}
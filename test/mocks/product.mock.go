@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
@@ -16,72 +18,151 @@ type MockProductService struct {
 	mock.Mock
 }
 
-func (m *MockProductRepository) Save(product *dto.Product) error {
-	args := m.Called(product)
-	return args.Error(0)
+func (m *MockProductRepository) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, product *dto.Product) (*dto.Product, error) {
+	args := m.Called(tx, product)
+	saved, _ := args.Get(0).(*dto.Product)
+	return saved, args.Error(1)
 }
 
-func (m *MockProductRepository) Update(product *dto.Product) error {
-	args := m.Called(product)
+func (m *MockProductRepository) UpdateWithTransaction(ctx context.Context, tx *sqlx.Tx, product *dto.Product) error {
+	args := m.Called(tx, product)
 	return args.Error(0)
 }
 
-func (m *MockProductRepository) FindByID(id uuid.UUID) (*dto.Product, int, error) {
+func (m *MockProductRepository) PatchWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, patch *dto.ProductPatch, version int64) (*dto.Product, error) {
+	args := m.Called(tx, id, patch, version)
+	product, _ := args.Get(0).(*dto.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductRepository) FindByID(ctx context.Context, id uuid.UUID) (*dto.Product, error) {
 	args := m.Called(id)
-	return args.Get(0).(*dto.Product), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) FindByName(name string) (*dto.Product, int, error) {
+func (m *MockProductRepository) FindByName(ctx context.Context, name string) (*dto.Product, error) {
 	args := m.Called(name)
-	return args.Get(0).(*dto.Product), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) FindBySKU(sku string) (*dto.Product, int, error) {
+func (m *MockProductRepository) FindBySKU(ctx context.Context, sku string) (*dto.Product, error) {
 	args := m.Called(sku)
-	return args.Get(0).(*dto.Product), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) GetAllProduct(pagination *web.PaginationRequest) ([]*dto.Product, error) {
-	args := m.Called(pagination)
+func (m *MockProductRepository) FindByNamesOrSKUs(ctx context.Context, names []string, skus []string) ([]*dto.Product, error) {
+	args := m.Called(names, skus)
 	return args.Get(0).([]*dto.Product), args.Error(1)
 }
 
-func (m *MockProductRepository) Delete(id uuid.UUID) (int, error) {
+func (m *MockProductRepository) GetAllProduct(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Product, *web.PageInfo, error) {
+	args := m.Called(scope, pagination)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.Product), page, args.Error(2)
+}
+
+func (m *MockProductRepository) DeleteWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, deletedBy uuid.UUID) error {
+	args := m.Called(tx, id, deletedBy)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) FindDeletedByID(ctx context.Context, id uuid.UUID) (*dto.Product, error) {
 	args := m.Called(id)
-	return args.Int(0), args.Error(1)
+	product, _ := args.Get(0).(*dto.Product)
+	return product, args.Error(1)
 }
 
-func (m *MockProductRepository) IncreaseStockWithTransaction(tx *sqlx.Tx, productID uuid.UUID, quantity int64) (int, error) {
+func (m *MockProductRepository) RestoreWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID) (*dto.Product, error) {
+	args := m.Called(tx, id)
+	product, _ := args.Get(0).(*dto.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductRepository) SaveBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, products []*dto.Product) error {
+	args := m.Called(tx, products)
+	return args.Error(0)
+}
+
+func (m *MockProductRepository) IncreaseStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productID uuid.UUID, quantity int64) error {
 	args := m.Called(tx, productID, quantity)
-	return args.Int(0), args.Error(1)
+	return args.Error(0)
 }
 
-func (m *MockProductRepository) DecreaseStockWithTransaction(tx *sqlx.Tx, productID uuid.UUID, quantity int64) (int, error) {
+func (m *MockProductRepository) DecreaseStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productID uuid.UUID, quantity int64) error {
 	args := m.Called(tx, productID, quantity)
-	return args.Int(0), args.Error(1)
+	return args.Error(0)
 }
 
-func (m *MockProductService) Create(product *dto.Product) (int, error) {
-	args := m.Called(product)
-	return args.Int(0), args.Error(1)
+func (m *MockProductRepository) LockStockWithTransaction(ctx context.Context, tx *sqlx.Tx, productIDs []uuid.UUID) (map[uuid.UUID]*dto.Product, error) {
+	args := m.Called(tx, productIDs)
+	products, _ := args.Get(0).(map[uuid.UUID]*dto.Product)
+	return products, args.Error(1)
 }
 
-func (m *MockProductService) GetAll(pagination *web.PaginationRequest) ([]*dto.Product, int, error) {
-	args := m.Called(pagination)
-	return args.Get(0).([]*dto.Product), args.Int(1), args.Error(2)
+func (m *MockProductRepository) AdjustStockBatchWithTransaction(ctx context.Context, tx *sqlx.Tx, deltas map[uuid.UUID]int64) error {
+	args := m.Called(tx, deltas)
+	return args.Error(0)
 }
 
-func (m *MockProductService) GetByID(id uuid.UUID) (*dto.Product, int, error) {
-	args := m.Called(id)
-	return args.Get(0).(*dto.Product), args.Int(1), args.Error(2)
+func (m *MockProductService) Create(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, product *dto.Product) (*dto.Product, error) {
+	args := m.Called(scope, actor, product)
+	created, _ := args.Get(0).(*dto.Product)
+	return created, args.Error(1)
 }
 
-func (m *MockProductService) Update(product *dto.Product) (int, error) {
-	args := m.Called(product)
-	return args.Int(0), args.Error(1)
+func (m *MockProductService) GetAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest) ([]*dto.Product, *web.PageInfo, error) {
+	args := m.Called(scope, pagination)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.Product), page, args.Error(2)
 }
 
-func (m *MockProductService) Delete(id uuid.UUID) (int, error) {
-	args := m.Called(id)
-	return args.Int(0), args.Error(1)
+func (m *MockProductService) GetByID(ctx context.Context, scope *dto.LocationScope, id uuid.UUID) (*dto.Product, error) {
+	args := m.Called(scope, id)
+	product, _ := args.Get(0).(*dto.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductService) Update(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, product *dto.Product) error {
+	args := m.Called(scope, actor, product)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Patch(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID, patch *dto.ProductPatch, version int64) (*dto.Product, error) {
+	args := m.Called(scope, actor, id, patch, version)
+	product, _ := args.Get(0).(*dto.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductService) Delete(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) error {
+	args := m.Called(scope, actor, id)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Restore(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, id uuid.UUID) (*dto.Product, error) {
+	args := m.Called(scope, actor, id)
+	product, _ := args.Get(0).(*dto.Product)
+	return product, args.Error(1)
+}
+
+func (m *MockProductService) Import(ctx context.Context, scope *dto.LocationScope, actor *dto.AuditActor, rows []*dto.ProductImportRow, dryRun bool) (*dto.ProductImportReport, error) {
+	args := m.Called(scope, actor, rows, dryRun)
+	report, _ := args.Get(0).(*dto.ProductImportReport)
+	return report, args.Error(1)
+}
+
+func (m *MockProductRepository) StreamAll(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Product) error) error {
+	args := m.Called(scope, pagination, fn)
+	return args.Error(0)
+}
+
+func (m *MockProductService) Export(ctx context.Context, scope *dto.LocationScope, pagination *web.PaginationRequest, fn func(*dto.Product) error) error {
+	args := m.Called(scope, pagination, fn)
+	if items, ok := args.Get(0).([]*dto.Product); ok {
+		for _, item := range items {
+			if err := fn(item); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
 }
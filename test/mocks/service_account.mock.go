@@ -0,0 +1,74 @@
+package mocks
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockServiceAccountRepository struct {
+	mock.Mock
+}
+
+func (m *MockServiceAccountRepository) SaveServiceAccountWithTransaction(ctx context.Context, tx *sqlx.Tx, account *dto.User) error {
+	args := m.Called(tx, account)
+	return args.Error(0)
+}
+
+func (m *MockServiceAccountRepository) SaveAPIKey(ctx context.Context, key *dto.APIKey) error {
+	args := m.Called(key)
+	return args.Error(0)
+}
+
+func (m *MockServiceAccountRepository) GetAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*dto.APIKey, error) {
+	args := m.Called(serviceAccountID)
+	keys, _ := args.Get(0).([]*dto.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *MockServiceAccountRepository) RevokeAPIKey(ctx context.Context, serviceAccountID uuid.UUID, keyID uuid.UUID) error {
+	args := m.Called(serviceAccountID, keyID)
+	return args.Error(0)
+}
+
+func (m *MockServiceAccountRepository) UseAPIKey(ctx context.Context, hash string) (*dto.APIKeyIdentity, error) {
+	args := m.Called(hash)
+	identity, _ := args.Get(0).(*dto.APIKeyIdentity)
+	return identity, args.Error(1)
+}
+
+type MockServiceAccountService struct {
+	mock.Mock
+}
+
+func (m *MockServiceAccountService) CreateServiceAccount(ctx context.Context, actor *dto.AuditActor, request *dto.ServiceAccountRequest) (*dto.User, error) {
+	args := m.Called(actor, request)
+	account, _ := args.Get(0).(*dto.User)
+	return account, args.Error(1)
+}
+
+func (m *MockServiceAccountService) CreateAPIKey(ctx context.Context, createdBy uuid.UUID, serviceAccountID uuid.UUID, request *dto.APIKeyRequest) (*dto.APIKeyResponse, error) {
+	args := m.Called(createdBy, serviceAccountID, request)
+	key, _ := args.Get(0).(*dto.APIKeyResponse)
+	return key, args.Error(1)
+}
+
+func (m *MockServiceAccountService) GetAPIKeys(ctx context.Context, serviceAccountID uuid.UUID) ([]*dto.APIKey, error) {
+	args := m.Called(serviceAccountID)
+	keys, _ := args.Get(0).([]*dto.APIKey)
+	return keys, args.Error(1)
+}
+
+func (m *MockServiceAccountService) RevokeAPIKey(ctx context.Context, serviceAccountID uuid.UUID, keyID uuid.UUID) error {
+	args := m.Called(serviceAccountID, keyID)
+	return args.Error(0)
+}
+
+func (m *MockServiceAccountService) AuthenticateAPIKey(ctx context.Context, key string) (*dto.APIKeyIdentity, error) {
+	args := m.Called(key)
+	identity, _ := args.Get(0).(*dto.APIKeyIdentity)
+	return identity, args.Error(1)
+}
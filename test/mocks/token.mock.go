@@ -0,0 +1,68 @@
+package mocks
+
+import (
+	"context"
+
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockTokenRepository) SaveRefreshToken(ctx context.Context, token *dto.RefreshToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) FindRefreshTokenByHash(ctx context.Context, hash string) (*dto.RefreshToken, error) {
+	args := m.Called(hash)
+	token, _ := args.Get(0).(*dto.RefreshToken)
+	return token, args.Error(1)
+}
+
+func (m *MockTokenRepository) RotateRefreshToken(ctx context.Context, id uuid.UUID, replacedBy uuid.UUID) (bool, error) {
+	args := m.Called(id, replacedBy)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	args := m.Called(familyID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) RevokeAccessToken(ctx context.Context, jti uuid.UUID, expiresAt time.Time) error {
+	args := m.Called(jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) IsAccessTokenRevoked(ctx context.Context, jti uuid.UUID) (bool, error) {
+	args := m.Called(jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTokenRepository) RevokeUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) SavePasswordResetToken(ctx context.Context, token *dto.PasswordResetToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockTokenRepository) FindPasswordResetToken(ctx context.Context, hash string) (*dto.PasswordResetToken, error) {
+	args := m.Called(hash)
+	token, _ := args.Get(0).(*dto.PasswordResetToken)
+	return token, args.Error(1)
+}
+
+func (m *MockTokenRepository) UsePasswordResetToken(ctx context.Context, hash string) (*dto.PasswordResetToken, error) {
+	args := m.Called(hash)
+	token, _ := args.Get(0).(*dto.PasswordResetToken)
+	return token, args.Error(1)
+}
@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/mock"
 )
@@ -9,27 +11,12 @@ type MockTransactionRepository struct {
 	mock.Mock
 }
 
-func (m *MockTransactionRepository) Begin() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-func (m *MockTransactionRepository) Commit() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-func (m *MockTransactionRepository) Rollback() error {
-	args := m.Called()
-	return args.Error(0)
-}
-
-func (m *MockTransactionRepository) Transaction(callback func() error) error {
-	args := m.Called(callback)
-	return args.Error(0)
-}
-
-func (m *MockTransactionRepository) GetTx() (*sqlx.Tx, error) {
-	args := m.Called()
-	return args.Get(0).(*sqlx.Tx), args.Error(1)
+// Transaction runs fn with a nil tx unless the expectation returns an error,
+// so the repository calls inside it can be asserted.
+func (m *MockTransactionRepository) Transaction(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	args := m.Called(fn)
+	if err := args.Error(0); err != nil {
+		return err
+	}
+	return fn(nil)
 }
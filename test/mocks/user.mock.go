@@ -1,9 +1,13 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -15,42 +19,203 @@ type MockUserService struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) Save(register *dto.RegisterRequest) error {
+func (m *MockUserRepository) Save(ctx context.Context, register *dto.RegisterRequest) error {
+	args := m.Called(register)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SaveWithTransaction(ctx context.Context, tx *sqlx.Tx, register *dto.RegisterRequest) error {
+	args := m.Called(tx, register)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SaveFirstAdmin(ctx context.Context, register *dto.RegisterRequest) error {
 	args := m.Called(register)
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) FindByEmail(email string) (*dto.User, int, error) {
+func (m *MockUserRepository) RehashPassword(ctx context.Context, id uuid.UUID, oldPassword string, newPassword string) error {
+	args := m.Called(id, oldPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetPasswordHistory(ctx context.Context, id uuid.UUID, limit int) ([]string, error) {
+	args := m.Called(id, limit)
+	hashes, _ := args.Get(0).([]string)
+	return hashes, args.Error(1)
+}
+
+func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*dto.User, error) {
 	args := m.Called(email)
-	return args.Get(0).(*dto.User), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.User), args.Error(1)
 }
 
-func (m *MockUserRepository) FindByID(id uuid.UUID) (*dto.User, int, error) {
+func (m *MockUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*dto.User, error) {
 	args := m.Called(id)
-	return args.Get(0).(*dto.User), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetAll(pagination *web.PaginationRequest) ([]*dto.User, error) {
+func (m *MockUserRepository) GetAll(ctx context.Context, pagination *web.PaginationRequest) ([]*dto.User, *web.PageInfo, error) {
 	args := m.Called(pagination)
-	return args.Get(0).([]*dto.User), args.Error(1)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.User), page, args.Error(2)
+}
+
+func (m *MockUserRepository) SetLocationScopeWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, scope *dto.LocationScope) error {
+	args := m.Called(tx, id, scope)
+	return args.Error(0)
 }
 
-func (m *MockUserService) Register(req *dto.RegisterRequest) (int, error) {
-	args := m.Called(req)
-	return args.Int(0), args.Error(1)
+func (m *MockUserService) Register(ctx context.Context, actor *dto.AuditActor, req *dto.RegisterRequest) error {
+	args := m.Called(actor, req)
+	return args.Error(0)
 }
 
-func (m *MockUserService) Login(req *dto.LoginRequest) (string, int, error) {
-	args := m.Called(req)
-	return args.String(0), args.Int(1), args.Error(2)
+func (m *MockUserService) Login(ctx context.Context, req *dto.LoginRequest, ip string) (*dto.TokenResponse, *dto.MFAChallengeResponse, error) {
+	args := m.Called(req, ip)
+	tokens, _ := args.Get(0).(*dto.TokenResponse)
+	challenge, _ := args.Get(1).(*dto.MFAChallengeResponse)
+	return tokens, challenge, args.Error(2)
 }
 
-func (m *MockUserService) GetUserByID(id uuid.UUID) (*dto.User, int, error) {
+func (m *MockUserService) Refresh(ctx context.Context, refreshToken string) (*dto.TokenResponse, error) {
+	args := m.Called(refreshToken)
+	tokens, _ := args.Get(0).(*dto.TokenResponse)
+	return tokens, args.Error(1)
+}
+
+func (m *MockUserService) Logout(ctx context.Context, claims *utils.CustomClaims, refreshToken string) error {
+	args := m.Called(claims, refreshToken)
+	return args.Error(0)
+}
+
+func (m *MockUserService) IsTokenRevoked(ctx context.Context, jti string) (bool, error) {
+	args := m.Called(jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserService) GetUserByID(ctx context.Context, id uuid.UUID) (*dto.User, error) {
 	args := m.Called(id)
-	return args.Get(0).(*dto.User), args.Int(1), args.Error(2)
+	return args.Get(0).(*dto.User), args.Error(1)
 }
 
-func (m *MockUserService) GetAllUser(pagination *web.PaginationRequest) ([]*dto.User, int, error) {
+func (m *MockUserService) GetAllUser(ctx context.Context, pagination *web.PaginationRequest) ([]*dto.User, *web.PageInfo, error) {
 	args := m.Called(pagination)
-	return args.Get(0).([]*dto.User), args.Int(1), args.Error(2)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return args.Get(0).([]*dto.User), page, args.Error(2)
+}
+
+func (m *MockUserService) SetLocationScope(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, scope *dto.LocationScope) (*dto.User, error) {
+	args := m.Called(actor, id, scope)
+	user, _ := args.Get(0).(*dto.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepository) Update(ctx context.Context, user *dto.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateWithTransaction(ctx context.Context, tx *sqlx.Tx, user *dto.User) error {
+	args := m.Called(tx, user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePasswordWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, password string) error {
+	args := m.Called(tx, id, password)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetActiveWithTransaction(ctx context.Context, tx *sqlx.Tx, id uuid.UUID, active bool) error {
+	args := m.Called(tx, id, active)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) IsActive(ctx context.Context, id uuid.UUID, role dto.UserRole) (bool, error) {
+	args := m.Called(id, role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserService) UpdateUser(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, user *dto.UserUpdateRequest) (*dto.User, error) {
+	args := m.Called(actor, id, user)
+	userData, _ := args.Get(0).(*dto.User)
+	return userData, args.Error(1)
+}
+
+func (m *MockUserService) SetActive(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, active bool) error {
+	args := m.Called(actor, id, active)
+	return args.Error(0)
+}
+
+func (m *MockUserService) IsUserActive(ctx context.Context, id uuid.UUID, role dto.UserRole) (bool, error) {
+	args := m.Called(id, role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, request *dto.PasswordChangeRequest) error {
+	args := m.Called(actor, id, request)
+	return args.Error(0)
+}
+
+func (m *MockUserService) CreatePasswordReset(ctx context.Context, id uuid.UUID) (*dto.PasswordResetResponse, error) {
+	args := m.Called(id)
+	reset, _ := args.Get(0).(*dto.PasswordResetResponse)
+	return reset, args.Error(1)
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, actor *dto.AuditActor, request *dto.PasswordResetRequest) error {
+	args := m.Called(actor, request)
+	return args.Error(0)
+}
+
+func (m *MockUserService) CreateInvite(ctx context.Context, createdBy uuid.UUID, request *dto.InviteRequest) (*dto.InviteResponse, error) {
+	args := m.Called(createdBy, request)
+	invite, _ := args.Get(0).(*dto.InviteResponse)
+	return invite, args.Error(1)
+}
+
+func (m *MockUserService) BootstrapAdmin(ctx context.Context, register *dto.RegisterRequest) error {
+	args := m.Called(register)
+	return args.Error(0)
+}
+
+func (m *MockUserService) GetAuthEvents(ctx context.Context, filter *dto.AuthEventFilter, pagination *web.PaginationRequest) ([]*dto.AuthEvent, *web.PageInfo, error) {
+	args := m.Called(filter, pagination)
+	events, _ := args.Get(0).([]*dto.AuthEvent)
+	page, _ := args.Get(1).(*web.PageInfo)
+	return events, page, args.Error(2)
+}
+
+func (m *MockUserService) VerifyMFA(ctx context.Context, actor *dto.AuditActor, request *dto.MFAVerifyRequest) (*dto.TokenResponse, error) {
+	args := m.Called(actor, request)
+	tokens, _ := args.Get(0).(*dto.TokenResponse)
+	return tokens, args.Error(1)
+}
+
+func (m *MockUserService) StartMFAEnrollment(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) (*dto.MFAEnrollmentResponse, error) {
+	args := m.Called(actor, id)
+	enrollment, _ := args.Get(0).(*dto.MFAEnrollmentResponse)
+	return enrollment, args.Error(1)
+}
+
+func (m *MockUserService) StartMFAEnrollmentWithChallenge(ctx context.Context, actor *dto.AuditActor, mfaToken string) (*dto.MFAEnrollmentResponse, error) {
+	args := m.Called(actor, mfaToken)
+	enrollment, _ := args.Get(0).(*dto.MFAEnrollmentResponse)
+	return enrollment, args.Error(1)
+}
+
+func (m *MockUserService) ConfirmMFAEnrollment(ctx context.Context, actor *dto.AuditActor, id uuid.UUID, code string) (*dto.MFAEnrollmentResult, error) {
+	args := m.Called(actor, id, code)
+	result, _ := args.Get(0).(*dto.MFAEnrollmentResult)
+	return result, args.Error(1)
+}
+
+func (m *MockUserService) DisableMFA(ctx context.Context, actor *dto.AuditActor, claims *utils.CustomClaims, code string) error {
+	args := m.Called(actor, claims, code)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ResetMFA(ctx context.Context, actor *dto.AuditActor, id uuid.UUID) error {
+	args := m.Called(actor, id)
+	return args.Error(0)
 }
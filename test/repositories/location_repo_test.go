@@ -1,10 +1,12 @@
 package repositories_test
 
 import (
-	"database/sql"
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
@@ -19,12 +21,13 @@ func TestMockLocationRepositorySave_Success(t *testing.T) {
 		Capacity: 5,
 	}
 
-	mockRepo.On("Save", location).Return(nil)
+	mockRepo.On("SaveWithTransaction", (*sqlx.Tx)(nil), location).Return(location, nil)
 
-	err := mockRepo.Save(location)
+	saved, err := mockRepo.SaveWithTransaction(context.Background(), nil, location)
 
 	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "Save", location)
+	assert.Equal(t, location, saved)
+	mockRepo.AssertCalled(t, "SaveWithTransaction", (*sqlx.Tx)(nil), location)
 }
 
 func TestMockLocationRepositorySave_Error(t *testing.T) {
@@ -35,13 +38,13 @@ func TestMockLocationRepositorySave_Error(t *testing.T) {
 		Capacity: 5,
 	}
 
-	mockRepo.On("Save", location).Return(assert.AnError)
+	mockRepo.On("SaveWithTransaction", (*sqlx.Tx)(nil), location).Return(nil, assert.AnError)
 
-	err := mockRepo.Save(location)
+	_, err := mockRepo.SaveWithTransaction(context.Background(), nil, location)
 
 	assert.Error(t, err)
 	assert.Equal(t, assert.AnError, err)
-	mockRepo.AssertCalled(t, "Save", location)
+	mockRepo.AssertCalled(t, "SaveWithTransaction", (*sqlx.Tx)(nil), location)
 }
 
 func TestMockLocationRepositoryFindByName_Success(t *testing.T) {
@@ -54,12 +57,11 @@ func TestMockLocationRepositoryFindByName_Success(t *testing.T) {
 		Capacity: 5,
 	}
 
-	mockRepo.On("FindByName", locationName).Return(expectedLocation, 200, nil)
+	mockRepo.On("FindByName", locationName).Return(expectedLocation, nil)
 
-	location, code, err := mockRepo.FindByName(locationName)
+	location, err := mockRepo.FindByName(context.Background(), locationName)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, code)
 	assert.Equal(t, expectedLocation, location)
 	mockRepo.AssertCalled(t, "FindByName", locationName)
 }
@@ -68,13 +70,13 @@ func TestMockLocationRepositoryFindByName_Error404(t *testing.T) {
 	mockRepo := new(mocks.MockLocationRepository)
 	locationName := "Test Location"
 
-	mockRepo.On("FindByName", locationName).Return((*dto.Location)(nil), 404, sql.ErrNoRows)
+	mockRepo.On("FindByName", locationName).Return((*dto.Location)(nil), domain.ErrNotFound)
 
-	location, code, err := mockRepo.FindByName(locationName)
+	location, err := mockRepo.FindByName(context.Background(), locationName)
 
 	assert.Error(t, err)
-	assert.Equal(t, 404, code)
-	assert.Equal(t, err, sql.ErrNoRows)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, err, domain.ErrNotFound)
 	assert.Nil(t, location)
 	mockRepo.AssertCalled(t, "FindByName", locationName)
 }
@@ -83,12 +85,11 @@ func TestMockLocationRepositoryFindByName_Error500(t *testing.T) {
 	mockRepo := new(mocks.MockLocationRepository)
 	locationName := "Test Location"
 
-	mockRepo.On("FindByName", locationName).Return((*dto.Location)(nil), 500, assert.AnError)
+	mockRepo.On("FindByName", locationName).Return((*dto.Location)(nil), assert.AnError)
 
-	location, code, err := mockRepo.FindByName(locationName)
+	location, err := mockRepo.FindByName(context.Background(), locationName)
 
 	assert.Error(t, err)
-	assert.Equal(t, 500, code)
 	assert.Equal(t, err, assert.AnError)
 	assert.Nil(t, location)
 	mockRepo.AssertCalled(t, "FindByName", locationName)
@@ -100,21 +101,22 @@ func TestMockLocationRepositoryGetAllLocation_Success(t *testing.T) {
 		Page: 1,
 		Size: 10,
 	}
+	scope := &dto.LocationScope{All: true}
 
 	locations := []*dto.Location{
 		{ID: uuid.New(), Name: "Location 1", Capacity: 5},
 		{ID: uuid.New(), Name: "Location 2", Capacity: 5},
 	}
 
-	mockRepo.On("GetAllLocation", pagination).Return(locations, nil)
+	mockRepo.On("GetAllLocation", scope, pagination).Return(locations, &web.PageInfo{TotalItems: int64(len(locations))}, nil)
 
-	locations, err := mockRepo.GetAllLocation(pagination)
+	locations, _, err := mockRepo.GetAllLocation(context.Background(), scope, pagination)
 
 	assert.NotNil(t, locations)
 	assert.NoError(t, err)
 	assert.Len(t, locations, 2)
 	assert.Equal(t, locations[0].Name, "Location 1")
-	mockRepo.AssertCalled(t, "GetAllLocation", pagination)
+	mockRepo.AssertCalled(t, "GetAllLocation", scope, pagination)
 }
 
 func TestMockLocationRepositoryGetAllLocation_SuccessNil(t *testing.T) {
@@ -123,14 +125,15 @@ func TestMockLocationRepositoryGetAllLocation_SuccessNil(t *testing.T) {
 		Page: 1,
 		Size: 10,
 	}
+	scope := &dto.LocationScope{All: true}
 
-	mockRepo.On("GetAllLocation", pagination).Return(([]*dto.Location)(nil), nil)
+	mockRepo.On("GetAllLocation", scope, pagination).Return(([]*dto.Location)(nil), &web.PageInfo{}, nil)
 
-	locations, err := mockRepo.GetAllLocation(pagination)
+	locations, _, err := mockRepo.GetAllLocation(context.Background(), scope, pagination)
 
 	assert.Nil(t, locations)
 	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "GetAllLocation", pagination)
+	mockRepo.AssertCalled(t, "GetAllLocation", scope, pagination)
 }
 
 func TestMockLocationRepositoryGetAllLocation_Nil(t *testing.T) {
@@ -139,13 +142,14 @@ func TestMockLocationRepositoryGetAllLocation_Nil(t *testing.T) {
 		Page: 1,
 		Size: 10,
 	}
+	scope := &dto.LocationScope{All: true}
 
-	mockRepo.On("GetAllLocation", pagination).Return(([]*dto.Location)(nil), assert.AnError)
+	mockRepo.On("GetAllLocation", scope, pagination).Return(([]*dto.Location)(nil), (*web.PageInfo)(nil), assert.AnError)
 
-	locations, err := mockRepo.GetAllLocation(pagination)
+	locations, _, err := mockRepo.GetAllLocation(context.Background(), scope, pagination)
 
 	assert.Nil(t, locations)
 	assert.Error(t, err)
 	assert.EqualError(t, err, assert.AnError.Error())
-	mockRepo.AssertCalled(t, "GetAllLocation", pagination)
+	mockRepo.AssertCalled(t, "GetAllLocation", scope, pagination)
 }
@@ -1,11 +1,12 @@
 package repositories_test
 
 import (
-	"database/sql"
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
@@ -22,10 +23,11 @@ func TestMockOrderRepositorySaveWithTransaction_Success(t *testing.T) {
 		Quantity:  10,
 	}
 
-	mockRepo.On("SaveWithTransaction", tx, order).Return(nil)
-	err := mockRepo.SaveWithTransaction(tx, order)
+	mockRepo.On("SaveWithTransaction", tx, order).Return(order, nil)
+	saved, err := mockRepo.SaveWithTransaction(context.Background(), tx, order)
 
 	assert.NoError(t, err)
+	assert.Equal(t, order, saved)
 	mockRepo.AssertCalled(t, "SaveWithTransaction", tx, order)
 }
 
@@ -39,8 +41,8 @@ func TestMockOrderRepositorySaveWithTransaction_Error(t *testing.T) {
 		Quantity:  10,
 	}
 
-	mockRepo.On("SaveWithTransaction", tx, order).Return(assert.AnError)
-	err := mockRepo.SaveWithTransaction(tx, order)
+	mockRepo.On("SaveWithTransaction", tx, order).Return(nil, assert.AnError)
+	_, err := mockRepo.SaveWithTransaction(context.Background(), tx, order)
 
 	assert.Error(t, err)
 	assert.Equal(t, assert.AnError, err)
@@ -53,18 +55,19 @@ func TestMockOrderRepositoryFindAll_Success(t *testing.T) {
 		Page: 1,
 		Size: 10,
 	}
+	scope := &dto.LocationScope{All: true}
 
 	orders := []*dto.Order{
 		{ID: uuid.New(), Type: "purchase", ProductID: uuid.New(), Quantity: 10},
 		{ID: uuid.New(), Type: "sale", ProductID: uuid.New(), Quantity: 5},
 	}
 
-	mockRepo.On("FindAll", pagination).Return(orders, nil)
-	result, err := mockRepo.FindAll(pagination)
+	mockRepo.On("FindAll", scope, pagination).Return(orders, &web.PageInfo{TotalItems: int64(len(orders))}, nil)
+	result, _, err := mockRepo.FindAll(context.Background(), scope, pagination)
 
 	assert.NoError(t, err)
 	assert.Equal(t, orders, result)
-	mockRepo.AssertCalled(t, "FindAll", pagination)
+	mockRepo.AssertCalled(t, "FindAll", scope, pagination)
 }
 
 func TestMockOrderRepositoryFindAll_SuccessNil(t *testing.T) {
@@ -73,13 +76,14 @@ func TestMockOrderRepositoryFindAll_SuccessNil(t *testing.T) {
 		Page: 1,
 		Size: 10,
 	}
+	scope := &dto.LocationScope{All: true}
 
-	mockRepo.On("FindAll", pagination).Return(([]*dto.Order)(nil), nil)
-	result, err := mockRepo.FindAll(pagination)
+	mockRepo.On("FindAll", scope, pagination).Return(([]*dto.Order)(nil), &web.PageInfo{}, nil)
+	result, _, err := mockRepo.FindAll(context.Background(), scope, pagination)
 
 	assert.NoError(t, err)
 	assert.Nil(t, result)
-	mockRepo.AssertCalled(t, "FindAll", pagination)
+	mockRepo.AssertCalled(t, "FindAll", scope, pagination)
 }
 
 func TestMockOrderRepositoryFindAll_Error(t *testing.T) {
@@ -88,14 +92,15 @@ func TestMockOrderRepositoryFindAll_Error(t *testing.T) {
 		Page: 1,
 		Size: 10,
 	}
+	scope := &dto.LocationScope{All: true}
 
-	mockRepo.On("FindAll", pagination).Return(([]*dto.Order)(nil), assert.AnError)
-	result, err := mockRepo.FindAll(pagination)
+	mockRepo.On("FindAll", scope, pagination).Return(([]*dto.Order)(nil), (*web.PageInfo)(nil), assert.AnError)
+	result, _, err := mockRepo.FindAll(context.Background(), scope, pagination)
 
 	assert.Error(t, err)
 	assert.Nil(t, result)
 	assert.Equal(t, assert.AnError, err)
-	mockRepo.AssertCalled(t, "FindAll", pagination)
+	mockRepo.AssertCalled(t, "FindAll", scope, pagination)
 }
 
 func TestMockOrderRepositoryFindByID_Success(t *testing.T) {
@@ -108,11 +113,10 @@ func TestMockOrderRepositoryFindByID_Success(t *testing.T) {
 		Quantity:  10,
 	}
 
-	mockRepo.On("FindByID", id).Return(order, 200, nil)
-	result, code, err := mockRepo.FindByID(id)
+	mockRepo.On("FindByID", id).Return(order, nil)
+	result, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, code)
 	assert.Equal(t, order, result)
 	mockRepo.AssertCalled(t, "FindByID", id)
 }
@@ -121,24 +125,23 @@ func TestMockOrderRepositoryFindByID_Error404(t *testing.T) {
 	mockRepo := new(mocks.MockOrderRepository)
 	id := uuid.New()
 
-	mockRepo.On("FindByID", id).Return((*dto.Order)(nil), 404, sql.ErrNoRows)
-	result, code, err := mockRepo.FindByID(id)
+	mockRepo.On("FindByID", id).Return((*dto.Order)(nil), domain.ErrNotFound)
+	result, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.Error(t, err)
-	assert.Equal(t, 404, code)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
 	assert.Nil(t, result)
-	assert.Equal(t, sql.ErrNoRows, err)
+	assert.Equal(t, domain.ErrNotFound, err)
 }
 
 func TestMockOrderRepositoryFindByID_Error500(t *testing.T) {
 	mockRepo := new(mocks.MockOrderRepository)
 	id := uuid.New()
 
-	mockRepo.On("FindByID", id).Return((*dto.Order)(nil), 500, assert.AnError)
-	result, code, err := mockRepo.FindByID(id)
+	mockRepo.On("FindByID", id).Return((*dto.Order)(nil), assert.AnError)
+	result, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.Error(t, err)
-	assert.Equal(t, 500, code)
 	assert.Equal(t, assert.AnError, err)
 	assert.Nil(t, result)
 }
@@ -1,10 +1,12 @@
 package repositories_test
 
 import (
-	"errors"
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks/repositories"
@@ -15,13 +17,13 @@ func TestMockProductRepositorySave_Error(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
 	product := &dto.Product{ID: uuid.New(), Name: "Test Product"}
 
-	mockRepo.On("Save", product).Return(assert.AnError)
+	mockRepo.On("SaveWithTransaction", (*sqlx.Tx)(nil), product).Return(nil, assert.AnError)
 
-	err := mockRepo.Save(product)
+	_, err := mockRepo.SaveWithTransaction(context.Background(), nil, product)
 
 	assert.Error(t, err)
 	assert.EqualError(t, err, assert.AnError.Error())
-	mockRepo.AssertCalled(t, "Save", product)
+	mockRepo.AssertCalled(t, "SaveWithTransaction", (*sqlx.Tx)(nil), product)
 }
 
 func TestMockProductRepositoryFindByID_Success(t *testing.T) {
@@ -29,12 +31,11 @@ func TestMockProductRepositoryFindByID_Success(t *testing.T) {
 	product := &dto.Product{ID: uuid.New(), Name: "Test Product"}
 	id := product.ID
 
-	mockRepo.On("FindByID", id).Return(product, 200, nil)
+	mockRepo.On("FindByID", id).Return(product, nil)
 
-	result, code, err := mockRepo.FindByID(id)
+	result, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, code)
 	assert.Equal(t, product, result)
 	mockRepo.AssertCalled(t, "FindByID", id)
 }
@@ -43,12 +44,12 @@ func TestMockProductRepositoryFindByID_NotFound(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
 	id := uuid.New()
 
-	mockRepo.On("FindByID", id).Return((*dto.Product)(nil), 404, errors.New("product not found"))
+	mockRepo.On("FindByID", id).Return((*dto.Product)(nil), domain.NotFound("product_not_found", "product not found"))
 
-	result, code, err := mockRepo.FindByID(id)
+	result, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.Nil(t, result)
-	assert.Equal(t, 404, code)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
 	assert.EqualError(t, err, "product not found")
 	mockRepo.AssertCalled(t, "FindByID", id)
 }
@@ -56,39 +57,40 @@ func TestMockProductRepositoryFindByID_NotFound(t *testing.T) {
 func TestMockProductRepositoryGetAllProduct_Error(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
 	pagination := &web.PaginationRequest{Page: 1, Size: 10}
+	scope := &dto.LocationScope{All: true}
 
-	mockRepo.On("GetAllProduct", pagination).Return(([]*dto.Product)(nil), assert.AnError)
+	mockRepo.On("GetAllProduct", scope, pagination).Return(([]*dto.Product)(nil), (*web.PageInfo)(nil), assert.AnError)
 
-	result, err := mockRepo.GetAllProduct(pagination)
+	result, _, err := mockRepo.GetAllProduct(context.Background(), scope, pagination)
 
 	assert.Nil(t, result)
 	assert.Error(t, err)
 	assert.Equal(t, err, assert.AnError)
-	mockRepo.AssertCalled(t, "GetAllProduct", pagination)
+	mockRepo.AssertCalled(t, "GetAllProduct", scope, pagination)
 }
 
 func TestMockProductRepositoryDelete_Success(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
 	id := uuid.New()
+	deletedBy := uuid.New()
 
-	mockRepo.On("Delete", id).Return(200, nil)
+	mockRepo.On("DeleteWithTransaction", (*sqlx.Tx)(nil), id, deletedBy).Return(nil)
 
-	code, err := mockRepo.Delete(id)
+	err := mockRepo.DeleteWithTransaction(context.Background(), nil, id, deletedBy)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, code)
-	mockRepo.AssertCalled(t, "Delete", id)
+	mockRepo.AssertCalled(t, "DeleteWithTransaction", (*sqlx.Tx)(nil), id, deletedBy)
 }
 
 func TestMockProductRepositoryDelete_NotFound(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
 	id := uuid.New()
+	deletedBy := uuid.New()
 
-	mockRepo.On("Delete", id).Return(404, assert.AnError)
+	mockRepo.On("DeleteWithTransaction", (*sqlx.Tx)(nil), id, deletedBy).Return(domain.NotFound("product_not_found", "product not found"))
 
-	code, err := mockRepo.Delete(id)
+	err := mockRepo.DeleteWithTransaction(context.Background(), nil, id, deletedBy)
 
-	assert.Equal(t, 404, code)
-	assert.Equal(t, assert.AnError, err)
-	mockRepo.AssertCalled(t, "Delete", id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	mockRepo.AssertCalled(t, "DeleteWithTransaction", (*sqlx.Tx)(nil), id, deletedBy)
 }
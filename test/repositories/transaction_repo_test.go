@@ -1,6 +1,7 @@
 package repositories_test
 
 import (
+	"context"
 	"testing"
 
 	"github.com/jmoiron/sqlx"
@@ -9,107 +10,44 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
-func TestTransactionRepositoryBegin_Success(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-
-	mockRepo.On("Begin").Return(nil)
-	err := mockRepo.Begin()
-
-	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "Begin")
-}
-
-func TestTransactionRepositoryBegin_Error(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-
-	mockRepo.On("Begin").Return(assert.AnError)
-	err := mockRepo.Begin()
-
-	assert.Error(t, err)
-	assert.Equal(t, assert.AnError, err)
-}
-
-func TestTransactionRepositoryCommit_Success(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-
-	mockRepo.On("Commit").Return(nil)
-	err := mockRepo.Commit()
-
-	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "Commit")
-}
-
-func TestTransactionRepositoryCommit_Error(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-
-	mockRepo.On("Commit").Return(assert.AnError)
-	err := mockRepo.Commit()
-
-	assert.Error(t, err)
-	assert.Equal(t, assert.AnError, err)
-}
-
-func TestTransactionRepositoryRollback_Success(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-
-	mockRepo.On("Rollback").Return(nil)
-	err := mockRepo.Rollback()
-
-	assert.NoError(t, err)
-	mockRepo.AssertCalled(t, "Rollback")
-}
-
-func TestTransactionRepositoryRollback_Error(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-
-	mockRepo.On("Rollback").Return(assert.AnError)
-	err := mockRepo.Rollback()
-
-	assert.Error(t, err)
-	assert.Equal(t, assert.AnError, err)
-}
-
 func TestTransactionRepositoryTransaction_Success(t *testing.T) {
 	mockRepo := new(mocks.MockTransactionRepository)
-	callback := func() error { return nil }
+	called := false
+	callback := func(tx *sqlx.Tx) error {
+		called = true
+		return nil
+	}
 
 	mockRepo.On("Transaction", mock.Anything).Return(nil)
-	err := mockRepo.Transaction(callback)
+	err := mockRepo.Transaction(context.Background(), callback)
 
 	assert.NoError(t, err)
+	assert.True(t, called)
 	mockRepo.AssertCalled(t, "Transaction", mock.Anything)
 }
 
-func TestTransactionRepositoryTransaction_Error(t *testing.T) {
+func TestTransactionRepositoryTransaction_CallbackError(t *testing.T) {
 	mockRepo := new(mocks.MockTransactionRepository)
-	callback := func() error { return nil }
+	callback := func(tx *sqlx.Tx) error { return assert.AnError }
 
-	mockRepo.On("Transaction", mock.Anything).Return(assert.AnError)
-	err := mockRepo.Transaction(callback)
+	mockRepo.On("Transaction", mock.Anything).Return(nil)
+	err := mockRepo.Transaction(context.Background(), callback)
 
-	assert.Error(t, err)
 	assert.Equal(t, assert.AnError, err)
 }
 
-func TestTransactionRepositoryGetTx_Success(t *testing.T) {
-	mockRepo := new(mocks.MockTransactionRepository)
-	mockTx := &sqlx.Tx{}
-
-	mockRepo.On("GetTx").Return(mockTx, nil)
-	tx, err := mockRepo.GetTx()
-
-	assert.NoError(t, err)
-	assert.Equal(t, mockTx, tx)
-	mockRepo.AssertCalled(t, "GetTx")
-}
-
-func TestTransactionRepositoryGetTx_Error(t *testing.T) {
+func TestTransactionRepositoryTransaction_Error(t *testing.T) {
 	mockRepo := new(mocks.MockTransactionRepository)
+	called := false
+	callback := func(tx *sqlx.Tx) error {
+		called = true
+		return nil
+	}
 
-	mockRepo.On("GetTx").Return((*sqlx.Tx)(nil), assert.AnError)
-	tx, err := mockRepo.GetTx()
+	mockRepo.On("Transaction", mock.Anything).Return(assert.AnError)
+	err := mockRepo.Transaction(context.Background(), callback)
 
 	assert.Error(t, err)
-	assert.Nil(t, tx)
 	assert.Equal(t, assert.AnError, err)
+	assert.False(t, called)
 }
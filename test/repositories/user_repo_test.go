@@ -1,10 +1,11 @@
 package repositories_test
 
 import (
-	"database/sql"
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
@@ -22,12 +23,11 @@ func TestFindByEmail_Success(t *testing.T) {
 		Role:  "admin",
 	}
 
-	mockRepo.On("FindByEmail", email).Return(expectedUser, 200, nil)
+	mockRepo.On("FindByEmail", email).Return(expectedUser, nil)
 
-	user, code, err := mockRepo.FindByEmail(email)
+	user, err := mockRepo.FindByEmail(context.Background(), email)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, code)
 	assert.Equal(t, expectedUser, user)
 	mockRepo.AssertCalled(t, "FindByEmail", email)
 }
@@ -36,13 +36,13 @@ func TestFindByEmail_Error404(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
 	email := "test@example.com"
 
-	mockRepo.On("FindByEmail", email).Return((*dto.User)(nil), 404, sql.ErrNoRows)
+	mockRepo.On("FindByEmail", email).Return((*dto.User)(nil), domain.ErrNotFound)
 
-	_, code, err := mockRepo.FindByEmail(email)
+	_, err := mockRepo.FindByEmail(context.Background(), email)
 
 	assert.Error(t, err)
-	assert.Equal(t, 404, code)
-	assert.Equal(t, sql.ErrNoRows, err)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, domain.ErrNotFound, err)
 	mockRepo.AssertCalled(t, "FindByEmail", email)
 }
 
@@ -50,12 +50,11 @@ func TestFindByEmail_Error500(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
 	email := "test@example.com"
 
-	mockRepo.On("FindByEmail", email).Return((*dto.User)(nil), 500, assert.AnError)
+	mockRepo.On("FindByEmail", email).Return((*dto.User)(nil), assert.AnError)
 
-	_, code, err := mockRepo.FindByEmail(email)
+	_, err := mockRepo.FindByEmail(context.Background(), email)
 
 	assert.Error(t, err)
-	assert.Equal(t, 500, code)
 	assert.Equal(t, assert.AnError, err)
 	mockRepo.AssertCalled(t, "FindByEmail", email)
 }
@@ -71,12 +70,11 @@ func TestFindByID_Success(t *testing.T) {
 		Role:  "admin",
 	}
 
-	mockRepo.On("FindByID", id).Return(expectedUser, 200, nil)
+	mockRepo.On("FindByID", id).Return(expectedUser, nil)
 
-	user, code, err := mockRepo.FindByID(id)
+	user, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, code)
 	assert.Equal(t, expectedUser, user)
 	mockRepo.AssertCalled(t, "FindByID", id)
 }
@@ -85,12 +83,11 @@ func TestFindByID_Error500(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
 	id := uuid.New()
 
-	mockRepo.On("FindByID", id).Return((*dto.User)(nil), 500, assert.AnError)
+	mockRepo.On("FindByID", id).Return((*dto.User)(nil), assert.AnError)
 
-	_, code, err := mockRepo.FindByID(id)
+	_, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.Error(t, err)
-	assert.Equal(t, 500, code)
 	assert.Equal(t, assert.AnError, err)
 	mockRepo.AssertCalled(t, "FindByID", id)
 }
@@ -99,13 +96,13 @@ func TestFindByID_Error404(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
 	id := uuid.New()
 
-	mockRepo.On("FindByID", id).Return((*dto.User)(nil), 404, sql.ErrNoRows)
+	mockRepo.On("FindByID", id).Return((*dto.User)(nil), domain.ErrNotFound)
 
-	_, code, err := mockRepo.FindByID(id)
+	_, err := mockRepo.FindByID(context.Background(), id)
 
 	assert.Error(t, err)
-	assert.Equal(t, 404, code)
-	assert.Equal(t, sql.ErrNoRows, err)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Equal(t, domain.ErrNotFound, err)
 	mockRepo.AssertCalled(t, "FindByID", id)
 }
 
@@ -121,7 +118,7 @@ func TestSave_Success(t *testing.T) {
 
 	mockRepo.On("Save", registerRequest).Return(nil)
 
-	err := mockRepo.Save(registerRequest)
+	err := mockRepo.Save(context.Background(), registerRequest)
 
 	assert.NoError(t, err)
 	mockRepo.AssertCalled(t, "Save", registerRequest)
@@ -139,7 +136,7 @@ func TestSave_Error(t *testing.T) {
 
 	mockRepo.On("Save", registerRequest).Return(assert.AnError)
 
-	err := mockRepo.Save(registerRequest)
+	err := mockRepo.Save(context.Background(), registerRequest)
 
 	assert.Error(t, err)
 	assert.Equal(t, assert.AnError, err)
@@ -159,9 +156,9 @@ func TestGetAll_Success(t *testing.T) {
 		{ID: uuid.New(), Email: "user2@example.com", Name: "User Two", Role: "admin"},
 	}
 
-	mockRepo.On("GetAll", pagination).Return(expectedUsers, nil)
+	mockRepo.On("GetAll", pagination).Return(expectedUsers, &web.PageInfo{TotalItems: int64(len(expectedUsers))}, nil)
 
-	users, err := mockRepo.GetAll(pagination)
+	users, _, err := mockRepo.GetAll(context.Background(), pagination)
 
 	assert.NoError(t, err)
 	assert.Len(t, users, 2)
@@ -177,9 +174,9 @@ func TestGetAll_SuccessNil(t *testing.T) {
 		Size: 10,
 	}
 
-	mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), nil)
+	mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), &web.PageInfo{}, nil)
 
-	users, err := mockRepo.GetAll(pagination)
+	users, _, err := mockRepo.GetAll(context.Background(), pagination)
 
 	assert.NoError(t, err)
 	assert.Len(t, users, 0)
@@ -195,9 +192,9 @@ func TestGetAll_Nil(t *testing.T) {
 		Size: 10,
 	}
 
-	mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), nil)
+	mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), &web.PageInfo{}, nil)
 
-	users, err := mockRepo.GetAll(pagination)
+	users, _, err := mockRepo.GetAll(context.Background(), pagination)
 
 	assert.NoError(t, err)
 	assert.Len(t, users, 0)
@@ -0,0 +1,63 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/models/web"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetAuditLogs(t *testing.T) {
+	mockRepo := new(mocks.MockAuditRepository)
+	service := services.NewAuditService(mockRepo)
+
+	entityID := uuid.New()
+	filter := &dto.AuditLogFilter{Entity: "product", EntityID: &entityID}
+	pagination := &web.PaginationRequest{Page: 1, Size: 10}
+
+	t.Run("Success", func(t *testing.T) {
+		logs := []*dto.AuditLog{{ID: uuid.New(), Action: dto.AuditActionUpdate, Entity: "product", EntityID: entityID}}
+		mockRepo.On("GetAll", filter, pagination).Return(logs, &web.PageInfo{TotalItems: 1}, nil).Once()
+
+		result, page, err := service.GetAll(context.Background(), filter, pagination)
+
+		assert.NoError(t, err)
+		assert.Equal(t, logs, result)
+		assert.Equal(t, int64(1), page.TotalItems)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Internal Error", func(t *testing.T) {
+		mockRepo.On("GetAll", filter, pagination).Return(nil, nil, assert.AnError).Once()
+
+		_, _, err := service.GetAll(context.Background(), filter, pagination)
+
+		assert.ErrorIs(t, err, assert.AnError)
+	})
+}
+
+func TestGetAuditLogsInChronologicalOrder(t *testing.T) {
+	db := mocks.NewFailingDB(errors.New("connection reset"))
+	defer db.Close()
+	service := services.NewAuditService(repositories.NewAuditRepository(db.DB))
+
+	for _, pagination := range []*web.PaginationRequest{{Page: 2, Size: 10}, {Size: 10, Keyset: true, After: uuid.New()}} {
+		_, _, err := service.GetAll(context.Background(), &dto.AuditLogFilter{}, pagination)
+
+		assert.Error(t, err)
+	}
+
+	assert.Len(t, db.Queries, 2)
+	for _, query := range db.Queries {
+		assert.Contains(t, query, "ORDER BY created_at, id ")
+		assert.NotContains(t, query, "DESC")
+	}
+	assert.Contains(t, db.Queries[1], "(created_at, id) > (SELECT created_at, id FROM public.audit_logs WHERE id = $7)")
+}
@@ -0,0 +1,86 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/repositories"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotencyService(t *testing.T) {
+	idempotencyRepo := new(mocks.MockIdempotencyRepository)
+	service := services.NewIdempotencyService(idempotencyRepo, time.Hour)
+
+	userID := uuid.New()
+
+	t.Run("Fresh Key Runs The Request", func(t *testing.T) {
+		idempotencyRepo.On("ReserveIdempotencyKey", mock.MatchedBy(func(key *dto.IdempotencyKey) bool {
+			return key.Key == "fresh" && key.RequestHash == "hash" && time.Until(key.ExpiresAt) > 59*time.Minute
+		})).Return(true, nil).Once()
+
+		stored, err := service.Begin(context.Background(), userID, "fresh", "hash")
+
+		assert.NoError(t, err)
+		assert.Nil(t, stored)
+	})
+
+	t.Run("Completed Key Is Replayed", func(t *testing.T) {
+		completed := &dto.IdempotencyKey{UserID: userID, Key: "done", RequestHash: "hash", StatusCode: 201, ResponseBody: []byte(`{}`)}
+		idempotencyRepo.On("ReserveIdempotencyKey", mock.Anything).Return(false, nil).Once()
+		idempotencyRepo.On("FindIdempotencyKey", userID, "done").Return(completed, nil).Once()
+
+		stored, err := service.Begin(context.Background(), userID, "done", "hash")
+
+		assert.NoError(t, err)
+		assert.Equal(t, completed, stored)
+	})
+
+	t.Run("Different Payload Is Rejected", func(t *testing.T) {
+		idempotencyRepo.On("ReserveIdempotencyKey", mock.Anything).Return(false, nil).Once()
+		idempotencyRepo.On("FindIdempotencyKey", userID, "reused").Return(&dto.IdempotencyKey{RequestHash: "other", StatusCode: 201}, nil).Once()
+
+		stored, err := service.Begin(context.Background(), userID, "reused", "hash")
+
+		assert.Nil(t, stored)
+		assert.ErrorIs(t, err, domain.ErrUnprocessable)
+	})
+
+	t.Run("Unfinished Key Is In Progress", func(t *testing.T) {
+		idempotencyRepo.On("ReserveIdempotencyKey", mock.Anything).Return(false, nil).Once()
+		idempotencyRepo.On("FindIdempotencyKey", userID, "running").Return(&dto.IdempotencyKey{RequestHash: "hash"}, nil).Once()
+
+		stored, err := service.Begin(context.Background(), userID, "running", "hash")
+
+		assert.Nil(t, stored)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+	})
+
+	idempotencyRepo.AssertExpectations(t)
+}
+
+func TestPurgeExpiredLogsDatabaseErrors(t *testing.T) {
+	db := mocks.NewFailingDB(errors.New("connection refused"))
+	defer db.Close()
+	service := services.NewIdempotencyService(repositories.NewIdempotencyRepository(db.DB), time.Hour)
+
+	var logs bytes.Buffer
+	ctx := utils.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+
+	_, err := service.PurgeExpired(ctx)
+
+	assert.EqualError(t, err, "connection refused")
+	assert.Contains(t, logs.String(), "database error")
+	assert.Contains(t, logs.String(), "DeleteExpiredIdempotencyKeys")
+}
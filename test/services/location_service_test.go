@@ -1,61 +1,156 @@
 package services_test
 
 import (
-	"database/sql"
+	"context"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestSaveLocation(t *testing.T) {
 	mockRepo := new(mocks.MockLocationRepository)
-	service := services.NewLocationService(mockRepo)
+	service := services.NewLocationService(mockRepo, newAuditRepository())
 
 	location := &dto.Location{
 		Name: "Warehouse A",
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByName", location.Name).Return((*dto.Location)(nil), 0, sql.ErrNoRows).Once()
-		mockRepo.On("Save", location).Return(nil).Once()
+		mockRepo.On("FindByName", location.Name).Return((*dto.Location)(nil), domain.ErrNotFound).Once()
+		saved := &dto.Location{ID: uuid.New(), Name: location.Name}
+		mockRepo.On("SaveWithTransaction", mock.Anything, location).Return(saved, nil).Once()
 
-		statusCode, err := service.Save(location)
+		created, err := service.Save(context.Background(), allLocations, auditActor, location)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 201, statusCode)
+		assert.Equal(t, saved, created)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Location Exists", func(t *testing.T) {
-		mockRepo.On("FindByName", location.Name).Return(location, 200, nil).Once()
+		mockRepo.On("FindByName", location.Name).Return(location, nil).Once()
 
-		statusCode, err := service.Save(location)
+		_, err := service.Save(context.Background(), allLocations, auditActor, location)
 
 		assert.Error(t, err)
-		assert.Equal(t, 401, statusCode)
+		assert.ErrorIs(t, err, domain.ErrConflict)
 		assert.Equal(t, "location name is exists", err.Error())
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Error", func(t *testing.T) {
-		mockRepo.On("FindByName", location.Name).Return((*dto.Location)(nil), 500, assert.AnError).Once()
+		mockRepo.On("FindByName", location.Name).Return((*dto.Location)(nil), assert.AnError).Once()
 
-		statusCode, err := service.Save(location)
+		_, err := service.Save(context.Background(), allLocations, auditActor, location)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		assert.Equal(t, assert.AnError, err)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Restricted User", func(t *testing.T) {
+		_, err := service.Save(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}, auditActor, location)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockRepo.AssertNumberOfCalls(t, "SaveWithTransaction", 1)
+	})
+}
+
+func TestGetLocationByID(t *testing.T) {
+	mockRepo := new(mocks.MockLocationRepository)
+	service := services.NewLocationService(mockRepo, newAuditRepository())
+
+	location := &dto.Location{ID: uuid.New(), Name: "Warehouse A"}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("FindByID", location.ID).Return(location, nil).Once()
+
+		found, err := service.GetByID(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{location.ID}}, location.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, location, found)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Other Location", func(t *testing.T) {
+		_, err := service.GetByID(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}, location.ID)
+
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockRepo.AssertNumberOfCalls(t, "FindByID", 1)
+	})
+}
+
+func TestDeleteLocation(t *testing.T) {
+	mockRepo := new(mocks.MockLocationRepository)
+	service := services.NewLocationService(mockRepo, newAuditRepository())
+
+	locationID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("DeleteWithTransaction", mock.Anything, locationID, auditActor.UserID).Return(nil).Once()
+
+		err := service.Delete(context.Background(), allLocations, auditActor, locationID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Not Empty", func(t *testing.T) {
+		mockRepo.On("DeleteWithTransaction", mock.Anything, locationID, auditActor.UserID).Return(domain.Conflict("location_not_empty", "location still has products")).Once()
+
+		err := service.Delete(context.Background(), allLocations, auditActor, locationID)
+
+		assert.ErrorIs(t, err, domain.ErrConflict)
+	})
+
+	t.Run("Restricted User", func(t *testing.T) {
+		err := service.Delete(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{locationID}}, auditActor, locationID)
+
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockRepo.AssertNumberOfCalls(t, "DeleteWithTransaction", 2)
+	})
+}
+
+func TestRestoreLocation(t *testing.T) {
+	mockRepo := new(mocks.MockLocationRepository)
+	service := services.NewLocationService(mockRepo, newAuditRepository())
+
+	deleted := &dto.Location{ID: uuid.New(), Name: "Warehouse A"}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("FindDeletedByID", deleted.ID).Return(deleted, nil).Once()
+		mockRepo.On("FindByName", deleted.Name).Return((*dto.Location)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("RestoreWithTransaction", mock.Anything, deleted.ID).Return(deleted, nil).Once()
+
+		restored, err := service.Restore(context.Background(), allLocations, auditActor, deleted.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, deleted, restored)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Name Taken", func(t *testing.T) {
+		mockRepo.On("FindDeletedByID", deleted.ID).Return(deleted, nil).Once()
+		mockRepo.On("FindByName", deleted.Name).Return(&dto.Location{ID: uuid.New(), Name: deleted.Name}, nil).Once()
+
+		_, err := service.Restore(context.Background(), allLocations, auditActor, deleted.ID)
+
+		assert.ErrorIs(t, err, domain.ErrConflict)
+		mockRepo.AssertNumberOfCalls(t, "RestoreWithTransaction", 1)
+	})
 }
 
 func TestGetAllLocation(t *testing.T) {
 	mockRepo := new(mocks.MockLocationRepository)
-	service := services.NewLocationService(mockRepo)
+	service := services.NewLocationService(mockRepo, newAuditRepository())
 
 	pagination := &web.PaginationRequest{
 		Page: 1,
@@ -68,23 +163,21 @@ func TestGetAllLocation(t *testing.T) {
 			{Name: "Warehouse B"},
 		}
 
-		mockRepo.On("GetAllLocation", pagination).Return(locations, nil).Once()
+		mockRepo.On("GetAllLocation", allLocations, pagination).Return(locations, &web.PageInfo{TotalItems: int64(len(locations))}, nil).Once()
 
-		result, statusCode, err := service.GetAll(pagination)
+		result, _, err := service.GetAll(context.Background(), allLocations, pagination)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Equal(t, 2, len(result))
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Error", func(t *testing.T) {
-		mockRepo.On("GetAllLocation", pagination).Return(([]*dto.Location)(nil), assert.AnError).Once()
+		mockRepo.On("GetAllLocation", allLocations, pagination).Return(([]*dto.Location)(nil), (*web.PageInfo)(nil), assert.AnError).Once()
 
-		result, statusCode, err := service.GetAll(pagination)
+		result, _, err := service.GetAll(context.Background(), allLocations, pagination)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
@@ -0,0 +1,47 @@
+package services_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/mock"
+)
+
+var allLocations = &dto.LocationScope{All: true}
+
+var auditActor = &dto.AuditActor{UserID: uuid.New(), IP: "192.0.2.1", RequestID: "request-1"}
+
+// anonymousActor makes requests without signing in, such as registering. The
+// change is then recorded as made by the user it concerns.
+var anonymousActor = &dto.AuditActor{IP: "198.51.100.1", RequestID: "request-2"}
+
+// newAuditRepository runs audited changes directly, but only for auditActor
+// or anonymousActor acting as a user, so a service that drops or swaps the
+// actor fails its test.
+func newAuditRepository() *mocks.MockAuditRepository {
+	actor := mock.MatchedBy(func(actor *dto.AuditActor) bool {
+		if actor == auditActor {
+			return true
+		}
+
+		return actor != nil && actor.UserID != uuid.Nil && actor.IP == anonymousActor.IP && actor.RequestID == anonymousActor.RequestID
+	})
+
+	audit := new(mocks.MockAuditRepository)
+	audit.On("Transaction", actor, mock.Anything).Return(nil)
+	audit.On("TagWithTransaction", mock.Anything, actor).Return(nil)
+	return audit
+}
+
+func TestMain(m *testing.M) {
+	if err := utils.LoadKeys(config.JWTConfig{SecretKey: "test-secret"}); err != nil {
+		panic(err)
+	}
+
+	os.Exit(m.Run())
+}
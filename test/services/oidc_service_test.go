@@ -0,0 +1,224 @@
+package services_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCService(t *testing.T) {
+	idp := mocks.NewStandInIdP("warehouse")
+	defer idp.Close()
+
+	provider, err := utils.NewOIDCProvider(context.Background(), config.OIDCConfig{
+		IssuerURL:   idp.URL(),
+		ClientID:    "warehouse",
+		RedirectURL: "http://localhost:8080/api/v1/auth/oidc/callback",
+		GroupsClaim: "groups",
+	})
+	require.NoError(t, err)
+
+	groupRoles := []config.GroupRole{
+		{Group: "warehouse-admins", Role: string(dto.UserRoleAdmin)},
+		{Group: "warehouse-staff", Role: string(dto.UserRoleStaff)},
+	}
+
+	type fixture struct {
+		service   services.OIDCService
+		oidc      *mocks.MockOIDCRepository
+		user      *mocks.MockUserRepository
+		token     *mocks.MockTokenRepository
+		authEvent *mocks.MockAuthEventRepository
+		mfa       *mocks.MockMFARepository
+	}
+
+	newFixture := func() *fixture {
+		f := &fixture{
+			oidc:      new(mocks.MockOIDCRepository),
+			user:      new(mocks.MockUserRepository),
+			token:     new(mocks.MockTokenRepository),
+			authEvent: new(mocks.MockAuthEventRepository),
+			mfa:       new(mocks.MockMFARepository),
+		}
+		f.service = services.NewOIDCService(provider, f.oidc, f.user, f.token, f.authEvent, f.mfa, newAuditRepository(), config.MFAConfig{RequiredRoles: []string{"admin"}}, groupRoles)
+		return f
+	}
+
+	// signIn runs the browser part of the flow: start, visit the IdP and
+	// come back with a code and the state we stored.
+	signIn := func(t *testing.T, f *fixture) (string, string) {
+		var saved *dto.OIDCLoginState
+		f.oidc.On("SaveLoginState", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*dto.OIDCLoginState)
+		}).Return(nil).Once()
+
+		authURL, err := f.service.StartLogin(context.Background())
+		require.NoError(t, err)
+
+		parsed, _ := url.Parse(authURL)
+		assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+		assert.Equal(t, utils.PKCEChallenge(saved.CodeVerifier), parsed.Query().Get("code_challenge"))
+
+		code, state, err := idp.Authorize(authURL)
+		require.NoError(t, err)
+
+		f.oidc.On("UseLoginState", utils.HashToken(state)).Return(saved, nil).Once()
+		return code, state
+	}
+
+	t.Run("Provisions New User", func(t *testing.T) {
+		f := newFixture()
+		idp.Groups = []string{"other", "warehouse-staff"}
+		code, state := signIn(t, f)
+
+		f.oidc.On("FindUserIDBySubject", idp.URL(), "stand-in-user").Return(uuid.Nil, domain.ErrNotFound).Once()
+		f.user.On("FindByEmail", "jane@example.com").Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		f.user.On("SaveWithTransaction", mock.Anything, mock.MatchedBy(func(register *dto.RegisterRequest) bool {
+			return register.Email == "jane@example.com" && register.Role == dto.UserRoleStaff && register.Password == "" && !register.AllLocations
+		})).Return(nil).Once()
+		f.oidc.On("LinkIdentity", mock.Anything, idp.URL(), "stand-in-user").Return(nil).Once()
+		f.token.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+		f.authEvent.On("SaveAuthEvent", mock.MatchedBy(func(event *dto.AuthEvent) bool {
+			return event.Event == dto.AuthEventSSOLogin && event.Success
+		})).Return(nil).Once()
+
+		tokens, challenge, err := f.service.CompleteLogin(context.Background(), anonymousActor, code, state)
+
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+
+		claims, err := utils.VerifyToken(tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, dto.UserRoleStaff, claims.Role)
+		f.user.AssertExpectations(t)
+		f.oidc.AssertExpectations(t)
+	})
+
+	t.Run("Provisioned Admin Gets Every Location And An MFA Challenge", func(t *testing.T) {
+		f := newFixture()
+		idp.Groups = []string{"warehouse-admins"}
+		code, state := signIn(t, f)
+
+		f.oidc.On("FindUserIDBySubject", idp.URL(), "stand-in-user").Return(uuid.Nil, domain.ErrNotFound).Once()
+		f.user.On("FindByEmail", "jane@example.com").Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		f.user.On("SaveWithTransaction", mock.Anything, mock.MatchedBy(func(register *dto.RegisterRequest) bool {
+			return register.Role == dto.UserRoleAdmin && register.AllLocations
+		})).Return(nil).Once()
+		f.oidc.On("LinkIdentity", mock.Anything, idp.URL(), "stand-in-user").Return(nil).Once()
+		f.mfa.On("SaveChallenge", mock.Anything).Return(nil).Once()
+
+		tokens, challenge, err := f.service.CompleteLogin(context.Background(), anonymousActor, code, state)
+
+		require.NoError(t, err)
+		assert.Nil(t, tokens)
+		require.NotNil(t, challenge)
+		assert.True(t, challenge.EnrollmentRequired)
+		f.user.AssertExpectations(t)
+		f.mfa.AssertExpectations(t)
+		f.token.AssertNotCalled(t, "SaveRefreshToken", mock.Anything)
+	})
+
+	t.Run("Linked User Gets Role From Groups", func(t *testing.T) {
+		f := newFixture()
+		idp.Groups = []string{"warehouse-staff", "warehouse-admins"}
+		code, state := signIn(t, f)
+
+		user := &dto.User{ID: uuid.New(), Email: "jane@example.com", Role: dto.UserRoleStaff, Active: true, MFAEnabled: true}
+		f.oidc.On("FindUserIDBySubject", idp.URL(), "stand-in-user").Return(user.ID, nil).Once()
+		f.user.On("FindByID", user.ID).Return(user, nil).Once()
+		f.user.On("UpdateWithTransaction", mock.Anything, mock.MatchedBy(func(updated *dto.User) bool {
+			return updated.Role == dto.UserRoleAdmin && updated.Scope.All
+		})).Return(nil).Once()
+		f.mfa.On("SaveChallenge", mock.Anything).Return(nil).Once()
+
+		_, challenge, err := f.service.CompleteLogin(context.Background(), anonymousActor, code, state)
+
+		require.NoError(t, err)
+		require.NotNil(t, challenge)
+		assert.False(t, challenge.EnrollmentRequired)
+		f.user.AssertExpectations(t)
+	})
+
+	t.Run("Demoted Admin Loses Every Location", func(t *testing.T) {
+		f := newFixture()
+		idp.Groups = []string{"warehouse-staff"}
+		code, state := signIn(t, f)
+
+		user := &dto.User{ID: uuid.New(), Email: "jane@example.com", Role: dto.UserRoleAdmin, Active: true, Scope: dto.LocationScope{All: true}}
+		f.oidc.On("FindUserIDBySubject", idp.URL(), "stand-in-user").Return(user.ID, nil).Once()
+		f.user.On("FindByID", user.ID).Return(user, nil).Once()
+		f.user.On("UpdateWithTransaction", mock.Anything, mock.MatchedBy(func(updated *dto.User) bool {
+			return updated.Role == dto.UserRoleStaff && !updated.Scope.All
+		})).Return(nil).Once()
+		f.token.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+		f.authEvent.On("SaveAuthEvent", mock.Anything).Return(nil).Once()
+
+		tokens, _, err := f.service.CompleteLogin(context.Background(), anonymousActor, code, state)
+
+		require.NoError(t, err)
+		assert.NotNil(t, tokens)
+		f.user.AssertExpectations(t)
+	})
+
+	t.Run("No Matching Group", func(t *testing.T) {
+		f := newFixture()
+		idp.Groups = []string{"finance"}
+		code, state := signIn(t, f)
+
+		f.authEvent.On("SaveAuthEvent", mock.MatchedBy(func(event *dto.AuthEvent) bool {
+			return event.Reason == dto.AuthReasonNoRole
+		})).Return(nil).Once()
+
+		_, _, err := f.service.CompleteLogin(context.Background(), anonymousActor, code, state)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		f.authEvent.AssertExpectations(t)
+	})
+
+	t.Run("Unverified Email Is Not Linked", func(t *testing.T) {
+		f := newFixture()
+		idp.Groups = []string{"warehouse-staff"}
+		idp.EmailVerified = false
+		defer func() { idp.EmailVerified = true }()
+		code, state := signIn(t, f)
+
+		f.oidc.On("FindUserIDBySubject", idp.URL(), "stand-in-user").Return(uuid.Nil, domain.ErrNotFound).Once()
+
+		_, _, err := f.service.CompleteLogin(context.Background(), anonymousActor, code, state)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		f.user.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	})
+
+	t.Run("Unknown State", func(t *testing.T) {
+		f := newFixture()
+		f.oidc.On("UseLoginState", utils.HashToken("forged")).Return(nil, domain.ErrNotFound).Once()
+
+		_, _, err := f.service.CompleteLogin(context.Background(), anonymousActor, "code", "forged")
+
+		assert.EqualError(t, err, "invalid or expired login state")
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("Not Configured", func(t *testing.T) {
+		service := services.NewOIDCService(nil, new(mocks.MockOIDCRepository), new(mocks.MockUserRepository), new(mocks.MockTokenRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), new(mocks.MockAuditRepository), config.MFAConfig{}, nil)
+
+		_, err := service.StartLogin(context.Background())
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
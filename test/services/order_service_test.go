@@ -1,15 +1,19 @@
 package services_test
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -19,24 +23,21 @@ func TestReceiveOrder_Success(t *testing.T) {
 	productRepo := new(mocks.MockProductRepository)
 	transactionRepo := new(mocks.MockTransactionRepository)
 
-	orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo)
+	orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo, newAuditRepository())
 
 	orderRequest := &dto.OrderCreateRequest{
 		ProductID: uuid.New(),
 		Quantity:  10,
 	}
 
-	orderRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(nil)
-	productRepo.On("IncreaseStockWithTransaction", mock.Anything, mock.Anything, mock.Anything).Return(int64(10), nil)
-	transactionRepo.On("Begin").Return(nil)
-	transactionRepo.On("Commit").Return(nil)
+	productRepo.On("FindByID", orderRequest.ProductID).Return(&dto.Product{ID: orderRequest.ProductID, LocationID: uuid.New()}, nil)
+	orderRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(&dto.Order{ID: uuid.New()}, nil)
+	productRepo.On("IncreaseStockWithTransaction", mock.Anything, mock.Anything, mock.Anything).Return(nil)
 	transactionRepo.On("Transaction", mock.Anything).Return(nil)
-	transactionRepo.On("GetTx").Return(&sqlx.Tx{}, nil)
 
-	status, err := orderService.ReceiveOrder(orderRequest)
+	_, err := orderService.ReceiveOrder(context.Background(), allLocations, auditActor, orderRequest)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 200, status)
 }
 
 func TestOrderService(t *testing.T) {
@@ -44,7 +45,7 @@ func TestOrderService(t *testing.T) {
 	productRepo := new(mocks.MockProductRepository)
 	transactionRepo := new(mocks.MockTransactionRepository)
 
-	orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo)
+	orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo, newAuditRepository())
 
 	orderRequest := &dto.OrderCreateRequest{
 		ProductID: uuid.New(),
@@ -64,73 +65,194 @@ func TestOrderService(t *testing.T) {
 		Size: 10,
 	}
 
+	locationID := uuid.New()
+	product := &dto.Product{ID: orderRequest.ProductID, LocationID: locationID}
+
 	t.Run("ReceiveOrder - Success", func(t *testing.T) {
-		orderRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(nil).Once()
-		productRepo.On("IncreaseStockWithTransaction", mock.Anything, mock.Anything, mock.Anything).Return(int64(10), nil).Once()
-		transactionRepo.On("Begin").Return(nil).Once()
-		transactionRepo.On("Commit").Return(nil).Once()
+		productRepo.On("FindByID", orderRequest.ProductID).Return(product, nil).Once()
+		orderRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(&dto.Order{ID: uuid.New()}, nil).Once()
+		productRepo.On("IncreaseStockWithTransaction", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 		transactionRepo.On("Transaction", mock.Anything).Return(nil).Once()
-		transactionRepo.On("GetTx").Return(&sqlx.Tx{}, nil).Once()
 
-		status, err := orderService.ReceiveOrder(orderRequest)
+		_, err := orderService.ReceiveOrder(context.Background(), allLocations, auditActor, orderRequest)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, status)
 	})
 
 	t.Run("ShipOrder - Success", func(t *testing.T) {
-		orderRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(nil).Once()
-		productRepo.On("DecreaseStockWithTransaction", mock.Anything, mock.Anything, mock.Anything).Return(int64(5), nil).Once()
-		transactionRepo.On("Begin").Return(nil).Once()
-		transactionRepo.On("Commit").Return(nil).Once()
+		productRepo.On("FindByID", orderRequest.ProductID).Return(product, nil).Once()
+		orderRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(&dto.Order{ID: uuid.New()}, nil).Once()
+		productRepo.On("DecreaseStockWithTransaction", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 		transactionRepo.On("Transaction", mock.Anything).Return(nil).Once()
-		transactionRepo.On("GetTx").Return(&sqlx.Tx{}, nil).Once()
 
-		status, err := orderService.ShipOrder(orderRequest)
+		_, err := orderService.ShipOrder(context.Background(), allLocations, auditActor, orderRequest)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, status)
+		assert.Equal(t, float64(10), testutil.ToFloat64(utils.OrderUnits.WithLabelValues(string(dto.OrderTypeShipping), locationID.String())))
+	})
+
+	t.Run("ShipOrder - Other Location", func(t *testing.T) {
+		productRepo.On("FindByID", orderRequest.ProductID).Return(product, nil).Once()
+
+		_, err := orderService.ShipOrder(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}, auditActor, orderRequest)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		transactionRepo.AssertNumberOfCalls(t, "Transaction", 2)
 	})
 
 	t.Run("GetAllOrders - Success", func(t *testing.T) {
 		mockOrders := []*dto.Order{mockOrder}
-		orderRepo.On("FindAll", pagination).Return(mockOrders, nil).Once()
+		orderRepo.On("FindAll", allLocations, pagination).Return(mockOrders, &web.PageInfo{TotalItems: int64(len(mockOrders))}, nil).Once()
 
-		orders, status, err := orderService.GetAllOrders(pagination)
+		orders, _, err := orderService.GetAllOrders(context.Background(), allLocations, pagination)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, status)
 		assert.Equal(t, mockOrders, orders)
 	})
 
 	t.Run("GetAllOrders - Failure", func(t *testing.T) {
-		orderRepo.On("FindAll", pagination).Return(([]*dto.Order)(nil), errors.New("failed to get orders")).Once()
+		orderRepo.On("FindAll", allLocations, pagination).Return(([]*dto.Order)(nil), (*web.PageInfo)(nil), errors.New("failed to get orders")).Once()
 
-		orders, status, err := orderService.GetAllOrders(pagination)
+		orders, _, err := orderService.GetAllOrders(context.Background(), allLocations, pagination)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, status)
 		assert.Nil(t, orders)
 	})
 
 	t.Run("GetOrderByID - Success", func(t *testing.T) {
-		orderRepo.On("FindByID", orderID).Return(mockOrder, 200, nil).Once()
+		orderRepo.On("FindByID", orderID).Return(mockOrder, nil).Once()
+
+		order, err := orderService.GetOrderByID(context.Background(), allLocations, orderID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockOrder, order)
+	})
 
-		order, status, err := orderService.GetOrderByID(orderID)
+	t.Run("GetOrderByID - Scoped", func(t *testing.T) {
+		orderRepo.On("FindByID", orderID).Return(mockOrder, nil).Twice()
+		productRepo.On("FindByID", orderRequest.ProductID).Return(product, nil).Twice()
+
+		order, err := orderService.GetOrderByID(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{locationID}}, orderID)
+		assert.NoError(t, err)
+		assert.Equal(t, mockOrder, order)
+
+		order, err = orderService.GetOrderByID(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}, orderID)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		assert.Nil(t, order)
+	})
+
+	t.Run("GetOrderByID - Deleted Product", func(t *testing.T) {
+		orderRepo.On("FindByID", orderID).Return(mockOrder, nil).Once()
+		productRepo.On("FindByID", orderRequest.ProductID).Return((*dto.Product)(nil), domain.ErrNotFound).Once()
+		productRepo.On("FindDeletedByID", orderRequest.ProductID).Return(product, nil).Once()
+
+		order, err := orderService.GetOrderByID(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{locationID}}, orderID)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, status)
 		assert.Equal(t, mockOrder, order)
 	})
 
 	t.Run("GetOrderByID - Failure", func(t *testing.T) {
-		orderRepo.On("FindByID", orderID).Return((*dto.Order)(nil), 404, errors.New("order not found")).Once()
+		orderRepo.On("FindByID", orderID).Return((*dto.Order)(nil), domain.NotFound("order_not_found", "order not found")).Once()
 
-		order, status, err := orderService.GetOrderByID(orderID)
+		order, err := orderService.GetOrderByID(context.Background(), allLocations, orderID)
 
 		assert.Error(t, err)
-		assert.Equal(t, 404, status)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
 		assert.Nil(t, order)
 		assert.Equal(t, "order not found", err.Error())
 	})
 }
+
+func runTransaction(transactionRepo *mocks.MockTransactionRepository) {
+	transactionRepo.On("Transaction", mock.Anything).Return(nil).Once()
+}
+
+func TestBatchOrders(t *testing.T) {
+	productA := uuid.New()
+	productB := uuid.New()
+	missing := uuid.New()
+
+	lines := []dto.OrderBatchLine{
+		{Type: dto.OrderTypeReceiving, ProductID: productA, Quantity: 5},
+		{Type: dto.OrderTypeShipping, ProductID: productB, Quantity: 8},
+		{Type: dto.OrderTypeShipping, ProductID: missing, Quantity: 1},
+		{Type: dto.OrderTypeShipping, ProductID: productA, Quantity: 12},
+	}
+	productIDs := []uuid.UUID{productA, productB, missing, productA}
+	locationID := uuid.New()
+	stock := func() map[uuid.UUID]*dto.Product {
+		return map[uuid.UUID]*dto.Product{
+			productA: {ID: productA, Quantity: 10, LocationID: locationID},
+			productB: {ID: productB, Quantity: 3, LocationID: locationID},
+		}
+	}
+
+	t.Run("Best Effort", func(t *testing.T) {
+		orderRepo := new(mocks.MockOrderRepository)
+		productRepo := new(mocks.MockProductRepository)
+		transactionRepo := new(mocks.MockTransactionRepository)
+		orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo, newAuditRepository())
+
+		runTransaction(transactionRepo)
+		productRepo.On("LockStockWithTransaction", (*sqlx.Tx)(nil), productIDs).Return(stock(), nil).Once()
+		productRepo.On("AdjustStockBatchWithTransaction", (*sqlx.Tx)(nil), map[uuid.UUID]int64{productA: -7}).Return(nil).Once()
+		orderRepo.On("SaveBatchWithTransaction", (*sqlx.Tx)(nil), []*dto.Order{
+			{Type: dto.OrderTypeReceiving, ProductID: productA, Quantity: 5},
+			{Type: dto.OrderTypeShipping, ProductID: productA, Quantity: 12},
+		}).Return(nil).Once()
+
+		result, err := orderService.BatchOrders(context.Background(), allLocations, auditActor, &dto.OrderBatchRequest{Mode: dto.OrderBatchModeBestEffort, Lines: lines})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Applied)
+		assert.Equal(t, 2, result.Failed)
+		assert.Equal(t, "insufficient stock", result.Lines[1].Error)
+		assert.Equal(t, "product not found", result.Lines[2].Error)
+		assert.Equal(t, dto.OrderBatchLineApplied, result.Lines[3].Status)
+		assert.Equal(t, float64(5), testutil.ToFloat64(utils.OrderUnits.WithLabelValues(string(dto.OrderTypeReceiving), locationID.String())))
+		assert.Equal(t, float64(12), testutil.ToFloat64(utils.OrderUnits.WithLabelValues(string(dto.OrderTypeShipping), locationID.String())))
+		orderRepo.AssertExpectations(t)
+		productRepo.AssertExpectations(t)
+		transactionRepo.AssertExpectations(t)
+	})
+
+	t.Run("Atomic Rolls Back", func(t *testing.T) {
+		orderRepo := new(mocks.MockOrderRepository)
+		productRepo := new(mocks.MockProductRepository)
+		transactionRepo := new(mocks.MockTransactionRepository)
+		orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo, newAuditRepository())
+
+		runTransaction(transactionRepo)
+		productRepo.On("LockStockWithTransaction", (*sqlx.Tx)(nil), productIDs).Return(stock(), nil).Once()
+
+		result, err := orderService.BatchOrders(context.Background(), allLocations, auditActor, &dto.OrderBatchRequest{Lines: lines})
+
+		assert.ErrorIs(t, err, domain.ErrUnprocessable)
+		assert.Equal(t, dto.OrderBatchModeAtomic, result.Mode)
+		assert.Equal(t, 0, result.Applied)
+		assert.Equal(t, dto.OrderBatchLineRolledBack, result.Lines[0].Status)
+		assert.Equal(t, dto.OrderBatchLineFailed, result.Lines[1].Status)
+		productRepo.AssertNotCalled(t, "AdjustStockBatchWithTransaction", mock.Anything, mock.Anything)
+		orderRepo.AssertNotCalled(t, "SaveBatchWithTransaction", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Other Location", func(t *testing.T) {
+		orderRepo := new(mocks.MockOrderRepository)
+		productRepo := new(mocks.MockProductRepository)
+		transactionRepo := new(mocks.MockTransactionRepository)
+		orderService := services.NewOrderService(orderRepo, productRepo, transactionRepo, newAuditRepository())
+
+		runTransaction(transactionRepo)
+		productRepo.On("LockStockWithTransaction", (*sqlx.Tx)(nil), productIDs).Return(stock(), nil).Once()
+
+		result, err := orderService.BatchOrders(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}, auditActor, &dto.OrderBatchRequest{Mode: dto.OrderBatchModeBestEffort, Lines: lines})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		assert.Nil(t, result)
+		productRepo.AssertNotCalled(t, "AdjustStockBatchWithTransaction", mock.Anything, mock.Anything)
+	})
+}
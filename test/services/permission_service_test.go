@@ -0,0 +1,163 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPermissionService(t *testing.T) {
+	catalog := []*dto.Permission{
+		{Name: dto.PermissionProductRead},
+		{Name: dto.PermissionOrderShip},
+		{Name: dto.PermissionRoleManage},
+	}
+
+	t.Run("HasPermission_CachesRoles", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		permissionRepo.On("ListRoles").Return([]*dto.Role{
+			{Name: dto.UserRoleStaff, Permissions: []string{dto.PermissionProductRead, dto.PermissionOrderShip}},
+		}, nil).Once()
+
+		granted, err := permissionService.HasPermission(context.Background(), dto.UserRoleStaff, dto.PermissionOrderShip)
+		assert.NoError(t, err)
+		assert.True(t, granted)
+
+		granted, err = permissionService.HasPermission(context.Background(), dto.UserRoleStaff, dto.PermissionRoleManage)
+		assert.NoError(t, err)
+		assert.False(t, granted)
+
+		granted, err = permissionService.HasPermission(context.Background(), "auditor", dto.PermissionProductRead)
+		assert.NoError(t, err)
+		assert.False(t, granted)
+
+		permissionRepo.AssertNumberOfCalls(t, "ListRoles", 1)
+	})
+
+	t.Run("HasPermission_Error", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		permissionRepo.On("ListRoles").Return(nil, errors.New("db error")).Once()
+
+		granted, err := permissionService.HasPermission(context.Background(), dto.UserRoleAdmin, dto.PermissionRoleManage)
+		assert.Error(t, err)
+		assert.False(t, granted)
+	})
+
+	t.Run("CreateRole_Success_InvalidatesCache", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		transactionRepo := new(mocks.MockTransactionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, transactionRepo)
+
+		permissionRepo.On("ListRoles").Return([]*dto.Role{}, nil).Once()
+		granted, _ := permissionService.HasPermission(context.Background(), "auditor", dto.PermissionProductRead)
+		assert.False(t, granted)
+
+		role := &dto.Role{Name: "auditor", Permissions: []string{dto.PermissionProductRead}}
+
+		permissionRepo.On("FindRole", role.Name).Return(nil, domain.ErrNotFound).Once()
+		permissionRepo.On("ListPermissions").Return(catalog, nil).Once()
+		permissionRepo.On("SaveRoleWithTransaction", mock.Anything, role).Return(nil).Once()
+		permissionRepo.On("ReplaceRolePermissionsWithTransaction", mock.Anything, role.Name, role.Permissions).Return(nil).Once()
+		runTransaction(transactionRepo)
+
+		err := permissionService.CreateRole(context.Background(), role)
+		assert.NoError(t, err)
+
+		permissionRepo.On("ListRoles").Return([]*dto.Role{role}, nil).Once()
+		granted, err = permissionService.HasPermission(context.Background(), "auditor", dto.PermissionProductRead)
+		assert.NoError(t, err)
+		assert.True(t, granted)
+
+		permissionRepo.AssertExpectations(t)
+		transactionRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateRole_Exists", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		role := &dto.Role{Name: dto.UserRoleStaff, Permissions: []string{}}
+		permissionRepo.On("FindRole", role.Name).Return(role, nil).Once()
+
+		err := permissionService.CreateRole(context.Background(), role)
+		assert.EqualError(t, err, "role is exists")
+		assert.ErrorIs(t, err, domain.ErrConflict)
+	})
+
+	t.Run("CreateRole_UnknownPermission", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		role := &dto.Role{Name: "auditor", Permissions: []string{"product:fly"}}
+		permissionRepo.On("FindRole", role.Name).Return(nil, domain.ErrNotFound).Once()
+		permissionRepo.On("ListPermissions").Return(catalog, nil).Once()
+
+		err := permissionService.CreateRole(context.Background(), role)
+		assert.EqualError(t, err, "unknown permission product:fly")
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("UpdateRole_AdminKeepsRoleManage", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		permissionRepo.On("FindRole", dto.UserRoleAdmin).Return(&dto.Role{Name: dto.UserRoleAdmin}, nil).Once()
+
+		role, err := permissionService.UpdateRole(context.Background(), dto.UserRoleAdmin, &dto.RoleUpdateRequest{Permissions: []string{dto.PermissionProductRead}})
+		assert.Error(t, err)
+		assert.Nil(t, role)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("UpdateRole_NotFound", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		permissionRepo.On("FindRole", dto.UserRole("auditor")).Return(nil, domain.NotFound("role_not_found", "role not found")).Once()
+
+		_, err := permissionService.UpdateRole(context.Background(), "auditor", &dto.RoleUpdateRequest{Permissions: []string{}})
+		assert.EqualError(t, err, "role not found")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("DeleteRole_Assigned", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		permissionRepo.On("CountUsersWithRole", dto.UserRoleStaff).Return(int64(3), nil).Once()
+
+		err := permissionService.DeleteRole(context.Background(), dto.UserRoleStaff)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+	})
+
+	t.Run("DeleteRole_Admin", func(t *testing.T) {
+		permissionService := services.NewPermissionService(new(mocks.MockPermissionRepository), new(mocks.MockTransactionRepository))
+
+		err := permissionService.DeleteRole(context.Background(), dto.UserRoleAdmin)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("DeleteRole_Success", func(t *testing.T) {
+		permissionRepo := new(mocks.MockPermissionRepository)
+		permissionService := services.NewPermissionService(permissionRepo, new(mocks.MockTransactionRepository))
+
+		permissionRepo.On("CountUsersWithRole", dto.UserRole("auditor")).Return(int64(0), nil).Once()
+		permissionRepo.On("DeleteRole", dto.UserRole("auditor")).Return(nil).Once()
+
+		err := permissionService.DeleteRole(context.Background(), "auditor")
+		assert.NoError(t, err)
+	})
+}
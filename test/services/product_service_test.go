@@ -1,21 +1,23 @@
 package services_test
 
 import (
-	"database/sql"
-	"errors"
+	"context"
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestCreateProduct(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
-	service := services.NewProductService(mockRepo)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), mockTx, newAuditRepository())
 
 	product := &dto.Product{
 		ID:       uuid.New(),
@@ -25,52 +27,52 @@ func TestCreateProduct(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByName", product.Name).Return((*dto.Product)(nil), 0, sql.ErrNoRows).Once()
-		mockRepo.On("FindBySKU", product.SKU).Return((*dto.Product)(nil), 0, sql.ErrNoRows).Once()
-		mockRepo.On("Save", product).Return(nil).Once()
+		mockRepo.On("FindByName", product.Name).Return((*dto.Product)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("FindBySKU", product.SKU).Return((*dto.Product)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("SaveWithTransaction", mock.Anything, product).Return(product, nil).Once()
 
-		statusCode, err := service.Create(product)
+		created, err := service.Create(context.Background(), allLocations, auditActor, product)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 201, statusCode)
+		assert.Equal(t, product, created)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Product Name Exists", func(t *testing.T) {
-		mockRepo.On("FindByName", product.Name).Return(product, 200, nil).Once()
+		mockRepo.On("FindByName", product.Name).Return(product, nil).Once()
 
-		statusCode, err := service.Create(product)
+		_, err := service.Create(context.Background(), allLocations, auditActor, product)
 
 		assert.Error(t, err)
-		assert.Equal(t, 401, statusCode)
+		assert.ErrorIs(t, err, domain.ErrConflict)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Product SKU Exists", func(t *testing.T) {
-		mockRepo.On("FindByName", product.Name).Return((*dto.Product)(nil), 0, sql.ErrNoRows).Once()
-		mockRepo.On("FindBySKU", product.SKU).Return(product, 200, nil).Once()
+		mockRepo.On("FindByName", product.Name).Return((*dto.Product)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("FindBySKU", product.SKU).Return(product, nil).Once()
 
-		statusCode, err := service.Create(product)
+		_, err := service.Create(context.Background(), allLocations, auditActor, product)
 
 		assert.Error(t, err)
-		assert.Equal(t, 401, statusCode)
+		assert.ErrorIs(t, err, domain.ErrConflict)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Server Error", func(t *testing.T) {
-		mockRepo.On("FindByName", product.Name).Return((*dto.Product)(nil), 500, assert.AnError).Once()
+		mockRepo.On("FindByName", product.Name).Return((*dto.Product)(nil), assert.AnError).Once()
 
-		statusCode, err := service.Create(product)
+		_, err := service.Create(context.Background(), allLocations, auditActor, product)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		mockRepo.AssertExpectations(t)
 	})
 }
 
 func TestGetProductByID(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
-	service := services.NewProductService(mockRepo)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), mockTx, newAuditRepository())
 
 	productID := uuid.New()
 	product := &dto.Product{
@@ -81,21 +83,20 @@ func TestGetProductByID(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByID", productID).Return(product, 200, nil).Once()
+		mockRepo.On("FindByID", productID).Return(product, nil).Once()
 
-		result, statusCode, err := service.GetByID(productID)
+		result, err := service.GetByID(context.Background(), allLocations, productID)
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Equal(t, product, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Product Not Found", func(t *testing.T) {
-		mockRepo.On("FindByID", productID).Return((*dto.Product)(nil), 404, errors.New("not found")).Once()
+		mockRepo.On("FindByID", productID).Return((*dto.Product)(nil), domain.NotFound("product_not_found", "not found")).Once()
 
-		result, statusCode, err := service.GetByID(productID)
+		result, err := service.GetByID(context.Background(), allLocations, productID)
 		assert.Error(t, err)
-		assert.Equal(t, 404, statusCode)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
@@ -103,7 +104,8 @@ func TestGetProductByID(t *testing.T) {
 
 func TestGetAllProducts(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
-	service := services.NewProductService(mockRepo)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), mockTx, newAuditRepository())
 
 	pagination := &web.PaginationRequest{
 		Page: 1,
@@ -116,32 +118,29 @@ func TestGetAllProducts(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("GetAllProduct", pagination).Return(products, nil).Once()
+		mockRepo.On("GetAllProduct", allLocations, pagination).Return(products, &web.PageInfo{TotalItems: int64(len(products))}, nil).Once()
 
-		result, statusCode, err := service.GetAll(pagination)
+		result, _, err := service.GetAll(context.Background(), allLocations, pagination)
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Equal(t, products, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("No Products Found", func(t *testing.T) {
-		mockRepo.On("GetAllProduct", pagination).Return(([]*dto.Product)(nil), nil).Once()
+		mockRepo.On("GetAllProduct", allLocations, pagination).Return(([]*dto.Product)(nil), &web.PageInfo{}, nil).Once()
 
-		result, statusCode, err := service.GetAll(pagination)
+		result, _, err := service.GetAll(context.Background(), allLocations, pagination)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Empty(t, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Server Error", func(t *testing.T) {
-		mockRepo.On("GetAllProduct", pagination).Return(([]*dto.Product)(nil), assert.AnError).Once()
+		mockRepo.On("GetAllProduct", allLocations, pagination).Return(([]*dto.Product)(nil), (*web.PageInfo)(nil), assert.AnError).Once()
 
-		result, statusCode, err := service.GetAll(pagination)
+		result, _, err := service.GetAll(context.Background(), allLocations, pagination)
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
@@ -149,7 +148,8 @@ func TestGetAllProducts(t *testing.T) {
 
 func TestUpdateProduct(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
-	service := services.NewProductService(mockRepo)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), mockTx, newAuditRepository())
 
 	product := &dto.Product{
 		ID:       uuid.New(),
@@ -159,47 +159,264 @@ func TestUpdateProduct(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByID", product.ID).Return(product, 200, nil).Once()
-		mockRepo.On("Update", product).Return(nil).Once()
+		mockRepo.On("FindByID", product.ID).Return(product, nil).Once()
+		mockRepo.On("UpdateWithTransaction", mock.Anything, product).Return(nil).Once()
 
-		statusCode, err := service.Update(product)
+		err := service.Update(context.Background(), allLocations, auditActor, product)
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Product Not Found", func(t *testing.T) {
-		mockRepo.On("FindByID", product.ID).Return((*dto.Product)(nil), 404, errors.New("not found")).Once()
+		mockRepo.On("FindByID", product.ID).Return((*dto.Product)(nil), domain.NotFound("product_not_found", "not found")).Once()
 
-		statusCode, err := service.Update(product)
+		err := service.Update(context.Background(), allLocations, auditActor, product)
 		assert.Error(t, err)
-		assert.Equal(t, 404, statusCode)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Move To Other Location", func(t *testing.T) {
+		own := uuid.New()
+		current := &dto.Product{ID: product.ID, LocationID: own}
+		moved := &dto.Product{ID: product.ID, Name: product.Name, SKU: product.SKU, LocationID: uuid.New()}
+		mockRepo.On("FindByID", product.ID).Return(current, nil).Once()
+
+		err := service.Update(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{own}}, auditActor, moved)
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Update", moved)
+	})
+
+	t.Run("Deleted Location", func(t *testing.T) {
+		moved := &dto.Product{ID: product.ID, Name: product.Name, SKU: product.SKU, LocationID: uuid.New()}
+		mockRepo.On("FindByID", product.ID).Return(product, nil).Once()
+		mockRepo.On("UpdateWithTransaction", mock.Anything, moved).Return(domain.NotFound("location_not_found", "location not found")).Once()
+
+		err := service.Update(context.Background(), allLocations, auditActor, moved)
+		assert.EqualError(t, err, "location not found")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestPatchProduct(t *testing.T) {
+	mockRepo := new(mocks.MockProductRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), new(mocks.MockTransactionRepository), newAuditRepository())
+
+	own := uuid.New()
+	current := &dto.Product{ID: uuid.New(), Name: "Product", SKU: "SKU001", Quantity: 7, LocationID: own, Version: 3}
+	scope := &dto.LocationScope{LocationIDs: []uuid.UUID{own}}
+
+	t.Run("Success", func(t *testing.T) {
+		name := "Renamed"
+		patch := &dto.ProductPatch{Name: &name}
+		patched := &dto.Product{ID: current.ID, Name: name, SKU: current.SKU, Quantity: 7, LocationID: own, Version: 4}
+		mockRepo.On("FindByID", current.ID).Return(current, nil).Once()
+		mockRepo.On("PatchWithTransaction", mock.Anything, current.ID, patch, int64(3)).Return(patched, nil).Once()
+
+		product, err := service.Patch(context.Background(), scope, auditActor, current.ID, patch, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, patched, product)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Version Mismatch", func(t *testing.T) {
+		patch := &dto.ProductPatch{}
+		mockRepo.On("FindByID", current.ID).Return(current, nil).Once()
+		mockRepo.On("PatchWithTransaction", mock.Anything, current.ID, patch, int64(2)).Return(nil, domain.PreconditionFailed("version_mismatch", "product was changed since it was read")).Once()
+
+		_, err := service.Patch(context.Background(), scope, auditActor, current.ID, patch, 2)
+
+		assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Move To Other Location", func(t *testing.T) {
+		other := uuid.New()
+		patch := &dto.ProductPatch{LocationID: &other}
+		mockRepo.On("FindByID", current.ID).Return(current, nil).Once()
+
+		_, err := service.Patch(context.Background(), scope, auditActor, current.ID, patch, 0)
+
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "Patch", current.ID, patch, int64(0))
+	})
+
+	t.Run("Deleted Location", func(t *testing.T) {
+		patch := &dto.ProductPatch{LocationID: &own}
+		mockRepo.On("FindByID", current.ID).Return(current, nil).Once()
+		mockRepo.On("PatchWithTransaction", mock.Anything, current.ID, patch, int64(3)).Return(nil, domain.NotFound("location_not_found", "location not found")).Once()
+
+		product, err := service.Patch(context.Background(), scope, auditActor, current.ID, patch, 3)
+
+		assert.Nil(t, product)
+		assert.EqualError(t, err, "location not found")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
 		mockRepo.AssertExpectations(t)
 	})
 }
 
 func TestDeleteProduct(t *testing.T) {
 	mockRepo := new(mocks.MockProductRepository)
-	service := services.NewProductService(mockRepo)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), mockTx, newAuditRepository())
 
 	productID := uuid.New()
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByID", productID).Return(&dto.Product{}, 200, nil).Once()
-		mockRepo.On("Delete", productID).Return(200, nil).Once()
+		mockRepo.On("FindByID", productID).Return(&dto.Product{}, nil).Once()
+		mockRepo.On("DeleteWithTransaction", mock.Anything, productID, auditActor.UserID).Return(nil).Once()
 
-		statusCode, err := service.Delete(productID)
+		err := service.Delete(context.Background(), allLocations, auditActor, productID)
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Product Not Found", func(t *testing.T) {
-		mockRepo.On("FindByID", productID).Return((*dto.Product)(nil), 404, errors.New("not found")).Once()
+		mockRepo.On("FindByID", productID).Return((*dto.Product)(nil), domain.NotFound("product_not_found", "not found")).Once()
 
-		statusCode, err := service.Delete(productID)
+		err := service.Delete(context.Background(), allLocations, auditActor, productID)
 		assert.Error(t, err)
-		assert.Equal(t, 404, statusCode)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Product Has Stock", func(t *testing.T) {
+		mockRepo.On("FindByID", productID).Return(&dto.Product{Quantity: 3}, nil).Once()
+		mockRepo.On("DeleteWithTransaction", mock.Anything, productID, auditActor.UserID).Return(domain.Conflict("product_has_stock", "product still has stock")).Once()
+
+		err := service.Delete(context.Background(), allLocations, auditActor, productID)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestRestoreProduct(t *testing.T) {
+	mockRepo := new(mocks.MockProductRepository)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, new(mocks.MockLocationRepository), mockTx, newAuditRepository())
+
+	deleted := &dto.Product{ID: uuid.New(), Name: "Product 1", SKU: "SKU001", LocationID: uuid.New()}
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("FindDeletedByID", deleted.ID).Return(deleted, nil).Once()
+		mockRepo.On("FindByName", deleted.Name).Return((*dto.Product)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("RestoreWithTransaction", mock.Anything, deleted.ID).Return(deleted, nil).Once()
+
+		restored, err := service.Restore(context.Background(), allLocations, auditActor, deleted.ID)
+
+		assert.NoError(t, err)
+		assert.Equal(t, deleted, restored)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Name Taken By Live Product", func(t *testing.T) {
+		mockRepo.On("FindDeletedByID", deleted.ID).Return(deleted, nil).Once()
+		mockRepo.On("FindByName", deleted.Name).Return(&dto.Product{ID: uuid.New(), Name: deleted.Name}, nil).Once()
+
+		_, err := service.Restore(context.Background(), allLocations, auditActor, deleted.ID)
+
+		assert.ErrorIs(t, err, domain.ErrConflict)
+		mockRepo.AssertNumberOfCalls(t, "RestoreWithTransaction", 1)
+	})
+
+	t.Run("Other Location", func(t *testing.T) {
+		mockRepo.On("FindDeletedByID", deleted.ID).Return(deleted, nil).Once()
+
+		_, err := service.Restore(context.Background(), &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}, auditActor, deleted.ID)
+
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("Not Deleted", func(t *testing.T) {
+		mockRepo.On("FindDeletedByID", deleted.ID).Return((*dto.Product)(nil), domain.ErrNotFound).Once()
+
+		_, err := service.Restore(context.Background(), allLocations, auditActor, deleted.ID)
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestImportProducts(t *testing.T) {
+	mockRepo := new(mocks.MockProductRepository)
+	mockLocations := new(mocks.MockLocationRepository)
+	mockTx := new(mocks.MockTransactionRepository)
+	service := services.NewProductService(mockRepo, mockLocations, mockTx, newAuditRepository())
+
+	locationID := uuid.New()
+	newRows := func() []*dto.ProductImportRow {
+		return []*dto.ProductImportRow{
+			{Row: 2, Product: &dto.Product{Name: "Product 1", SKU: "SKU001", Quantity: 5, LocationID: locationID}},
+			{Row: 3, Product: &dto.Product{Name: "Product 2", SKU: "SKU002", Quantity: 7, LocationID: locationID}},
+		}
+	}
+
+	t.Run("Dry Run Reports Duplicates", func(t *testing.T) {
+		rows := append(newRows(), &dto.ProductImportRow{Row: 4, Product: &dto.Product{Name: "Product 1", SKU: "SKU003", Quantity: 1, LocationID: locationID}})
+		existing := []*dto.Product{{Name: "Other", SKU: "SKU002"}}
+
+		mockRepo.On("FindByNamesOrSKUs", []string{"Product 1", "Product 2", "Product 1"}, []string{"SKU001", "SKU002", "SKU003"}).Return(existing, nil).Once()
+		mockLocations.On("FindByIDs", []uuid.UUID{locationID, locationID, locationID}).Return([]*dto.Location{{ID: locationID}}, nil).Once()
+
+		report, err := service.Import(context.Background(), allLocations, auditActor, rows, true)
+
+		assert.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.Equal(t, 3, report.TotalRows)
+		assert.Equal(t, 1, report.Imported)
+		assert.Equal(t, []dto.ProductImportRowError{
+			{Row: 3, Errors: []string{"product sku is exists"}},
+			{Row: 4, Errors: []string{"product name is duplicated in row 2"}},
+		}, report.Errors)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Invalid Rows Are Not Committed", func(t *testing.T) {
+		rows := append(newRows(), &dto.ProductImportRow{Row: 4, Errors: []string{"quantity must be an integer"}})
+
+		mockRepo.On("FindByNamesOrSKUs", []string{"Product 1", "Product 2"}, []string{"SKU001", "SKU002"}).Return([]*dto.Product{}, nil).Once()
+		mockLocations.On("FindByIDs", []uuid.UUID{locationID, locationID}).Return([]*dto.Location{{ID: locationID}}, nil).Once()
+
+		report, err := service.Import(context.Background(), allLocations, auditActor, rows, false)
+
+		assert.ErrorIs(t, err, domain.ErrUnprocessable)
+		assert.Equal(t, 0, report.Imported)
+		assert.Len(t, report.Errors, 1)
+		mockRepo.AssertExpectations(t)
+		mockTx.AssertNotCalled(t, "Transaction", mock.Anything)
+	})
+
+	t.Run("Unknown Location Is Reported Per Row", func(t *testing.T) {
+		deletedLocation := uuid.New()
+		rows := append(newRows(), &dto.ProductImportRow{Row: 4, Product: &dto.Product{Name: "Product 3", SKU: "SKU003", Quantity: 1, LocationID: deletedLocation}})
+
+		mockRepo.On("FindByNamesOrSKUs", []string{"Product 1", "Product 2", "Product 3"}, []string{"SKU001", "SKU002", "SKU003"}).Return([]*dto.Product{}, nil).Once()
+		mockLocations.On("FindByIDs", []uuid.UUID{locationID, locationID, deletedLocation}).Return([]*dto.Location{{ID: locationID}}, nil).Once()
+
+		report, err := service.Import(context.Background(), allLocations, auditActor, rows, false)
+
+		assert.ErrorIs(t, err, domain.ErrUnprocessable)
+		assert.Equal(t, []dto.ProductImportRowError{
+			{Row: 4, Errors: []string{"location not found"}},
+		}, report.Errors)
+		mockLocations.AssertExpectations(t)
+		mockTx.AssertNotCalled(t, "Transaction", mock.Anything)
+	})
+
+	t.Run("Commit", func(t *testing.T) {
+		mockRepo.On("FindByNamesOrSKUs", []string{"Product 1", "Product 2"}, []string{"SKU001", "SKU002"}).Return([]*dto.Product{}, nil).Once()
+		mockLocations.On("FindByIDs", []uuid.UUID{locationID, locationID}).Return([]*dto.Location{{ID: locationID}}, nil).Once()
+		mockTx.On("Transaction", mock.Anything).Return(nil).Once()
+		mockRepo.On("SaveBatchWithTransaction", mock.Anything, mock.Anything).Return(nil).Once()
+
+		report, err := service.Import(context.Background(), allLocations, auditActor, newRows(), false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Imported)
+		assert.Empty(t, report.Errors)
 		mockRepo.AssertExpectations(t)
+		mockTx.AssertExpectations(t)
 	})
 }
@@ -0,0 +1,127 @@
+package services_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/services"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestServiceAccountService(t *testing.T) {
+	serviceAccountRepo := new(mocks.MockServiceAccountRepository)
+	userRepo := new(mocks.MockUserRepository)
+	permissionRepo := new(mocks.MockPermissionRepository)
+	service := services.NewServiceAccountService(serviceAccountRepo, userRepo, permissionRepo, newAuditRepository())
+
+	adminID := uuid.New()
+	account := &dto.User{ID: uuid.New(), Name: "ERP", Role: dto.UserRoleStaff, Active: true, ServiceAccount: true}
+	staffRole := &dto.Role{Name: dto.UserRoleStaff, Permissions: []string{dto.PermissionOrderReceive, dto.PermissionOrderRead}}
+
+	t.Run("CreateServiceAccount", func(t *testing.T) {
+		serviceAccountRepo.On("SaveServiceAccountWithTransaction", mock.Anything, mock.MatchedBy(func(user *dto.User) bool {
+			return user.ServiceAccount && user.Name == "ERP" && strings.HasSuffix(user.Email, ".invalid")
+		})).Return(nil).Once()
+
+		created, err := service.CreateServiceAccount(context.Background(), auditActor, &dto.ServiceAccountRequest{Name: "ERP", Role: dto.UserRoleStaff})
+
+		assert.NoError(t, err)
+		assert.True(t, created.ServiceAccount)
+		assert.False(t, created.Scope.All)
+		serviceAccountRepo.AssertExpectations(t)
+	})
+
+	t.Run("CreateAPIKey", func(t *testing.T) {
+		userRepo.On("FindByID", account.ID).Return(account, nil).Once()
+		permissionRepo.On("FindRole", dto.UserRoleStaff).Return(staffRole, nil).Once()
+
+		var saved *dto.APIKey
+		serviceAccountRepo.On("SaveAPIKey", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*dto.APIKey)
+		}).Return(nil).Once()
+
+		key, err := service.CreateAPIKey(context.Background(), adminID, account.ID, &dto.APIKeyRequest{
+			Name:   "conveyor",
+			Scopes: []string{dto.PermissionOrderReceive, dto.PermissionOrderReceive},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(key.Key, utils.APIKeyPrefix))
+		assert.True(t, strings.HasPrefix(key.Key, saved.Prefix))
+		assert.Equal(t, utils.HashToken(key.Key), saved.KeyHash)
+		assert.Equal(t, []string{dto.PermissionOrderReceive}, saved.Scopes)
+		assert.Equal(t, adminID, *saved.CreatedBy)
+	})
+
+	t.Run("CreateAPIKey_ScopeOutsideRole", func(t *testing.T) {
+		userRepo.On("FindByID", account.ID).Return(account, nil).Once()
+		permissionRepo.On("FindRole", dto.UserRoleStaff).Return(staffRole, nil).Once()
+
+		_, err := service.CreateAPIKey(context.Background(), adminID, account.ID, &dto.APIKeyRequest{
+			Name:   "too-wide",
+			Scopes: []string{dto.PermissionUserWrite},
+		})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("CreateAPIKey_PastExpiry", func(t *testing.T) {
+		userRepo.On("FindByID", account.ID).Return(account, nil).Once()
+		past := time.Now().Add(-time.Hour)
+
+		_, err := service.CreateAPIKey(context.Background(), adminID, account.ID, &dto.APIKeyRequest{
+			Name:      "expired",
+			Scopes:    []string{dto.PermissionOrderReceive},
+			ExpiresAt: &past,
+		})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("CreateAPIKey_NotServiceAccount", func(t *testing.T) {
+		person := &dto.User{ID: uuid.New(), Role: dto.UserRoleStaff}
+		userRepo.On("FindByID", person.ID).Return(person, nil).Once()
+
+		_, err := service.CreateAPIKey(context.Background(), adminID, person.ID, &dto.APIKeyRequest{
+			Name:   "person",
+			Scopes: []string{dto.PermissionOrderReceive},
+		})
+
+		assert.EqualError(t, err, "service account not found")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("RevokeAPIKey_NotFound", func(t *testing.T) {
+		keyID := uuid.New()
+		serviceAccountRepo.On("RevokeAPIKey", account.ID, keyID).Return(domain.NotFound("api_key_not_found", "api key not found")).Once()
+
+		err := service.RevokeAPIKey(context.Background(), account.ID, keyID)
+
+		assert.EqualError(t, err, "api key not found")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("AuthenticateAPIKey", func(t *testing.T) {
+		identity := &dto.APIKeyIdentity{KeyID: uuid.New(), Scopes: []string{dto.PermissionOrderReceive}, User: *account}
+		serviceAccountRepo.On("UseAPIKey", utils.HashToken("wms_valid")).Return(identity, nil).Once()
+		serviceAccountRepo.On("UseAPIKey", utils.HashToken("wms_revoked")).Return(nil, domain.ErrNotFound).Once()
+
+		found, err := service.AuthenticateAPIKey(context.Background(), "wms_valid")
+		assert.NoError(t, err)
+		assert.Equal(t, account.ID, found.User.ID)
+
+		_, err = service.AuthenticateAPIKey(context.Background(), "wms_revoked")
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	})
+}
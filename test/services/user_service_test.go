@@ -1,10 +1,20 @@
 package services_test
 
 import (
-	"database/sql"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
 	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
 	"github.com/nabilwafi/warehouse-management-system/src/models/web"
 	"github.com/nabilwafi/warehouse-management-system/src/services"
@@ -12,11 +22,17 @@ import (
 	mocks "github.com/nabilwafi/warehouse-management-system/test/mocks"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestLogin(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	mockToken := new(mocks.MockTokenRepository)
+	mockAuthEvent := new(mocks.MockAuthEventRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), mockAuthEvent, new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+	const ip = "203.0.113.7"
 
 	loginRequest := &dto.LoginRequest{
 		Email:    "test@example.com",
@@ -31,16 +47,31 @@ func TestLogin(t *testing.T) {
 		Email:    "test@example.com",
 		Password: passwordHash,
 		Role:     "user",
+		Active:   true,
+	}
+
+	noFailures := &dto.LoginFailures{}
+	recorded := func(reason string) any {
+		return mock.MatchedBy(func(event *dto.AuthEvent) bool {
+			return event.Reason == reason && event.Success == (reason == "") && event.IP == ip && event.Email == loginRequest.Email
+		})
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByEmail", loginRequest.Email).Return(user, 200, nil).Once()
-		token, statusCode, err := service.Login(loginRequest)
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(noFailures, nil).Once()
+		mockRepo.On("FindByEmail", loginRequest.Email).Return(user, nil).Once()
+		mockToken.On("SaveRefreshToken", mock.MatchedBy(func(token *dto.RefreshToken) bool {
+			return token.UserID == user.ID && token.TokenHash != ""
+		})).Return(nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", recorded("")).Return(nil).Once()
+		token, _, err := service.Login(context.Background(), loginRequest, ip)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
-		assert.NotEmpty(t, token)
+		assert.NotEmpty(t, token.AccessToken)
+		assert.NotEmpty(t, token.RefreshToken)
 		mockRepo.AssertExpectations(t)
+		mockToken.AssertExpectations(t)
+		mockAuthEvent.AssertExpectations(t)
 	})
 
 	t.Run("Wrong Password", func(t *testing.T) {
@@ -48,89 +79,288 @@ func TestLogin(t *testing.T) {
 			Email:    "test@example.com",
 			Password: "wrongpassword",
 		}
-		mockRepo.On("FindByEmail", wrongPasswordRequest.Email).Return(user, 200, nil).Once()
+		mockAuthEvent.On("CountLoginFailures", wrongPasswordRequest.Email, ip, mock.Anything).Return(noFailures, nil).Once()
+		mockRepo.On("FindByEmail", wrongPasswordRequest.Email).Return(user, nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", recorded(dto.AuthReasonWrongPassword)).Return(nil).Once()
 
-		token, statusCode, err := service.Login(wrongPasswordRequest)
+		token, _, err := service.Login(context.Background(), wrongPasswordRequest, ip)
 
 		assert.Error(t, err)
-		assert.Equal(t, 400, statusCode)
-		assert.Equal(t, "", token)
-		assert.Equal(t, "wrong password", err.Error())
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+		assert.Nil(t, token)
+		assert.Equal(t, "invalid email or password", err.Error())
 		mockRepo.AssertExpectations(t)
+		mockAuthEvent.AssertExpectations(t)
+	})
+
+	t.Run("Deactivated", func(t *testing.T) {
+		deactivated := *user
+		deactivated.Active = false
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(noFailures, nil).Once()
+		mockRepo.On("FindByEmail", loginRequest.Email).Return(&deactivated, nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", recorded(dto.AuthReasonDeactivated)).Return(nil).Once()
+
+		token, _, err := service.Login(context.Background(), loginRequest, ip)
+
+		assert.EqualError(t, err, "user is deactivated")
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		assert.Nil(t, token)
+		mockToken.AssertNumberOfCalls(t, "SaveRefreshToken", 1)
 	})
 
 	t.Run("User Not Found", func(t *testing.T) {
-		mockRepo.On("FindByEmail", loginRequest.Email).Return((*dto.User)(nil), 404, sql.ErrNoRows).Once()
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(noFailures, nil).Once()
+		mockRepo.On("FindByEmail", loginRequest.Email).Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		mockAuthEvent.On("SaveAuthEvent", recorded(dto.AuthReasonUnknownEmail)).Return(nil).Once()
 
-		token, statusCode, err := service.Login(loginRequest)
+		token, _, err := service.Login(context.Background(), loginRequest, ip)
 
 		assert.Error(t, err)
-		assert.Equal(t, 404, statusCode)
-		assert.Equal(t, "", token)
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+		assert.Equal(t, "invalid email or password", err.Error())
+		assert.Nil(t, token)
 		mockRepo.AssertExpectations(t)
+		mockAuthEvent.AssertExpectations(t)
+	})
+
+	t.Run("Account Locked", func(t *testing.T) {
+		failures := &dto.LoginFailures{Account: 6, AccountLast: time.Now()}
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(failures, nil).Once()
+
+		token, _, err := service.Login(context.Background(), loginRequest, ip)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrTooManyRequests)
+		assert.Nil(t, token)
+		mockRepo.AssertNumberOfCalls(t, "FindByEmail", 4)
+		mockAuthEvent.AssertNotCalled(t, "SaveAuthEvent", recorded(dto.AuthReasonLocked))
+		mockAuthEvent.AssertExpectations(t)
+	})
+
+	t.Run("Address Locked", func(t *testing.T) {
+		failures := &dto.LoginFailures{IP: 20, IPLast: time.Now()}
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(failures, nil).Once()
+
+		_, _, err := service.Login(context.Background(), loginRequest, ip)
+
+		assert.ErrorIs(t, err, domain.ErrTooManyRequests)
+	})
+
+	t.Run("Email Case Shares The Lock", func(t *testing.T) {
+		failures := &dto.LoginFailures{Account: 6, AccountLast: time.Now()}
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(failures, nil).Once()
+
+		_, _, err := service.Login(context.Background(), &dto.LoginRequest{Email: " Test@Example.COM ", Password: "guess"}, ip)
+
+		assert.ErrorIs(t, err, domain.ErrTooManyRequests)
+		mockAuthEvent.AssertExpectations(t)
+	})
+
+	t.Run("Lockout Expired", func(t *testing.T) {
+		// Six failures lock the account for two minutes.
+		failures := &dto.LoginFailures{Account: 6, AccountLast: time.Now().Add(-3 * time.Minute)}
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(failures, nil).Once()
+		mockRepo.On("FindByEmail", loginRequest.Email).Return(user, nil).Once()
+		mockToken.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", recorded("")).Return(nil).Once()
+
+		_, _, err := service.Login(context.Background(), loginRequest, ip)
+
+		assert.NoError(t, err)
 	})
 
 	t.Run("Internal Server Error", func(t *testing.T) {
-		mockRepo.On("FindByEmail", loginRequest.Email).Return((*dto.User)(nil), 500, assert.AnError).Once()
+		mockAuthEvent.On("CountLoginFailures", loginRequest.Email, ip, mock.Anything).Return(noFailures, nil).Once()
+		mockRepo.On("FindByEmail", loginRequest.Email).Return((*dto.User)(nil), assert.AnError).Once()
 
-		token, statusCode, err := service.Login(loginRequest)
+		token, _, err := service.Login(context.Background(), loginRequest, ip)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
-		assert.Equal(t, "", token)
+		assert.Nil(t, token)
 		mockRepo.AssertExpectations(t)
 	})
 }
 
 func TestRegister(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	mockToken := new(mocks.MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
 
 	registerRequest := &dto.RegisterRequest{
 		Name:     "New User",
 		Email:    "newuser@example.com",
 		Password: "password123",
+		Role:     dto.UserRoleAdmin,
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByEmail", registerRequest.Email).Return((*dto.User)(nil), 404, sql.ErrNoRows).Once()
-		mockRepo.On("Save", mock.Anything).Return(nil).Once()
+		mockRepo.On("FindByEmail", registerRequest.Email).Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("SaveWithTransaction", mock.Anything, mock.MatchedBy(func(register *dto.RegisterRequest) bool {
+			return register.Role == dto.UserRoleStaff && register.ID != uuid.Nil
+		})).Return(nil).Once()
 
-		statusCode, err := service.Register(registerRequest)
+		err := service.Register(context.Background(), anonymousActor, registerRequest)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 201, statusCode)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Email Already Exists", func(t *testing.T) {
 		existingUser := &dto.User{Email: registerRequest.Email}
-		mockRepo.On("FindByEmail", registerRequest.Email).Return(existingUser, 200, nil).Once()
+		mockRepo.On("FindByEmail", registerRequest.Email).Return(existingUser, nil).Once()
 
-		statusCode, err := service.Register(registerRequest)
+		err := service.Register(context.Background(), anonymousActor, registerRequest)
 
 		assert.Error(t, err)
-		assert.Equal(t, 401, statusCode)
+		assert.ErrorIs(t, err, domain.ErrConflict)
 		assert.Equal(t, "email is exists", err.Error())
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Server Error on Save", func(t *testing.T) {
-		mockRepo.On("FindByEmail", registerRequest.Email).Return((*dto.User)(nil), 404, sql.ErrNoRows).Once()
-		mockRepo.On("Save", mock.Anything).Return(assert.AnError).Once()
+		mockRepo.On("FindByEmail", registerRequest.Email).Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		mockRepo.On("SaveWithTransaction", mock.Anything, mock.Anything).Return(assert.AnError).Once()
 
-		statusCode, err := service.Register(registerRequest)
+		err := service.Register(context.Background(), anonymousActor, registerRequest)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		assert.Equal(t, assert.AnError, err)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		mockRepo := new(mocks.MockUserRepository)
+		service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationDisabled, config.MFAConfig{})
+
+		err := service.Register(context.Background(), anonymousActor, &dto.RegisterRequest{Email: "new@example.com", Password: "password123"})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockRepo.AssertNotCalled(t, "SaveWithTransaction", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRegisterWithInvite(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockToken := new(mocks.MockTokenRepository)
+	mockInvite := new(mocks.MockInviteRepository)
+	service := services.NewUserService(mockRepo, mockToken, mockInvite, new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationInvite, config.MFAConfig{})
+
+	var tx *sqlx.Tx
+
+	t.Run("Success", func(t *testing.T) {
+		request := &dto.RegisterRequest{Email: "invited@example.com", Password: "password123", Name: "Invited", InviteCode: "invite-code"}
+
+		mockRepo.On("FindByEmail", request.Email).Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		mockInvite.On("UseInviteWithTransaction", tx, utils.HashToken("invite-code"), mock.Anything).Return(&dto.Invite{Role: "auditor"}, nil).Once()
+		mockRepo.On("SaveWithTransaction", tx, mock.MatchedBy(func(register *dto.RegisterRequest) bool {
+			return register.Role == "auditor" && !register.AllLocations
+		})).Return(nil).Once()
+
+		err := service.Register(context.Background(), anonymousActor, request)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockInvite.AssertExpectations(t)
+	})
+
+	t.Run("Missing Code", func(t *testing.T) {
+		err := service.Register(context.Background(), anonymousActor, &dto.RegisterRequest{Email: "invited@example.com", Password: "password123"})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("Used Or Expired Code", func(t *testing.T) {
+		request := &dto.RegisterRequest{Email: "late@example.com", Password: "password123", InviteCode: "stale"}
+
+		mockRepo.On("FindByEmail", request.Email).Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		mockInvite.On("UseInviteWithTransaction", tx, utils.HashToken("stale"), mock.Anything).Return(nil, domain.ErrNotFound).Once()
+
+		err := service.Register(context.Background(), anonymousActor, request)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		assert.Equal(t, "invalid or expired invite code", err.Error())
+		mockRepo.AssertNumberOfCalls(t, "SaveWithTransaction", 1)
+	})
+
+	t.Run("Admin Gets All Locations", func(t *testing.T) {
+		request := &dto.RegisterRequest{Email: "admin2@example.com", Password: "password123", Name: "Admin", InviteCode: "admin-code"}
+
+		mockRepo.On("FindByEmail", request.Email).Return((*dto.User)(nil), domain.ErrNotFound).Once()
+		mockInvite.On("UseInviteWithTransaction", tx, utils.HashToken("admin-code"), mock.Anything).Return(&dto.Invite{Role: dto.UserRoleAdmin}, nil).Once()
+		mockRepo.On("SaveWithTransaction", tx, mock.MatchedBy(func(register *dto.RegisterRequest) bool {
+			return register.Role == dto.UserRoleAdmin && register.AllLocations
+		})).Return(nil).Once()
+
+		err := service.Register(context.Background(), anonymousActor, request)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestCreateInvite(t *testing.T) {
+	mockInvite := new(mocks.MockInviteRepository)
+	service := services.NewUserService(new(mocks.MockUserRepository), new(mocks.MockTokenRepository), mockInvite, new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationInvite, config.MFAConfig{})
+
+	adminID := uuid.New()
+
+	t.Run("Success", func(t *testing.T) {
+		var saved *dto.Invite
+		mockInvite.On("SaveInvite", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*dto.Invite)
+		}).Return(nil).Once()
+
+		invite, err := service.CreateInvite(context.Background(), adminID, &dto.InviteRequest{Role: dto.UserRoleStaff})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, invite.InviteCode)
+		assert.Equal(t, utils.HashToken(invite.InviteCode), saved.CodeHash)
+		assert.Equal(t, adminID, saved.CreatedBy)
+		assert.Equal(t, dto.UserRoleStaff, saved.Role)
+	})
+
+	t.Run("Not Invite Mode", func(t *testing.T) {
+		service := services.NewUserService(new(mocks.MockUserRepository), new(mocks.MockTokenRepository), mockInvite, new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+		_, err := service.CreateInvite(context.Background(), adminID, &dto.InviteRequest{Role: dto.UserRoleStaff})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+}
+
+func TestBootstrapAdmin(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	service := services.NewUserService(mockRepo, new(mocks.MockTokenRepository), new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationDisabled, config.MFAConfig{})
+
+	t.Run("Success", func(t *testing.T) {
+		mockRepo.On("SaveFirstAdmin", mock.MatchedBy(func(register *dto.RegisterRequest) bool {
+			return register.Role == dto.UserRoleAdmin && register.Password != "password123"
+		})).Return(nil).Once()
+
+		err := service.BootstrapAdmin(context.Background(), &dto.RegisterRequest{Email: "admin@example.com", Password: "password123"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("Admin Exists", func(t *testing.T) {
+		mockRepo.On("SaveFirstAdmin", mock.Anything).Return(domain.Conflict("admin_exists", "admin already exists")).Once()
+
+		err := service.BootstrapAdmin(context.Background(), &dto.RegisterRequest{Email: "second@example.com", Password: "password123"})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+		mockRepo.AssertExpectations(t)
+	})
 }
 
 func TestGetUserByID(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	mockToken := new(mocks.MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
 
 	userID := uuid.New()
 	user := &dto.User{
@@ -140,34 +370,32 @@ func TestGetUserByID(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("FindByID", userID).Return(user, 200, nil).Once()
+		mockRepo.On("FindByID", userID).Return(user, nil).Once()
 
-		result, statusCode, err := service.GetUserByID(userID)
+		result, err := service.GetUserByID(context.Background(), userID)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Equal(t, user, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("User Not Found", func(t *testing.T) {
-		mockRepo.On("FindByID", userID).Return((*dto.User)(nil), 404, sql.ErrNoRows).Once()
+		mockRepo.On("FindByID", userID).Return((*dto.User)(nil), domain.ErrNotFound).Once()
 
-		result, statusCode, err := service.GetUserByID(userID)
+		result, err := service.GetUserByID(context.Background(), userID)
 
 		assert.Error(t, err)
-		assert.Equal(t, 404, statusCode)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Server Error", func(t *testing.T) {
-		mockRepo.On("FindByID", userID).Return((*dto.User)(nil), 500, assert.AnError).Once()
+		mockRepo.On("FindByID", userID).Return((*dto.User)(nil), assert.AnError).Once()
 
-		result, statusCode, err := service.GetUserByID(userID)
+		result, err := service.GetUserByID(context.Background(), userID)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
@@ -175,7 +403,8 @@ func TestGetUserByID(t *testing.T) {
 
 func TestGetAllUser(t *testing.T) {
 	mockRepo := new(mocks.MockUserRepository)
-	service := services.NewUserService(mockRepo)
+	mockToken := new(mocks.MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
 
 	pagination := &web.PaginationRequest{
 		Page: 1,
@@ -196,35 +425,557 @@ func TestGetAllUser(t *testing.T) {
 	}
 
 	t.Run("Success", func(t *testing.T) {
-		mockRepo.On("GetAll", pagination).Return(users, nil).Once()
+		mockRepo.On("GetAll", pagination).Return(users, &web.PageInfo{TotalItems: int64(len(users))}, nil).Once()
 
-		result, statusCode, err := service.GetAllUser(pagination)
+		result, _, err := service.GetAllUser(context.Background(), pagination)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Equal(t, users, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("No Users Found", func(t *testing.T) {
-		mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), nil).Once()
+		mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), &web.PageInfo{}, nil).Once()
 
-		result, statusCode, err := service.GetAllUser(pagination)
+		result, _, err := service.GetAllUser(context.Background(), pagination)
 
 		assert.NoError(t, err)
-		assert.Equal(t, 200, statusCode)
 		assert.Empty(t, result)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("Internal Server Error", func(t *testing.T) {
-		mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), assert.AnError).Once()
+		mockRepo.On("GetAll", pagination).Return(([]*dto.User)(nil), (*web.PageInfo)(nil), assert.AnError).Once()
 
-		result, statusCode, err := service.GetAllUser(pagination)
+		result, _, err := service.GetAllUser(context.Background(), pagination)
 
 		assert.Error(t, err)
-		assert.Equal(t, 500, statusCode)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestRefresh(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockToken := new(mocks.MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+	user := &dto.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com", Role: dto.UserRoleStaff, Active: true}
+	refreshToken := "refresh-token"
+
+	t.Run("Success", func(t *testing.T) {
+		stored := &dto.RefreshToken{ID: uuid.New(), UserID: user.ID, FamilyID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+
+		mockToken.On("FindRefreshTokenByHash", utils.HashToken(refreshToken)).Return(stored, nil).Once()
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		mockToken.On("RotateRefreshToken", stored.ID, mock.Anything).Return(true, nil).Once()
+		mockToken.On("SaveRefreshToken", mock.MatchedBy(func(token *dto.RefreshToken) bool {
+			return token.FamilyID == stored.FamilyID && token.ID != stored.ID
+		})).Return(nil).Once()
+
+		tokens, err := service.Refresh(context.Background(), refreshToken)
+
+		assert.NoError(t, err)
+		assert.NotEqual(t, refreshToken, tokens.RefreshToken)
+		mockRepo.AssertExpectations(t)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("Reused Token Revokes Family", func(t *testing.T) {
+		revokedAt := time.Now()
+		stored := &dto.RefreshToken{ID: uuid.New(), UserID: user.ID, FamilyID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}
+
+		mockToken.On("FindRefreshTokenByHash", utils.HashToken(refreshToken)).Return(stored, nil).Once()
+		mockToken.On("RevokeRefreshTokenFamily", stored.FamilyID).Return(nil).Once()
+
+		tokens, err := service.Refresh(context.Background(), refreshToken)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+		assert.Nil(t, tokens)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("Unknown Token", func(t *testing.T) {
+		mockToken.On("FindRefreshTokenByHash", utils.HashToken(refreshToken)).Return(nil, domain.ErrNotFound).Once()
+
+		tokens, err := service.Refresh(context.Background(), refreshToken)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+		assert.Nil(t, tokens)
+		mockToken.AssertExpectations(t)
+	})
+}
+
+func TestLogout(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockToken := new(mocks.MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+	jti := uuid.New()
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims := &utils.CustomClaims{
+		ID: uuid.New(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	t.Run("Revokes Access And Refresh Tokens", func(t *testing.T) {
+		stored := &dto.RefreshToken{ID: uuid.New(), UserID: claims.ID, FamilyID: uuid.New()}
+
+		mockToken.On("RevokeAccessToken", jti, expiresAt).Return(nil).Once()
+		mockToken.On("FindRefreshTokenByHash", utils.HashToken("refresh-token")).Return(stored, nil).Once()
+		mockToken.On("RevokeRefreshTokenFamily", stored.FamilyID).Return(nil).Once()
+
+		err := service.Logout(context.Background(), claims, "refresh-token")
+
+		assert.NoError(t, err)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("Foreign Refresh Token", func(t *testing.T) {
+		stored := &dto.RefreshToken{ID: uuid.New(), UserID: uuid.New(), FamilyID: uuid.New()}
+
+		mockToken.On("RevokeAccessToken", jti, expiresAt).Return(nil).Once()
+		mockToken.On("FindRefreshTokenByHash", utils.HashToken("refresh-token")).Return(stored, nil).Once()
+
+		err := service.Logout(context.Background(), claims, "refresh-token")
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		mockToken.AssertExpectations(t)
+	})
+}
+
+func TestUserAdministration(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockToken := new(mocks.MockTokenRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+	passwordHash, _ := utils.HashPassword("old-password")
+	user := &dto.User{ID: uuid.New(), Name: "Picker", Email: "picker@example.com", Password: passwordHash, Role: dto.UserRoleStaff, Active: true}
+
+	t.Run("UpdateUser", func(t *testing.T) {
+		request := &dto.UserUpdateRequest{Email: "lead@example.com", Name: "Lead", Role: "supervisor"}
+		mockRepo.On("FindByID", user.ID).Return(&dto.User{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}, nil).Once()
+		mockRepo.On("UpdateWithTransaction", mock.Anything, mock.MatchedBy(func(u *dto.User) bool {
+			return u.ID == user.ID && u.Email == request.Email && u.Role == request.Role
+		})).Return(nil).Once()
+		mockToken.On("RevokeUserRefreshTokens", user.ID).Return(nil).Once()
+
+		updated, err := service.UpdateUser(context.Background(), auditActor, user.ID, request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Lead", updated.Name)
+		assert.False(t, updated.Scope.All)
+		mockRepo.AssertExpectations(t)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("UpdateUser Promotes To Admin", func(t *testing.T) {
+		request := &dto.UserUpdateRequest{Email: user.Email, Name: user.Name, Role: dto.UserRoleAdmin}
+		mockRepo.On("FindByID", user.ID).Return(&dto.User{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}, nil).Once()
+		mockRepo.On("UpdateWithTransaction", mock.Anything, mock.MatchedBy(func(u *dto.User) bool {
+			return u.Role == dto.UserRoleAdmin && u.Scope.All
+		})).Return(nil).Once()
+		mockToken.On("RevokeUserRefreshTokens", user.ID).Return(nil).Once()
+
+		updated, err := service.UpdateUser(context.Background(), auditActor, user.ID, request)
+
+		assert.NoError(t, err)
+		assert.True(t, updated.Scope.All)
+		mockRepo.AssertExpectations(t)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("UpdateUser Same Role Keeps Tokens", func(t *testing.T) {
+		mockToken := new(mocks.MockTokenRepository)
+		service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), new(mocks.MockAuthEventRepository), new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+		request := &dto.UserUpdateRequest{Email: user.Email, Name: "Picker Two", Role: user.Role}
+		mockRepo.On("FindByID", user.ID).Return(&dto.User{ID: user.ID, Email: user.Email, Name: user.Name, Role: user.Role}, nil).Once()
+		mockRepo.On("UpdateWithTransaction", mock.Anything, mock.Anything).Return(nil).Once()
+
+		_, err := service.UpdateUser(context.Background(), auditActor, user.ID, request)
+
+		assert.NoError(t, err)
+		mockToken.AssertNotCalled(t, "RevokeUserRefreshTokens", user.ID)
+	})
+
+	t.Run("UpdateUser Email Taken", func(t *testing.T) {
+		mockRepo.On("FindByID", user.ID).Return(&dto.User{ID: user.ID}, nil).Once()
+		mockRepo.On("UpdateWithTransaction", mock.Anything, mock.Anything).Return(domain.Conflict("email_taken", "email is exists")).Once()
+
+		_, err := service.UpdateUser(context.Background(), auditActor, user.ID, &dto.UserUpdateRequest{Email: "taken@example.com", Name: "x", Role: dto.UserRoleStaff})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrConflict)
+	})
+
+	t.Run("Deactivate Revokes Refresh Tokens", func(t *testing.T) {
+		mockRepo.On("SetActiveWithTransaction", mock.Anything, user.ID, false).Return(nil).Once()
+		mockToken.On("RevokeUserRefreshTokens", user.ID).Return(nil).Once()
+
+		err := service.SetActive(context.Background(), auditActor, user.ID, false)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("Deactivate Self", func(t *testing.T) {
+		err := service.SetActive(context.Background(), &dto.AuditActor{UserID: user.ID}, user.ID, false)
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("SetLocationScope", func(t *testing.T) {
+		scope := &dto.LocationScope{LocationIDs: []uuid.UUID{uuid.New()}}
+		mockRepo.On("SetLocationScopeWithTransaction", mock.Anything, user.ID, scope).Return(nil).Once()
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+
+		updated, err := service.SetLocationScope(context.Background(), auditActor, user.ID, scope)
+
+		assert.NoError(t, err)
+		assert.Equal(t, user, updated)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("ChangePassword Wrong Current", func(t *testing.T) {
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+
+		err := service.ChangePassword(context.Background(), auditActor, user.ID, &dto.PasswordChangeRequest{CurrentPassword: "nope", NewPassword: "new-password"})
+
+		assert.EqualError(t, err, "wrong password")
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		mockRepo.On("UpdatePasswordWithTransaction", mock.Anything, user.ID, mock.MatchedBy(func(hash string) bool {
+			return utils.VerifyPassword(hash, "new-password") == nil
+		})).Return(nil).Once()
+		mockToken.On("RevokeUserRefreshTokens", user.ID).Return(nil).Once()
+
+		err := service.ChangePassword(context.Background(), auditActor, user.ID, &dto.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "new-password"})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("Password Reset Round Trip", func(t *testing.T) {
+		var saved *dto.PasswordResetToken
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		mockToken.On("SavePasswordResetToken", mock.Anything).Run(func(args mock.Arguments) {
+			saved = args.Get(0).(*dto.PasswordResetToken)
+		}).Return(nil).Once()
+
+		reset, err := service.CreatePasswordReset(context.Background(), user.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, utils.HashToken(reset.ResetToken), saved.TokenHash)
+
+		mockToken.On("FindPasswordResetToken", saved.TokenHash).Return(saved, nil).Once()
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		mockToken.On("UsePasswordResetToken", saved.TokenHash).Return(saved, nil).Once()
+		mockRepo.On("UpdatePasswordWithTransaction", mock.Anything, user.ID, mock.Anything).Return(nil).Once()
+		mockToken.On("RevokeUserRefreshTokens", user.ID).Return(nil).Once()
+
+		err = service.ResetPassword(context.Background(), anonymousActor, &dto.PasswordResetRequest{ResetToken: reset.ResetToken, NewPassword: "brand-new-password"})
+		assert.NoError(t, err)
+		mockToken.AssertExpectations(t)
+	})
+
+	t.Run("Password Reset Used Token", func(t *testing.T) {
+		mockToken.On("FindPasswordResetToken", utils.HashToken("stale")).Return(nil, domain.ErrNotFound).Once()
+
+		err := service.ResetPassword(context.Background(), anonymousActor, &dto.PasswordResetRequest{ResetToken: "stale", NewPassword: "brand-new-password"})
+
+		assert.EqualError(t, err, "invalid or expired reset token")
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+
+	t.Run("Password Reset Rejected Keeps Token", func(t *testing.T) {
+		token := &dto.PasswordResetToken{UserID: user.ID, TokenHash: utils.HashToken("fresh")}
+		mockToken.On("FindPasswordResetToken", token.TokenHash).Return(token, nil).Once()
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+
+		err := service.ResetPassword(context.Background(), anonymousActor, &dto.PasswordResetRequest{ResetToken: "fresh", NewPassword: "short"})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		mockToken.AssertNumberOfCalls(t, "UsePasswordResetToken", 1)
+	})
+
+	t.Run("ChangePassword Reused", func(t *testing.T) {
+		require.NoError(t, utils.LoadPasswordPolicy(config.PasswordConfig{MinLength: 8, HistorySize: 3}))
+		defer utils.LoadPasswordPolicy(config.PasswordConfig{MinLength: 8})
+
+		previous, _ := utils.HashPassword("previous-password")
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		mockRepo.On("GetPasswordHistory", user.ID, 3).Return([]string{passwordHash, previous}, nil).Once()
+
+		err := service.ChangePassword(context.Background(), auditActor, user.ID, &dto.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "previous-password"})
+
+		assert.EqualError(t, err, "password must differ from your last 3 passwords")
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		mockRepo.AssertNumberOfCalls(t, "UpdatePasswordWithTransaction", 2)
+	})
+
+	t.Run("ChangePassword Matches Email", func(t *testing.T) {
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+
+		err := service.ChangePassword(context.Background(), auditActor, user.ID, &dto.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "Picker@Example.com"})
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+	})
+}
+
+func TestLoginRehashesLegacyPassword(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockToken := new(mocks.MockTokenRepository)
+	mockAuthEvent := new(mocks.MockAuthEventRepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), mockAuthEvent, new(mocks.MockMFARepository), newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &dto.User{ID: uuid.New(), Email: "legacy@example.com", Password: string(legacy), Active: true}
+
+	mockAuthEvent.On("CountLoginFailures", user.Email, "", mock.Anything).Return(&dto.LoginFailures{}, nil).Once()
+	mockAuthEvent.On("SaveAuthEvent", mock.Anything).Return(nil).Once()
+	mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+	mockRepo.On("RehashPassword", user.ID, user.Password, mock.MatchedBy(func(hash string) bool {
+		return strings.HasPrefix(hash, "$argon2id$") && utils.VerifyPassword(hash, "password123") == nil
+	})).Return(nil).Once()
+	mockToken.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+
+	_, _, err := service.Login(context.Background(), &dto.LoginRequest{Email: user.Email, Password: "password123"}, "")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+
+	t.Run("Failed Rehash Is Logged", func(t *testing.T) {
+		var logs bytes.Buffer
+		ctx := utils.WithLogger(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+
+		mockAuthEvent.On("CountLoginFailures", user.Email, "", mock.Anything).Return(&dto.LoginFailures{}, nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", mock.Anything).Return(nil).Once()
+		mockRepo.On("FindByEmail", user.Email).Return(user, nil).Once()
+		mockRepo.On("RehashPassword", user.ID, user.Password, mock.Anything).Return(errors.New("connection reset")).Once()
+		mockToken.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+
+		tokens, _, err := service.Login(ctx, &dto.LoginRequest{Email: user.Email, Password: "password123"}, "")
+
+		assert.NoError(t, err)
+		assert.NotNil(t, tokens)
+		assert.Contains(t, logs.String(), "password rehash failed")
+		assert.Contains(t, logs.String(), "connection reset")
+	})
+}
+
+func TestMFA(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockToken := new(mocks.MockTokenRepository)
+	mockAuthEvent := new(mocks.MockAuthEventRepository)
+	mockMFA := new(mocks.MockMFARepository)
+	service := services.NewUserService(mockRepo, mockToken, new(mocks.MockInviteRepository), mockAuthEvent, mockMFA, newAuditRepository(), config.RegistrationStaff, config.MFAConfig{Issuer: "Warehouse", RequiredRoles: []string{"admin"}})
+
+	passwordHash, _ := utils.HashPassword("password123")
+	secret, _ := utils.GenerateTOTPSecret()
+	enrolled := &dto.User{ID: uuid.New(), Email: "staff@example.com", Password: passwordHash, Role: dto.UserRoleStaff, Active: true, MFAEnabled: true}
+	admin := &dto.User{ID: uuid.New(), Email: "admin@example.com", Password: passwordHash, Role: dto.UserRoleAdmin, Active: true}
+
+	mockAuthEvent.On("CountLoginFailures", mock.Anything, mock.Anything, mock.Anything).Return(&dto.LoginFailures{}, nil)
+
+	challengeFor := func(user *dto.User, mfaToken string) *dto.MFAChallenge {
+		challenge := &dto.MFAChallenge{ID: uuid.New(), UserID: user.ID}
+		mockMFA.On("FindChallenge", utils.HashToken(mfaToken), 5).Return(challenge, nil).Once()
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		return challenge
+	}
+
+	t.Run("Login Returns Challenge", func(t *testing.T) {
+		mockRepo.On("FindByEmail", enrolled.Email).Return(enrolled, nil).Once()
+		mockMFA.On("SaveChallenge", mock.MatchedBy(func(challenge *dto.MFAChallenge) bool {
+			return challenge.UserID == enrolled.ID && challenge.TokenHash != ""
+		})).Return(nil).Once()
+
+		tokens, challenge, err := service.Login(context.Background(), &dto.LoginRequest{Email: enrolled.Email, Password: "password123"}, "")
+
+		assert.NoError(t, err)
+		assert.Nil(t, tokens)
+		assert.NotEmpty(t, challenge.MFAToken)
+		assert.False(t, challenge.EnrollmentRequired)
+		mockToken.AssertNotCalled(t, "SaveRefreshToken", mock.Anything)
+		mockAuthEvent.AssertNotCalled(t, "SaveAuthEvent", mock.Anything)
+	})
+
+	t.Run("Required Role Must Enrol", func(t *testing.T) {
+		mockRepo.On("FindByEmail", admin.Email).Return(admin, nil).Once()
+		mockMFA.On("SaveChallenge", mock.Anything).Return(nil).Once()
+
+		_, challenge, err := service.Login(context.Background(), &dto.LoginRequest{Email: admin.Email, Password: "password123"}, "")
+
+		assert.NoError(t, err)
+		assert.True(t, challenge.EnrollmentRequired)
+	})
+
+	t.Run("Verify With TOTP", func(t *testing.T) {
+		challenge := challengeFor(enrolled, "challenge-1")
+		code, _ := utils.TOTPCode(secret, time.Now())
+		mockMFA.On("GetTOTP", enrolled.ID).Return(&dto.TOTPState{Secret: &secret, Enabled: true}, nil).Once()
+		mockMFA.On("UseTOTPStep", enrolled.ID, mock.Anything).Return(true, nil).Once()
+		mockMFA.On("UseChallenge", challenge.ID).Return(true, nil).Once()
+		mockToken.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", mock.MatchedBy(func(event *dto.AuthEvent) bool { return event.Success })).Return(nil).Once()
+
+		tokens, err := service.VerifyMFA(context.Background(), anonymousActor, &dto.MFAVerifyRequest{MFAToken: "challenge-1", Code: code})
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, tokens.AccessToken)
+		assert.Empty(t, tokens.RecoveryCodes)
+		mockMFA.AssertExpectations(t)
+	})
+
+	t.Run("Replayed TOTP", func(t *testing.T) {
+		challenge := challengeFor(enrolled, "challenge-2")
+		code, _ := utils.TOTPCode(secret, time.Now())
+		mockMFA.On("GetTOTP", enrolled.ID).Return(&dto.TOTPState{Secret: &secret, Enabled: true}, nil).Once()
+		mockMFA.On("UseTOTPStep", enrolled.ID, mock.Anything).Return(false, nil).Once()
+		mockMFA.On("FailChallenge", challenge.ID).Return(nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", mock.MatchedBy(func(event *dto.AuthEvent) bool {
+			return event.Reason == dto.AuthReasonWrongMFACode
+		})).Return(nil).Once()
+
+		_, err := service.VerifyMFA(context.Background(), anonymousActor, &dto.MFAVerifyRequest{MFAToken: "challenge-2", Code: code})
+
+		assert.EqualError(t, err, "invalid two-factor code")
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+		mockMFA.AssertExpectations(t)
+		mockAuthEvent.AssertExpectations(t)
+	})
+
+	t.Run("Verify With Recovery Code", func(t *testing.T) {
+		challenge := challengeFor(enrolled, "challenge-3")
+		mockMFA.On("GetTOTP", enrolled.ID).Return(&dto.TOTPState{Secret: &secret, Enabled: true}, nil).Once()
+		mockMFA.On("UseRecoveryCode", enrolled.ID, utils.HashToken("abcde-fghij")).Return(true, nil).Once()
+		mockMFA.On("UseChallenge", challenge.ID).Return(true, nil).Once()
+		mockToken.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", mock.Anything).Return(nil).Once()
+
+		_, err := service.VerifyMFA(context.Background(), anonymousActor, &dto.MFAVerifyRequest{MFAToken: "challenge-3", Code: "ABCDEFGHIJ"})
+
+		assert.NoError(t, err)
+		mockMFA.AssertExpectations(t)
+	})
+
+	t.Run("Expired Challenge", func(t *testing.T) {
+		mockMFA.On("FindChallenge", utils.HashToken("stale"), 5).Return(nil, domain.ErrNotFound).Once()
+
+		_, err := service.VerifyMFA(context.Background(), anonymousActor, &dto.MFAVerifyRequest{MFAToken: "stale", Code: "123456"})
+
+		assert.EqualError(t, err, "invalid or expired mfa token")
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	})
+
+	t.Run("Enrol Through Challenge", func(t *testing.T) {
+		challengeFor(admin, "challenge-4")
+		var pending string
+		mockMFA.On("SetPendingTOTPSecretWithTransaction", mock.Anything, admin.ID, mock.Anything).Run(func(args mock.Arguments) {
+			pending = args.String(2)
+		}).Return(nil).Once()
+
+		enrollment, err := service.StartMFAEnrollmentWithChallenge(context.Background(), anonymousActor, "challenge-4")
+
+		assert.NoError(t, err)
+		assert.Equal(t, pending, enrollment.Secret)
+		assert.Contains(t, enrollment.URI, "issuer=Warehouse")
+
+		challenge := challengeFor(admin, "challenge-4")
+		code, _ := utils.TOTPCode(pending, time.Now())
+		mockMFA.On("GetTOTP", admin.ID).Return(&dto.TOTPState{Secret: &pending}, nil).Once()
+		mockMFA.On("UseTOTPStep", admin.ID, mock.Anything).Return(true, nil).Once()
+		mockMFA.On("UseChallenge", challenge.ID).Return(true, nil).Once()
+		mockMFA.On("EnableMFAWithTransaction", mock.Anything, admin.ID, mock.MatchedBy(func(hashes []string) bool { return len(hashes) == 10 })).Return(nil).Once()
+		mockToken.On("SaveRefreshToken", mock.Anything).Return(nil).Once()
+		mockAuthEvent.On("SaveAuthEvent", mock.Anything).Return(nil).Once()
+
+		tokens, err := service.VerifyMFA(context.Background(), anonymousActor, &dto.MFAVerifyRequest{MFAToken: "challenge-4", Code: code})
+
+		assert.NoError(t, err)
+		assert.Len(t, tokens.RecoveryCodes, 10)
+		mockMFA.AssertExpectations(t)
+	})
+
+	t.Run("Confirm Enrolment", func(t *testing.T) {
+		code, _ := utils.TOTPCode(secret, time.Now())
+		mockMFA.On("GetTOTP", enrolled.ID).Return(&dto.TOTPState{Secret: &secret}, nil).Once()
+		mockMFA.On("UseTOTPStep", enrolled.ID, mock.Anything).Return(true, nil).Once()
+		mockMFA.On("EnableMFAWithTransaction", mock.Anything, enrolled.ID, mock.Anything).Return(nil).Once()
+
+		result, err := service.ConfirmMFAEnrollment(context.Background(), auditActor, enrolled.ID, code)
+
+		assert.NoError(t, err)
+		assert.Len(t, result.RecoveryCodes, 10)
+	})
+
+	t.Run("Required Role Cannot Disable", func(t *testing.T) {
+		err := service.DisableMFA(context.Background(), auditActor, &utils.CustomClaims{ID: admin.ID, Role: dto.UserRoleAdmin}, "123456")
+
+		assert.Error(t, err)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		mockMFA.AssertNotCalled(t, "DisableMFAWithTransaction", mock.Anything, admin.ID)
+	})
+
+	t.Run("Admin Resets MFA", func(t *testing.T) {
+		mockMFA.On("DisableMFAWithTransaction", mock.Anything, enrolled.ID).Return(nil).Once()
+
+		err := service.ResetMFA(context.Background(), auditActor, enrolled.ID)
+
+		assert.NoError(t, err)
+		mockMFA.AssertExpectations(t)
+	})
+}
+
+func TestMFAFailuresLockLogin(t *testing.T) {
+	mockRepo := new(mocks.MockUserRepository)
+	mockAuthEvent := new(mocks.MockAuthEventRepository)
+	mockMFA := new(mocks.MockMFARepository)
+	service := services.NewUserService(mockRepo, new(mocks.MockTokenRepository), new(mocks.MockInviteRepository), mockAuthEvent, mockMFA, newAuditRepository(), config.RegistrationStaff, config.MFAConfig{})
+
+	secret, _ := utils.GenerateTOTPSecret()
+	user := &dto.User{ID: uuid.New(), Email: "Picker@Example.com", Role: dto.UserRoleStaff, Active: true, MFAEnabled: true}
+
+	// The stored failures are what the next login counts, but only under
+	// the address the login normalizes to.
+	failures := &dto.LoginFailures{}
+	mockAuthEvent.On("SaveAuthEvent", mock.MatchedBy(func(event *dto.AuthEvent) bool {
+		return event.Email == "picker@example.com" && event.Reason == dto.AuthReasonWrongMFACode
+	})).Run(func(args mock.Arguments) {
+		failures.Account++
+		failures.AccountLast = time.Now()
+	}).Return(nil)
+
+	for i := range 5 {
+		mfaToken := fmt.Sprintf("challenge-%d", i)
+		challenge := &dto.MFAChallenge{ID: uuid.New(), UserID: user.ID}
+		mockMFA.On("FindChallenge", utils.HashToken(mfaToken), 5).Return(challenge, nil).Once()
+		mockRepo.On("FindByID", user.ID).Return(user, nil).Once()
+		mockMFA.On("GetTOTP", user.ID).Return(&dto.TOTPState{Secret: &secret, Enabled: true}, nil).Once()
+		mockMFA.On("UseRecoveryCode", user.ID, mock.Anything).Return(false, nil).Once()
+		mockMFA.On("FailChallenge", challenge.ID).Return(nil).Once()
+
+		_, err := service.VerifyMFA(context.Background(), anonymousActor, &dto.MFAVerifyRequest{MFAToken: mfaToken, Code: "wrong-code"})
+		assert.ErrorIs(t, err, domain.ErrUnauthorized)
+	}
+
+	mockAuthEvent.On("CountLoginFailures", "picker@example.com", "", mock.Anything).Return(failures, nil).Once()
+
+	_, _, err := service.Login(context.Background(), &dto.LoginRequest{Email: " PICKER@example.com", Password: "password123"}, "")
+
+	assert.ErrorIs(t, err, domain.ErrTooManyRequests)
+	mockRepo.AssertNotCalled(t, "FindByEmail", mock.Anything)
+	mockAuthEvent.AssertExpectations(t)
+}
@@ -0,0 +1,125 @@
+package utils_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, name, kind string, der []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0600))
+	return path
+}
+
+func writeRSAKey(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	private, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+
+	return writePEM(t, "rsa.key", "PRIVATE KEY", private), writePEM(t, "rsa.pub", "PUBLIC KEY", public)
+}
+
+func writeEdKey(t *testing.T) (string, string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	private, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	public, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	return writePEM(t, "ed.key", "PRIVATE KEY", private), writePEM(t, "ed.pub", "PUBLIC KEY", public)
+}
+
+func TestTokenSigning(t *testing.T) {
+	claims := &utils.CustomClaims{ID: uuid.New(), Name: "Test User", Role: dto.UserRoleAdmin}
+
+	t.Run("RS256 With Kid", func(t *testing.T) {
+		privatePath, _ := writeRSAKey(t)
+		require.NoError(t, utils.LoadKeys(config.JWTConfig{Algorithm: "RS256", SigningKeyFile: privatePath, SigningKeyID: "rsa-1"}))
+
+		token, err := utils.GenerateToken(claims)
+		require.NoError(t, err)
+
+		parsed, _, err := jwt.NewParser().ParseUnverified(token, &utils.CustomClaims{})
+		require.NoError(t, err)
+		assert.Equal(t, "rsa-1", parsed.Header["kid"])
+		assert.Equal(t, "RS256", parsed.Method.Alg())
+
+		verified, err := utils.VerifyToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, claims.ID, verified.ID)
+
+		jwks := utils.GetJWKS()
+		require.Len(t, jwks.Keys, 1)
+		assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+		assert.Equal(t, "rsa-1", jwks.Keys[0].Kid)
+	})
+
+	t.Run("Rotation Keeps Old Tokens Valid", func(t *testing.T) {
+		oldPrivate, oldPublic := writeRSAKey(t)
+		require.NoError(t, utils.LoadKeys(config.JWTConfig{Algorithm: "RS256", SigningKeyFile: oldPrivate, SigningKeyID: "old"}))
+
+		oldToken, err := utils.GenerateToken(claims)
+		require.NoError(t, err)
+
+		newPrivate, _ := writeEdKey(t)
+		require.NoError(t, utils.LoadKeys(config.JWTConfig{
+			Algorithm:            "EdDSA",
+			SigningKeyFile:       newPrivate,
+			SigningKeyID:         "new",
+			VerificationKeyFiles: []string{"old=" + oldPublic},
+		}))
+
+		newToken, err := utils.GenerateToken(claims)
+		require.NoError(t, err)
+
+		_, err = utils.VerifyToken(oldToken)
+		assert.NoError(t, err)
+		_, err = utils.VerifyToken(newToken)
+		assert.NoError(t, err)
+		assert.Len(t, utils.GetJWKS().Keys, 2)
+	})
+
+	t.Run("Rejects Other Algorithms", func(t *testing.T) {
+		privatePath, _ := writeRSAKey(t)
+		require.NoError(t, utils.LoadKeys(config.JWTConfig{Algorithm: "RS256", SigningKeyFile: privatePath, SigningKeyID: "rsa-1"}))
+
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		forged.Header["kid"] = "rsa-1"
+		token, err := forged.SignedString([]byte("guessed-secret"))
+		require.NoError(t, err)
+
+		_, err = utils.VerifyToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("HS256 Publishes No Keys", func(t *testing.T) {
+		require.NoError(t, utils.LoadKeys(config.JWTConfig{SecretKey: "secret"}))
+
+		token, err := utils.GenerateToken(claims)
+		require.NoError(t, err)
+
+		_, err = utils.VerifyToken(token)
+		assert.NoError(t, err)
+		assert.Empty(t, utils.GetJWKS().Keys)
+	})
+}
@@ -0,0 +1,100 @@
+package utils_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/nabilwafi/warehouse-management-system/test/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOIDCProvider(t *testing.T) {
+	idp := mocks.NewStandInIdP("warehouse")
+	defer idp.Close()
+
+	idp.Groups = []string{"warehouse-staff"}
+
+	provider, err := utils.NewOIDCProvider(context.Background(), config.OIDCConfig{
+		IssuerURL:   idp.URL(),
+		ClientID:    "warehouse",
+		RedirectURL: "http://localhost:8080/api/v1/auth/oidc/callback",
+		GroupsClaim: "groups",
+	})
+	require.NoError(t, err)
+
+	login := func(t *testing.T, verifier string) string {
+		code, state, err := idp.Authorize(provider.AuthCodeURL("state-1", "nonce-1", verifier))
+		require.NoError(t, err)
+		require.Equal(t, "state-1", state)
+		require.NotEmpty(t, code)
+		return code
+	}
+
+	t.Run("Authorization Code With PKCE", func(t *testing.T) {
+		code := login(t, "verifier-1")
+
+		identity, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+
+		require.NoError(t, err)
+		assert.Equal(t, idp.URL(), identity.Issuer)
+		assert.Equal(t, "stand-in-user", identity.Subject)
+		assert.Equal(t, "jane@example.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+		assert.Equal(t, []string{"warehouse-staff"}, identity.Groups)
+	})
+
+	t.Run("Wrong Verifier", func(t *testing.T) {
+		code := login(t, "verifier-1")
+
+		_, err := provider.Exchange(context.Background(), code, "someone-elses-verifier", "nonce-1")
+		assert.ErrorContains(t, err, "invalid_grant")
+	})
+
+	t.Run("Code Used Twice", func(t *testing.T) {
+		code := login(t, "verifier-1")
+
+		_, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+		require.NoError(t, err)
+
+		_, err = provider.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Wrong Nonce", func(t *testing.T) {
+		code := login(t, "verifier-1")
+
+		_, err := provider.Exchange(context.Background(), code, "verifier-1", "another-nonce")
+		assert.ErrorContains(t, err, "nonce")
+	})
+
+	t.Run("Rejected Tokens", func(t *testing.T) {
+		cases := map[string]jwt.MapClaims{
+			"Other Audience": {"aud": "another-client"},
+			"Other Issuer":   {"iss": "https://evil.example.com"},
+			"Expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+			"No Subject":     {"sub": ""},
+		}
+
+		for name, overrides := range cases {
+			t.Run(name, func(t *testing.T) {
+				idp.Overrides = overrides
+				defer func() { idp.Overrides = nil }()
+
+				code := login(t, "verifier-1")
+
+				_, err := provider.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("Issuer Mismatch", func(t *testing.T) {
+		_, err := utils.NewOIDCProvider(context.Background(), config.OIDCConfig{IssuerURL: idp.URL() + "/other"})
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nabilwafi/warehouse-management-system/src/config"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestPasswordHashing(t *testing.T) {
+	t.Run("Argon2id Round Trip", func(t *testing.T) {
+		hash, err := utils.HashPassword("correct horse battery staple")
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=65536,t=3,p=2$"))
+		assert.NoError(t, utils.VerifyPassword(hash, "correct horse battery staple"))
+		assert.ErrorIs(t, utils.VerifyPassword(hash, "correct horse battery"), utils.ErrPasswordMismatch)
+		assert.False(t, utils.NeedsRehash(hash))
+	})
+
+	t.Run("Salted", func(t *testing.T) {
+		first, _ := utils.HashPassword("same-password")
+		second, _ := utils.HashPassword("same-password")
+
+		assert.NotEqual(t, first, second)
+	})
+
+	t.Run("Legacy Bcrypt", func(t *testing.T) {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+
+		assert.NoError(t, utils.VerifyPassword(string(hash), "password123"))
+		assert.ErrorIs(t, utils.VerifyPassword(string(hash), "password124"), utils.ErrPasswordMismatch)
+		assert.True(t, utils.NeedsRehash(string(hash)))
+	})
+
+	t.Run("Older Argon2id Parameters", func(t *testing.T) {
+		// Parameters from an earlier, weaker configuration.
+		hash := "$argon2id$v=19$m=19456,t=2,p=1$c2FsdHNhbHRzYWx0c2FsdA$QHn8ptJ0D9vEP5fuUgLf9g3p3zLB0Kk3bYgcBl2kZzM"
+
+		assert.True(t, utils.NeedsRehash(hash))
+	})
+
+	t.Run("Malformed Hash", func(t *testing.T) {
+		assert.Error(t, utils.VerifyPassword("$argon2id$v=19$m=0,t=0,p=0$AA$AA", "password123"))
+	})
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	defer utils.LoadPasswordPolicy(config.PasswordConfig{MinLength: 8})
+
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte("password123\nQwertyuiop\n\n"), 0600))
+	require.NoError(t, utils.LoadPasswordPolicy(config.PasswordConfig{MinLength: 10, BreachedListFile: list, HistorySize: 4}))
+
+	assert.Equal(t, 4, utils.PasswordHistorySize())
+	assert.EqualError(t, utils.CheckPassword("short-pw"), "password must be at least 10 characters")
+	assert.Error(t, utils.CheckPassword(strings.Repeat("a", utils.MaxPasswordLength+1)))
+	assert.ErrorIs(t, utils.CheckPassword("PASSWORD123"), utils.ErrPasswordBreached)
+	assert.ErrorIs(t, utils.CheckPassword("qwertyuiop"), utils.ErrPasswordBreached)
+	assert.Error(t, utils.CheckPassword("someone@example.com", "Someone@Example.com"))
+	assert.NoError(t, utils.CheckPassword("correct horse battery staple", "someone@example.com"))
+
+	assert.Error(t, utils.LoadPasswordPolicy(config.PasswordConfig{BreachedListFile: filepath.Join(t.TempDir(), "missing.txt")}))
+}
@@ -0,0 +1,75 @@
+package utils_test
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA-1 key from RFC 6238 appendix B.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTP(t *testing.T) {
+	t.Run("RFC 6238 Vectors", func(t *testing.T) {
+		vectors := map[int64]string{
+			59:         "287082",
+			1111111109: "081804",
+			1234567890: "005924",
+			2000000000: "279037",
+		}
+
+		for unix, want := range vectors {
+			code, err := utils.TOTPCode(rfc6238Secret, time.Unix(unix, 0))
+			require.NoError(t, err)
+			assert.Equal(t, want, code, "T=%d", unix)
+		}
+	})
+
+	t.Run("Allows One Step Of Drift", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		previous, _ := utils.TOTPCode(rfc6238Secret, now.Add(-30*time.Second))
+		stale, _ := utils.TOTPCode(rfc6238Secret, now.Add(-90*time.Second))
+
+		step, ok := utils.ValidateTOTP(rfc6238Secret, previous, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30-1, step)
+
+		_, ok = utils.ValidateTOTP(rfc6238Secret, stale, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Rejects Malformed Input", func(t *testing.T) {
+		_, ok := utils.ValidateTOTP(rfc6238Secret, "12345", time.Now())
+		assert.False(t, ok)
+
+		_, ok = utils.ValidateTOTP("not base32!", "123456", time.Now())
+		assert.False(t, ok)
+	})
+
+	t.Run("Secret And URI", func(t *testing.T) {
+		secret, err := utils.GenerateTOTPSecret()
+		require.NoError(t, err)
+		assert.Len(t, secret, 32)
+
+		uri, err := url.Parse(utils.TOTPURI(secret, "Warehouse", "admin@example.com"))
+		require.NoError(t, err)
+		assert.Equal(t, "otpauth", uri.Scheme)
+		assert.Equal(t, "totp", uri.Host)
+		assert.Equal(t, "/Warehouse:admin@example.com", uri.Path)
+		assert.Equal(t, secret, uri.Query().Get("secret"))
+	})
+}
+
+func TestRecoveryCode(t *testing.T) {
+	code, err := utils.GenerateRecoveryCode()
+	require.NoError(t, err)
+
+	assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, code)
+	assert.Equal(t, code, utils.NormalizeRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))+" "))
+}
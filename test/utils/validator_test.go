@@ -0,0 +1,63 @@
+package utils_test
+
+import (
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nabilwafi/warehouse-management-system/src/domain"
+	"github.com/nabilwafi/warehouse-management-system/src/models/dto"
+	"github.com/nabilwafi/warehouse-management-system/src/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Run("Lists Every Field", func(t *testing.T) {
+		err := utils.Validate(&dto.Product{Name: "Product A"})
+
+		var domainErr *domain.Error
+		require.ErrorAs(t, err, &domainErr)
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		assert.Equal(t, "invalid_data", domainErr.Code)
+		assert.Equal(t, []domain.FieldError{
+			{Field: "sku", Rule: "required", Message: "is required"},
+			{Field: "quantity", Rule: "required", Message: "is required"},
+			{Field: "location_id", Rule: "required", Message: "is required"},
+		}, domainErr.Fields)
+	})
+
+	t.Run("Nested Fields", func(t *testing.T) {
+		err := utils.Validate(&dto.OrderBatchRequest{
+			Lines: []dto.OrderBatchLine{{Type: "returns", ProductID: uuid.New(), Quantity: 1}},
+		})
+
+		var domainErr *domain.Error
+		require.ErrorAs(t, err, &domainErr)
+		assert.Equal(t, []domain.FieldError{
+			{Field: "lines[0].type", Rule: "oneof", Param: "receiving shipping", Message: "must be one of: receiving, shipping"},
+		}, domainErr.Fields)
+	})
+
+	t.Run("Valid", func(t *testing.T) {
+		assert.NoError(t, utils.Validate(&dto.Product{Name: "Product A", SKU: "SKU-1", Quantity: 1, LocationID: uuid.New()}))
+	})
+}
+
+func TestBindingError(t *testing.T) {
+	t.Run("Empty Body", func(t *testing.T) {
+		err := utils.BindingError(io.EOF)
+
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		assert.EqualError(t, err, "request body is empty")
+	})
+
+	t.Run("Malformed JSON Is Not Echoed", func(t *testing.T) {
+		var product dto.Product
+		err := utils.BindingError(json.Unmarshal([]byte(`{"name":}`), &product))
+
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		assert.EqualError(t, err, "request body is not valid JSON")
+	})
+}